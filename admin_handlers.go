@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexUsage adalah statistik pemakaian satu index dari stage $indexStats
+type IndexUsage struct {
+	Name     string `bson:"name" json:"name"`
+	Key      bson.M `bson:"key" json:"key"`
+	Accesses struct {
+		Ops   int64     `bson:"ops" json:"ops"`
+		Since time.Time `bson:"since" json:"since"`
+	} `bson:"accesses" json:"accesses"`
+}
+
+// indexStatsHandler mengembalikan jumlah akses tiap index sejak server MongoDB terakhir restart
+func (s *Server) indexStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$indexStats": bson.M{}},
+		bson.M{"$sort": bson.M{"accesses.ops": 1}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	stats := []IndexUsage{}
+	if err = cursor.All(ctx, &stats); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, stats)
+}
+
+const (
+	// defaultSchemaSample adalah jumlah dokumen default yang diambil untuk inferensi schema
+	defaultSchemaSample = 1000
+	// maxSchemaSample adalah batas maksimum dokumen yang boleh diambil untuk inferensi schema
+	maxSchemaSample = 10000
+)
+
+// FieldSchema adalah ringkasan satu field yang ditemukan dari sampel dokumen
+type FieldSchema struct {
+	Field       string           `json:"field"`
+	Occurrences int64            `json:"occurrences"`
+	Types       map[string]int64 `json:"types"`
+}
+
+// sampleSchemaHandler mengambil sampel dokumen lalu merangkum field top-level dan properties.* beserta tipe datanya
+func (s *Server) sampleSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var warns []string
+	sampleSize := defaultSchemaSample
+	if raw := r.URL.Query().Get("sampleSize"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "sampleSize must be a positive integer")
+			return
+		}
+		if n > maxSchemaSample {
+			addLimitWarning(&warns, "sampleSize was reduced from %d to the server maximum of %d", n, maxSchemaSample)
+			n = maxSchemaSample
+		}
+		sampleSize = n
+	}
+	setWarningHeaders(w, warns)
+
+	// Field top-level digabung dengan field di dalam properties (jika berupa object) dengan prefix "properties."
+	propertiesFields := bson.M{"$map": bson.M{
+		"input": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$properties"}, "object"}},
+			bson.M{"$objectToArray": "$properties"},
+			bson.A{},
+		}},
+		"as": "p",
+		"in": bson.M{"k": bson.M{"$concat": bson.A{"properties.", "$$p.k"}}, "v": "$$p.v"},
+	}}
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$sample": bson.M{"size": sampleSize}},
+		bson.M{"$project": bson.M{"fields": bson.M{"$concatArrays": bson.A{
+			bson.M{"$objectToArray": "$$ROOT"},
+			propertiesFields,
+		}}}},
+		bson.M{"$unwind": "$fields"},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"field": "$fields.k", "type": bson.M{"$type": "$fields.v"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id.field": 1}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID struct {
+			Field string `bson:"field"`
+			Type  string `bson:"type"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// Hasil aggregation sudah terurut per field, sehingga tipe untuk field yang sama bisa langsung digabung
+	fields := []FieldSchema{}
+	var sampled int64
+	for _, row := range rows {
+		if len(fields) == 0 || fields[len(fields)-1].Field != row.ID.Field {
+			fields = append(fields, FieldSchema{Field: row.ID.Field, Types: map[string]int64{}})
+		}
+		f := &fields[len(fields)-1]
+		f.Occurrences += row.Count
+		f.Types[row.ID.Type] += row.Count
+		if row.ID.Field == "_id" {
+			sampled += row.Count
+		}
+	}
+
+	response := map[string]interface{}{
+		"sampled": sampled,
+		"fields":  fields,
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// maxRepairableListed adalah batas jumlah ID dokumen bermasalah yang dicantumkan di response geo-check
+const maxRepairableListed = 100
+
+// invalidGeometryFilter mencocokkan dokumen dengan tipe geometri yang tidak didukung, atau Point yang tidak valid.
+// Koordinat LineString dan Polygon tidak diperiksa di sini; index 2dsphere sudah menolaknya saat ditulis.
+var invalidGeometryFilter = bson.M{"$or": bson.A{
+	bson.M{"location.type": bson.M{"$nin": bson.A{"Point", "LineString", "Polygon"}}},
+	bson.M{"location.type": "Point", "$or": bson.A{
+		bson.M{"location.coordinates": bson.M{"$not": bson.M{"$size": 2}}},
+		bson.M{"location.coordinates.0": bson.M{"$not": bson.M{"$gte": -180, "$lte": 180}}},
+		bson.M{"location.coordinates.1": bson.M{"$not": bson.M{"$gte": -90, "$lte": 90}}},
+	}},
+}}
+
+// findGeoIndexName mencari nama index 2dsphere pada field location, string kosong jika tidak ada
+func (s *Server) findGeoIndexName(ctx context.Context) (string, error) {
+	cursor, err := s.collection.Indexes().List(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []struct {
+		Name string `bson:"name"`
+		Key  bson.M `bson:"key"`
+	}
+	if err = cursor.All(ctx, &indexes); err != nil {
+		return "", err
+	}
+	for _, idx := range indexes {
+		if idx.Key["location"] == "2dsphere" {
+			return idx.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// geoNearUsesIndex menjalankan explain untuk probe $geoNear dan mengecek apakah plan memakai index 2dsphere
+func (s *Server) geoNearUsesIndex(ctx context.Context) (bool, error) {
+	pipeline := bson.A{
+		bson.M{"$geoNear": bson.M{
+			"near":          Point{Type: "Point", Coordinates: []float64{0, 0}},
+			"distanceField": "distance",
+			"spherical":     true,
+		}},
+		bson.M{"$limit": 1},
+	}
+	explain := bson.D{
+		{Key: "explain", Value: bson.D{
+			{Key: "aggregate", Value: s.collection.Name()},
+			{Key: "pipeline", Value: pipeline},
+			{Key: "cursor", Value: bson.D{}},
+		}},
+		{Key: "verbosity", Value: "queryPlanner"},
+	}
+
+	var plan bson.Raw
+	if err := s.collection.Database().RunCommand(ctx, explain).Decode(&plan); err != nil {
+		return false, err
+	}
+	return strings.Contains(plan.String(), "GEO_NEAR_2DSPHERE"), nil
+}
+
+// geoCheckHandler memeriksa kesehatan index 2dsphere dan geometri dokumen, serta memperbaikinya jika ?repair=true
+func (s *Server) geoCheckHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	indexName, err := s.findGeoIndexName(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	report := map[string]interface{}{
+		"indexPresent": indexName != "",
+		"indexName":    indexName,
+	}
+
+	// Probe $geoNear akan gagal jika index 2dsphere tidak ada, sehingga hasilnya dicatat sebagai tidak terpakai
+	used, err := s.geoNearUsesIndex(ctx)
+	report["indexUsed"] = err == nil && used
+	if err != nil {
+		report["probeError"] = err.Error()
+	}
+
+	invalidCount, err := s.collection.CountDocuments(ctx, invalidGeometryFilter)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	report["invalidGeometryCount"] = invalidCount
+
+	healthy := indexName != "" && used && invalidCount == 0
+	report["healthy"] = healthy
+
+	if r.URL.Query().Get("repair") == "true" && !healthy {
+		// Index dibuat ulang dari awal; jika masih ada geometri rusak, MongoDB akan menolak pembuatannya
+		if indexName != "" {
+			if _, err := s.collection.Indexes().DropOne(ctx, indexName); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+		}
+		if err := s.ensureGeoIndex(ctx); err != nil {
+			report["repaired"] = false
+			report["repairError"] = err.Error()
+		} else {
+			report["repaired"] = true
+		}
+
+		cursor, err := s.collection.Find(ctx, invalidGeometryFilter,
+			options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(maxRepairableListed))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err = cursor.All(ctx, &docs); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		ids := make([]string, 0, len(docs))
+		for _, d := range docs {
+			ids = append(ids, d.ID.Hex())
+		}
+		report["repairableDocuments"] = ids
+	}
+
+	writeResponse(w, r, http.StatusOK, report)
+}
+
+// CollectionStats adalah ringkasan hasil perintah collStats untuk kebutuhan capacity planning
+type CollectionStats struct {
+	Namespace      string           `bson:"ns" json:"namespace"`
+	Count          int64            `bson:"count" json:"count"`
+	Size           int64            `bson:"size" json:"sizeBytes"`
+	StorageSize    int64            `bson:"storageSize" json:"storageSizeBytes"`
+	AvgObjSize     float64          `bson:"avgObjSize" json:"avgObjSizeBytes"`
+	TotalIndexSize int64            `bson:"totalIndexSize" json:"totalIndexSizeBytes"`
+	IndexSizes     map[string]int64 `bson:"indexSizes" json:"indexSizes"`
+}
+
+// collectionStatsHandler menjalankan collStats dan mengembalikan ukuran data, jumlah dokumen, dan ukuran index
+func (s *Server) collectionStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var stats CollectionStats
+	err := s.collection.Database().RunCommand(ctx, bson.D{{Key: "collStats", Value: s.collection.Name()}}).Decode(&stats)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, stats)
+}
+
+// adminReportHandler menggabungkan beberapa statistik (total, per kategori, dibuat hari ini, dan extent koleksi)
+// ke dalam satu laporan yang dihitung dengan $facet dalam satu round trip ke database. Seperti endpoint statistik
+// satuannya, lokasi di trash dan yang sudah kedaluwarsa tidak ikut dihitung.
+func (s *Server) adminReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	tz, ok := parseTimezone(r)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Unknown timezone in tz parameter")
+		return
+	}
+	now := time.Now().In(tz)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tz)
+
+	lng := bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}
+	lat := bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, nil)},
+		bson.M{"$facet": bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"categories": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$ifNull": bson.A{"$category", "uncategorized"}},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+			"createdToday": bson.A{
+				bson.M{"$match": bson.M{"created_at": bson.M{"$gte": startOfDay}}},
+				bson.M{"$count": "count"},
+			},
+			"extent": bson.A{
+				bson.M{"$match": bson.M{"location.type": "Point"}},
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"west":  bson.M{"$min": lng},
+					"south": bson.M{"$min": lat},
+					"east":  bson.M{"$max": lng},
+					"north": bson.M{"$max": lat},
+				}},
+			},
+		}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Categories   []CategoryCount `bson:"categories"`
+		CreatedToday []struct {
+			Count int64 `bson:"count"`
+		} `bson:"createdToday"`
+		Extent []BBox `bson:"extent"`
+	}
+	if err = cursor.All(ctx, &facets); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// $facet selalu menghasilkan tepat satu dokumen; sub-pipeline $count kosong jika tidak ada data
+	report := map[string]interface{}{
+		"generatedAt":  time.Now().UTC(),
+		"timezone":     tz.String(),
+		"total":        int64(0),
+		"categories":   []CategoryCount{},
+		"createdToday": int64(0),
+		"extent":       nil,
+	}
+	if len(facets) == 1 {
+		f := facets[0]
+		if len(f.Total) > 0 {
+			report["total"] = f.Total[0].Count
+		}
+		if f.Categories != nil {
+			report["categories"] = f.Categories
+		}
+		if len(f.CreatedToday) > 0 {
+			report["createdToday"] = f.CreatedToday[0].Count
+		}
+		if len(f.Extent) > 0 {
+			report["extent"] = f.Extent[0]
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, report)
+}
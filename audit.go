@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Operasi yang dicatat di audit log
+const (
+	auditCreate  = "create"
+	auditUpdate  = "update"
+	auditDelete  = "delete"
+	auditRestore = "restore"
+	auditPurge   = "purge"
+)
+
+// auditWriteTimeout adalah batas waktu menyimpan event audit. Event tetap disimpan walau client sudah
+// memutus koneksi, karena write lokasinya sendiri sudah terjadi.
+const auditWriteTimeout = 5 * time.Second
+
+// auditIgnoredFields tidak dihitung sebagai perubahan di Changed karena selalu ikut berubah pada setiap write
+var auditIgnoredFields = map[string]bool{"updated_at": true, "revision": true, "name_normalized": true}
+
+// AuditEvent adalah satu write lokasi di audit log. Before kosong untuk create dan restore, After kosong
+// untuk delete dan purge; Changed berisi nama field yang berubah pada update.
+type AuditEvent struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id,omitempty" json:"tenantId,omitempty"`
+	LocationID primitive.ObjectID `bson:"location_id" json:"locationId"`
+	Operation  string             `bson:"operation" json:"operation"`
+	Actor      string             `bson:"actor" json:"actor"`
+	RequestID  string             `bson:"request_id,omitempty" json:"requestId,omitempty"`
+	Before     *Location          `bson:"before,omitempty" json:"before,omitempty"`
+	After      *Location          `bson:"after,omitempty" json:"after,omitempty"`
+	Changed    []string           `bson:"changed,omitempty" json:"changed,omitempty"`
+	OccurredAt time.Time          `bson:"occurred_at" json:"occurredAt"`
+}
+
+// newAuditEvent menyusun event audit untuk satu lokasi; actor, tenant, dan waktu diisi oleh recordAudit
+func newAuditEvent(op string, before, after *Location) AuditEvent {
+	evt := AuditEvent{Operation: op, Before: before, After: after}
+	if after != nil {
+		evt.LocationID = after.ID
+	} else if before != nil {
+		evt.LocationID = before.ID
+	}
+	if before != nil && after != nil {
+		evt.Changed = changedFields(*before, *after)
+	}
+	return evt
+}
+
+// createAuditEvents menyusun event create untuk setiap lokasi yang baru disimpan
+func createAuditEvents(locs []Location) []AuditEvent {
+	events := make([]AuditEvent, len(locs))
+	for i := range locs {
+		events[i] = newAuditEvent(auditCreate, nil, &locs[i])
+	}
+	return events
+}
+
+// changedFields membandingkan dua lokasi per field BSON tingkat atas dan mengembalikan nama field yang berbeda
+func changedFields(before, after Location) []string {
+	b, errB := toBSONMap(before)
+	a, errA := toBSONMap(after)
+	if errB != nil || errA != nil {
+		return nil
+	}
+	var changed []string
+	for k, v := range a {
+		if !auditIgnoredFields[k] && !reflect.DeepEqual(b[k], v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok && !auditIgnoredFields[k] {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// toBSONMap mengubah lokasi menjadi bson.M lewat marshal BSON, sehingga perbandingan memakai nama field di database
+func toBSONMap(loc Location) (bson.M, error) {
+	raw, err := bson.Marshal(loc)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	err = bson.Unmarshal(raw, &m)
+	return m, err
+}
+
+// auditActorKey adalah key context untuk pelaku write yang ditentukan server sendiri, misalnya cli untuk
+// perintah admin yang tidak datang lewat HTTP
+type auditActorKey struct{}
+
+// auditActor mengembalikan identitas pelaku write: admin, api-key, tenant:<id>, jwt:<sub>, cli, atau anonymous
+// jika server berjalan tanpa autentikasi
+func auditActor(r *http.Request) string {
+	if actor, ok := r.Context().Value(auditActorKey{}).(string); ok {
+		return actor
+	}
+	if validAdminKey(r) {
+		return "admin"
+	}
+	if credential, ok := rateLimitCredential(r); ok {
+		return credential
+	}
+	return "anonymous"
+}
+
+// recordAudit menyimpan event audit untuk write yang sudah berhasil. Kegagalan tidak membatalkan write
+// (yang sudah terjadi) maupun response-nya, tetapi dicatat di log dan metrik agar bisa ditindaklanjuti.
+func (s *Server) recordAudit(r *http.Request, events ...AuditEvent) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), auditWriteTimeout)
+	defer cancel()
+
+	docs := auditDocuments(r, events)
+	if _, err := s.auditEvents.InsertMany(ctx, docs); err != nil {
+		auditFailures.Add(float64(len(docs)))
+		requestLogger(r).Error("audit events could not be saved", "events", len(docs), "error", err)
+	}
+}
+
+// auditDocuments melengkapi event dengan ID, tenant, pelaku, request ID, dan waktu request r
+func auditDocuments(r *http.Request, events []AuditEvent) []interface{} {
+	actor, tenant, requestID := auditActor(r), tenantFromContext(r.Context()), requestIDFrom(r.Context())
+	now := time.Now()
+	docs := make([]interface{}, len(events))
+	for i, evt := range events {
+		evt.ID = primitive.NewObjectID()
+		evt.TenantID = tenant
+		evt.Actor = actor
+		evt.RequestID = requestID
+		evt.OccurredAt = now
+		docs[i] = evt
+	}
+	return docs
+}
+
+// ensureAuditIndexes membuat index untuk riwayat per lokasi dan untuk daftar admin yang urut waktu
+func (s *Server) ensureAuditIndexes(ctx context.Context) error {
+	_, err := s.auditEvents.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "location_id", Value: 1}, {Key: "occurred_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "occurred_at", Value: -1}}},
+	})
+	return err
+}
+
+// listAuditEvents mengembalikan satu halaman event yang cocok dengan filter beserta totalnya
+func (s *Server) listAuditEvents(ctx context.Context, filter bson.M, sortDir, limit, page int) ([]AuditEvent, int64, error) {
+	total, err := s.auditEvents.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "occurred_at", Value: sortDir}, {Key: "_id", Value: sortDir}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64((page - 1) * limit))
+	cursor, err := s.auditEvents.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	events := []AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// locationHistoryHandler mengembalikan riwayat write satu lokasi per halaman, dari yang paling lama.
+// Riwayat tetap tersedia setelah lokasi di-purge.
+func (s *Server) locationHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	events, total, err := s.listAuditEvents(ctx, forTenant(ctx, bson.M{"location_id": id}), 1, limit, page)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if total == 0 {
+		writeJSONError(w, http.StatusNotFound, "No history for this location")
+		return
+	}
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":  events,
+		"limit": limit,
+		"page":  page,
+		"total": total,
+	})
+}
+
+// parseAuditFilter membaca filter GET /admin/audit: locationId, operation, actor, tenant, since, dan until
+// (RFC3339). Tanpa tenant, event semua tenant ikut ditampilkan.
+func parseAuditFilter(r *http.Request) (bson.M, error) {
+	q := r.URL.Query()
+	filter := bson.M{}
+	if raw := q.Get("locationId"); raw != "" {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return nil, errors.New("locationId must be a location ID (24 hex characters)")
+		}
+		filter["location_id"] = id
+	}
+	if op := q.Get("operation"); op != "" {
+		switch op {
+		case auditCreate, auditUpdate, auditDelete, auditRestore, auditPurge:
+			filter["operation"] = op
+		default:
+			return nil, fmt.Errorf("operation must be one of %s, %s, %s, %s or %s", auditCreate, auditUpdate, auditDelete, auditRestore, auditPurge)
+		}
+	}
+	if actor := q.Get("actor"); actor != "" {
+		filter["actor"] = actor
+	}
+	if tenant := q.Get("tenant"); tenant != "" {
+		filter["tenant_id"] = tenant
+	}
+
+	occurred := bson.M{}
+	for param, op := range map[string]string{"since": "$gte", "until": "$lt"} {
+		if raw := q.Get(param); raw != "" {
+			t, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be an RFC3339 timestamp", param)
+			}
+			occurred[op] = t
+		}
+	}
+	if len(occurred) > 0 {
+		filter["occurred_at"] = occurred
+	}
+	return filter, nil
+}
+
+// auditEventsHandler mengembalikan audit log per halaman untuk admin, yang terbaru lebih dulu
+func (s *Server) auditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	events, total, err := s.listAuditEvents(ctx, filter, -1, limit, page)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":  events,
+		"limit": limit,
+		"page":  page,
+		"total": total,
+	})
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxBatchOperations membatasi jumlah operasi dalam satu request POST /locations/batch
+const maxBatchOperations = 500
+
+// batchOperation adalah satu operasi dalam batch: create memakai data, update memakai id dan data
+// (hanya field yang dikirim yang diubah, seperti PATCH), delete cukup id
+type batchOperation struct {
+	Op   string          `json:"op"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// BatchResult adalah hasil satu operasi batch. Status mengikuti status HTTP yang akan dikembalikan
+// endpoint tunggalnya, sehingga client bisa memakai logika yang sama.
+type BatchResult struct {
+	Index  int       `json:"index"`
+	Op     string    `json:"op"`
+	ID     string    `json:"id,omitempty"`
+	Status int       `json:"status"`
+	Error  *APIError `json:"error,omitempty"`
+}
+
+// batchFailure mengisi hasil gagal untuk satu operasi
+func batchFailure(res *BatchResult, status int, code, message string) {
+	res.Status = status
+	res.Error = &APIError{Code: code, Message: message}
+}
+
+// batchLocationsHandler menjalankan banyak create/update/delete dalam satu BulkWrite, untuk client mobile yang
+// menyinkronkan perubahan offline sekaligus. Setiap operasi divalidasi dan dilaporkan sendiri-sendiri: operasi
+// yang gagal tidak membatalkan yang lain, dan urutan eksekusi antar operasi tidak dijamin. Update dan delete
+// hanya berlaku jika lokasinya belum diubah request lain sejak dibaca; jika sudah, operasinya dilaporkan 412,
+// atau 404 jika lokasinya sudah dihapus.
+//
+// Dengan ?atomic=true batch bersifat semua-atau-tidak-sama-sekali: jika satu operasi gagal validasi tidak ada
+// yang ditulis, dan write-nya (beserta audit log) dijalankan dalam satu transaksi. Pada MongoDB tanpa
+// transaksi operasi ditulis berurutan dan berhenti di operasi pertama yang gagal; operasi sebelumnya tetap
+// tersimpan dan dilaporkan sukses di results.
+func (s *Server) batchLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var ops []batchOperation
+	if err := decodeBody(w, r, &ops); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(ops) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "body must be a non-empty array of operations")
+		return
+	}
+	if len(ops) > maxBatchOperations {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d operations can be sent per batch", maxBatchOperations))
+		return
+	}
+	atomic := r.URL.Query().Get("atomic") == "true"
+
+	results := make([]BatchResult, len(ops))
+	ids := make([]primitive.ObjectID, len(ops))
+	// Satu lokasi hanya boleh disentuh sekali per batch, karena urutan update dalam BulkWrite unordered tidak pasti
+	seen := map[primitive.ObjectID]bool{}
+	var lookup []primitive.ObjectID
+	for i, op := range ops {
+		results[i] = BatchResult{Index: i, Op: op.Op, ID: op.ID}
+		switch op.Op {
+		case "create":
+			if op.ID != "" {
+				batchFailure(&results[i], http.StatusBadRequest, "bad_request", "create does not accept an id")
+			}
+		case "update", "delete":
+			id, err := primitive.ObjectIDFromHex(op.ID)
+			if err != nil {
+				batchFailure(&results[i], http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+				continue
+			}
+			if seen[id] {
+				batchFailure(&results[i], http.StatusConflict, "duplicate_operation", "Location appears in more than one operation of the batch")
+				continue
+			}
+			seen[id] = true
+			ids[i] = id
+			lookup = append(lookup, id)
+		default:
+			batchFailure(&results[i], http.StatusBadRequest, "bad_request", "op must be one of create, update or delete")
+		}
+	}
+
+	existing := map[primitive.ObjectID]Location{}
+	if len(lookup) > 0 {
+		existing, err = s.findLocationsByIDs(ctx, lookup)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	var models []mongo.WriteModel
+	// modelOps memetakan index model BulkWrite ke index operasi, untuk menerjemahkan WriteErrors
+	var modelOps []int
+	// afters menyimpan hasil akhir setiap create/update untuk evaluasi geofence
+	afters := make([]*Location, len(ops))
+	for i, op := range ops {
+		if results[i].Error != nil {
+			continue
+		}
+		var model mongo.WriteModel
+		switch op.Op {
+		case "create":
+			model, afters[i] = s.batchCreateModel(r, op, now, &results[i])
+		case "update":
+			model, afters[i] = batchUpdateModel(ctx, op, existing, ids[i], now, &results[i])
+		case "delete":
+			current, ok := existing[ids[i]]
+			if !ok {
+				batchFailure(&results[i], http.StatusNotFound, "not_found", "Location not found")
+				continue
+			}
+			if !canModify(ctx, current) {
+				batchFailure(&results[i], http.StatusForbidden, "not_owner", "Only the owner of this location or an admin can change it")
+				continue
+			}
+			// Seperti update, delete hanya berlaku jika lokasinya belum diubah request lain sejak dibaca
+			filter := revisionFilter(current)
+			filter["_id"] = ids[i]
+			model = mongo.NewUpdateOneModel().
+				SetFilter(withoutDeleted(ctx, filter)).
+				SetUpdate(bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}})
+			results[i].Status = http.StatusOK
+		}
+		if model != nil {
+			models = append(models, model)
+			modelOps = append(modelOps, i)
+		}
+	}
+
+	if atomic && len(models) < len(ops) {
+		for _, i := range modelOps {
+			batchFailure(&results[i], http.StatusFailedDependency, "batch_aborted", "Not applied because another operation of the atomic batch failed")
+		}
+		models = nil
+	}
+
+	inTxn := false
+	if len(models) > 0 {
+		var result *mongo.BulkWriteResult
+		var err error
+		// unapplied berisi operasi update/delete yang tidak cocok dengan dokumen mana pun saat ditulis
+		var unapplied map[int]*Location
+		if atomic {
+			audits := batchAuditEvents(results, existing, ids, afters)
+			inTxn, err = s.runInTransaction(r, func(ctx context.Context) error {
+				var err error
+				result, err = coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+				// Tanpa transaksi, operasi yang tidak cocok diperiksa setelah write seperti batch biasa
+				if err != nil || mongo.SessionFromContext(ctx) == nil {
+					return err
+				}
+				if unapplied, err = s.batchUnapplied(ctx, result, results, ids, afters, now); err != nil {
+					return err
+				}
+				if len(unapplied) > 0 {
+					return errBatchUnapplied
+				}
+				return s.auditInTransaction(ctx, r, audits...)
+			})
+		} else {
+			result, err = coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		}
+		var bwe mongo.BulkWriteException
+		if err != nil && !errors.Is(err, errBatchUnapplied) && !(errors.As(err, &bwe) && bwe.WriteConcernError == nil) {
+			s.writeWriteError(w, r, err)
+			return
+		}
+		markBatchWriteErrors(r, results, modelOps, bwe)
+		// Transaksi yang gagal tidak menulis apa pun; tanpa transaksi, BulkWrite berurutan sudah menulis
+		// operasi sebelum yang gagal dan berhenti di sana
+		if atomic && len(bwe.WriteErrors) > 0 {
+			failed := modelOps[bwe.WriteErrors[0].Index]
+			for _, i := range modelOps {
+				if results[i].Error == nil && (inTxn || i > failed) {
+					batchFailure(&results[i], http.StatusFailedDependency, "batch_aborted", "Not applied because another operation of the atomic batch failed")
+				}
+			}
+		}
+		if !inTxn {
+			// Operasi yang gagal karena write error sudah ditandai, sehingga tidak ikut diharapkan cocok
+			if unapplied, err = s.batchUnapplied(ctx, result, results, ids, afters, now); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+		}
+		for i, current := range unapplied {
+			if current == nil || current.DeletedAt != nil {
+				batchFailure(&results[i], http.StatusNotFound, "not_found", "Location not found")
+				continue
+			}
+			// Seperti writePreconditionFailed, ETag terkini dikirim agar client tahu versi mana yang harus diambil
+			batchFailure(&results[i], http.StatusPreconditionFailed, "precondition_failed", "Location was modified by another request, fetch it again and retry")
+			results[i].Error.Details = map[string]interface{}{"currentETag": current.ETag()}
+		}
+		// Di dalam transaksi, satu operasi yang tidak cocok membatalkan seluruh batch
+		if inTxn && len(unapplied) > 0 {
+			for _, i := range modelOps {
+				if results[i].Error == nil {
+					batchFailure(&results[i], http.StatusFailedDependency, "batch_aborted", "Not applied because another operation of the atomic batch failed")
+				}
+			}
+		}
+	}
+
+	succeeded := 0
+	for i, res := range results {
+		if res.Error != nil {
+			continue
+		}
+		succeeded++
+		if afters[i] != nil {
+			s.notifyLocationChange(batchBefore(res, existing, ids[i]), afters[i])
+		}
+	}
+	if succeeded > 0 {
+		s.bumpCollectionVersion(ctx)
+		// Audit batch yang ter-commit dalam transaksi sudah tersimpan bersama write-nya
+		if !inTxn {
+			s.recordAudit(r, batchAuditEvents(results, existing, ids, afters)...)
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// errBatchUnapplied membatalkan transaksi batch atomik yang salah satu operasinya tidak cocok dengan dokumen
+var errBatchUnapplied = errors.New("batch operation did not match its location")
+
+// markBatchWriteErrors mencatat WriteErrors BulkWrite sebagai hasil gagal operasi yang bersangkutan
+func markBatchWriteErrors(r *http.Request, results []BatchResult, modelOps []int, bwe mongo.BulkWriteException) {
+	for _, we := range bwe.WriteErrors {
+		res := &results[modelOps[we.Index]]
+		if isDuplicateExternalID(we) {
+			batchFailure(res, http.StatusConflict, "duplicate_external_id", "A location with this external_id already exists")
+			continue
+		}
+		if mongo.IsDuplicateKeyError(we) {
+			batchFailure(res, http.StatusConflict, "duplicate_name", "A location with this name already exists")
+			continue
+		}
+		requestLogger(r).Error("batch operation failed", "index", res.Index, "op", res.Op, "error", we.Message)
+		batchFailure(res, http.StatusInternalServerError, "internal_error", "Write failed")
+	}
+}
+
+// batchUnapplied mencari operasi update dan delete yang tidak mengubah apa pun karena lokasinya dihapus atau
+// diubah request lain sejak dibaca, dikunci per index operasi dengan dokumen terkininya (nil jika sudah tidak
+// ada). BulkWriteResult hanya memuat jumlah total, sehingga dokumen hanya dibaca ulang jika MatchedCount
+// kurang dari jumlah operasi yang diharapkan cocok.
+func (s *Server) batchUnapplied(ctx context.Context, result *mongo.BulkWriteResult, results []BatchResult, ids []primitive.ObjectID, afters []*Location, now time.Time) (map[int]*Location, error) {
+	var pending []int
+	var lookup []primitive.ObjectID
+	for i, res := range results {
+		if res.Error == nil && (res.Op == "update" || res.Op == "delete") {
+			pending = append(pending, i)
+			lookup = append(lookup, ids[i])
+		}
+	}
+	if result == nil || result.MatchedCount >= int64(len(pending)) {
+		return nil, nil
+	}
+
+	cursor, err := s.collection.Find(ctx, forTenant(ctx, bson.M{"_id": bson.M{"$in": lookup}}),
+		options.Find().SetProjection(bson.M{"revision": 1, "updated_at": 1, "deleted_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []Location
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	current := make(map[primitive.ObjectID]Location, len(docs))
+	for _, doc := range docs {
+		current[doc.ID] = doc
+	}
+
+	// Write batch ini memberi updated_at (dan deleted_at untuk delete) yang sama dengan now, dalam presisi
+	// milidetik BSON; update juga menaikkan revision tepat satu
+	written := now.Truncate(time.Millisecond)
+	unapplied := map[int]*Location{}
+	for _, i := range pending {
+		doc, ok := current[ids[i]]
+		if !ok {
+			unapplied[i] = nil
+			continue
+		}
+		applied := doc.UpdatedAt.Equal(written)
+		if results[i].Op == "update" {
+			applied = applied && doc.Revision == afters[i].Revision
+		} else {
+			applied = applied && doc.DeletedAt != nil && doc.DeletedAt.Equal(written)
+		}
+		if !applied {
+			unapplied[i] = &doc
+		}
+	}
+	return unapplied, nil
+}
+
+// batchBefore mengembalikan lokasi sebelum operasi update atau delete, atau nil untuk create
+func batchBefore(res BatchResult, existing map[primitive.ObjectID]Location, id primitive.ObjectID) *Location {
+	if current, ok := existing[id]; ok && res.Op != "create" {
+		return &current
+	}
+	return nil
+}
+
+// batchAuditEvents membuat event audit untuk setiap operasi batch yang tidak gagal
+func batchAuditEvents(results []BatchResult, existing map[primitive.ObjectID]Location, ids []primitive.ObjectID, afters []*Location) []AuditEvent {
+	var audits []AuditEvent
+	for i, res := range results {
+		if res.Error != nil {
+			continue
+		}
+		before := batchBefore(res, existing, ids[i])
+		switch res.Op {
+		case "create":
+			audits = append(audits, newAuditEvent(auditCreate, nil, afters[i]))
+		case "update":
+			audits = append(audits, newAuditEvent(auditUpdate, before, afters[i]))
+		case "delete":
+			audits = append(audits, newAuditEvent(auditDelete, before, nil))
+		}
+	}
+	return audits
+}
+
+// batchCreateModel memvalidasi operasi create seperti POST /locations dan mengembalikan InsertOne-nya beserta
+// lokasi yang akan disimpan, atau nil jika gagal (alasannya dicatat di res)
+func (s *Server) batchCreateModel(r *http.Request, op batchOperation, now time.Time, res *BatchResult) (mongo.WriteModel, *Location) {
+	var loc Location
+	if err := decodeStrict(op.Data, &loc); err != nil {
+		batchFailure(res, http.StatusBadRequest, "bad_request", "data: "+err.Error())
+		return nil, nil
+	}
+	prepareNewLocation(r.Context(), &loc, now)
+	if errs := loc.validate(); len(errs) > 0 {
+		batchFailure(res, http.StatusUnprocessableEntity, "validation_failed", "Validation failed")
+		res.Error.Details = map[string]interface{}{"fields": errs}
+		return nil, nil
+	}
+	if err := validateBusinessRules(loc, nil); err != nil {
+		batchFailure(res, http.StatusUnprocessableEntity, "rule_violated", err.Error())
+		return nil, nil
+	}
+	if pos, ok := loc.Location.Position(); ok && rejectCoincidentPointsEnabled() {
+		nearest, err := s.findNearestLocation(r.Context(), pos[0], pos[1], coincidentEpsilonMeters())
+		if err != nil {
+			requestLogger(r).Error("coincident check failed", "index", res.Index, "error", err)
+			batchFailure(res, http.StatusInternalServerError, "internal_error", "Coincident location check failed")
+			return nil, nil
+		}
+		if nearest != nil {
+			batchFailure(res, http.StatusConflict, "coincident_location", "A location already exists at these coordinates")
+			res.Error.Details = map[string]interface{}{"existingId": nearest.ID.Hex()}
+			return nil, nil
+		}
+	}
+	res.ID = loc.ID.Hex()
+	res.Status = http.StatusCreated
+	return mongo.NewInsertOneModel().SetDocument(loc), &loc
+}
+
+// batchUpdateModel menerapkan data ke dokumen yang ada seperti PATCH /locations/{id} dan mengembalikan
+// UpdateOne-nya beserta hasil akhir lokasinya, atau nil jika gagal (alasannya dicatat di res)
+func batchUpdateModel(ctx context.Context, op batchOperation, existing map[primitive.ObjectID]Location, id primitive.ObjectID, now time.Time, res *BatchResult) (mongo.WriteModel, *Location) {
+	current, ok := existing[id]
+	if !ok {
+		batchFailure(res, http.StatusNotFound, "not_found", "Location not found")
+		return nil, nil
+	}
+	if !canModify(ctx, current) {
+		batchFailure(res, http.StatusForbidden, "not_owner", "Only the owner of this location or an admin can change it")
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(op.Data, &fields); err != nil {
+		batchFailure(res, http.StatusBadRequest, "bad_request", "data must be a JSON object")
+		return nil, nil
+	}
+	merged := current
+	if err := decodeStrict(op.Data, &merged); err != nil {
+		batchFailure(res, http.StatusBadRequest, "bad_request", "data: "+err.Error())
+		return nil, nil
+	}
+	merged.Tags = normalizeTags(merged.Tags)
+	if errs := merged.validate(); len(errs) > 0 {
+		batchFailure(res, http.StatusUnprocessableEntity, "validation_failed", "Validation failed")
+		res.Error.Details = map[string]interface{}{"fields": errs}
+		return nil, nil
+	}
+	if err := validateBusinessRules(merged, fields); err != nil {
+		batchFailure(res, http.StatusUnprocessableEntity, "rule_violated", err.Error())
+		return nil, nil
+	}
+	update := locationUpdate(fields, merged, now)
+	if update == nil {
+		batchFailure(res, http.StatusBadRequest, "bad_request", "data must set at least one of name, description, category, tags, address, location or expires_at")
+		return nil, nil
+	}
+	res.Status = http.StatusOK
+	merged.UpdatedAt = now
+	merged.Revision++
+	// revisionFilter mencegah update menimpa perubahan request lain di antara pembacaan dan BulkWrite
+	filter := revisionFilter(current)
+	filter["_id"] = id
+	return mongo.NewUpdateOneModel().SetFilter(withoutDeleted(ctx, filter)).SetUpdate(update), &merged
+}
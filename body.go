@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxBodyBytes adalah ukuran maksimum body request JSON pada endpoint write (default 1 MB)
+var maxBodyBytes int64 = 1 << 20
+
+// loadMaxBodyBytes membaca MAX_BODY_BYTES dari environment (kosong berarti memakai default)
+func loadMaxBodyBytes() {
+	raw := os.Getenv("MAX_BODY_BYTES")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		fatal("MAX_BODY_BYTES must be a positive integer", "value", raw)
+	}
+	maxBodyBytes = n
+	slog.Info("request body limit configured", "max_body_bytes", n)
+}
+
+// readBody membaca seluruh body request dengan batas maxBodyBytes
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+}
+
+// maxRequestBytes adalah batas body semua request di bodyLimitMiddleware: yang terbesar dari batas body JSON,
+// file import, dan upload foto. Endpoint tetap memakai batasnya sendiri yang lebih ketat; middleware ini
+// memastikan body raksasa ditolak di endpoint mana pun, termasuk yang tidak membaca body.
+func maxRequestBytes() int64 {
+	return max(maxBodyBytes, maxImportBodyBytes, maxPhotoBytes+photoMultipartOverhead)
+}
+
+// bodyLimitMiddleware menolak Content-Length yang melebihi maxRequestBytes dengan 413 tanpa membaca body-nya,
+// dan membatasi body tanpa Content-Length (chunked) dengan MaxBytesReader
+func bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := maxRequestBytes()
+		if r.ContentLength > limit {
+			writeBodyError(w, &http.MaxBytesError{Limit: limit})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeStrict men-decode JSON ke dst dan menolak field yang tidak dikenal, agar salah ketik nama field
+// tidak diabaikan diam-diam
+func decodeStrict(body []byte, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	return decodeSingle(dec, dst)
+}
+
+// decodeSingle men-decode satu nilai JSON dari dec dan menolak data lain sesudahnya, misalnya dua object
+// yang tergabung atau sisa body yang terpotong
+func decodeSingle(dec *json.Decoder, dst interface{}) error {
+	if err := dec.Decode(dst); err != nil {
+		return describeJSONError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+// describeJSONError mengganti pesan error encoding/json dengan pesan yang menyebut letak kesalahannya.
+// Error lain (termasuk http.MaxBytesError dan validasi dari UnmarshalJSON) dikembalikan apa adanya.
+func describeJSONError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return errors.New("JSON body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("JSON body ends unexpectedly")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON at byte %d: %s", syntaxErr.Offset, syntaxErr.Error())
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Errorf("field %s must be of type %s", typeErr.Field, typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
+
+// decodeBody membaca body dengan batas maxBodyBytes lalu men-decode-nya secara ketat ke dst
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	body, err := readBody(w, r)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(body, dst)
+}
+
+// writeBodyError menulis error pembacaan body: 413 jika melebihi maxBodyBytes, selain itu 400
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	writeJSONError(w, http.StatusBadRequest, err.Error())
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxBulkCoordinateRows membatasi jumlah baris CSV yang diproses dalam satu request
+const maxBulkCoordinateRows = 10000
+
+// coordinateRow adalah satu baris id,lng,lat yang sudah lolos validasi
+type coordinateRow struct {
+	Row int
+	ID  primitive.ObjectID
+	Lng float64
+	Lat float64
+}
+
+// RowError menjelaskan kenapa satu baris CSV ditolak
+type RowError struct {
+	Row   int    `json:"row"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// parseCoordinateRecord memvalidasi satu record CSV id,lng,lat
+func parseCoordinateRecord(rec []string) (primitive.ObjectID, float64, float64, error) {
+	if len(rec) != 3 {
+		return primitive.NilObjectID, 0, 0, fmt.Errorf("expected 3 columns (id,lng,lat), got %d", len(rec))
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(rec[0]))
+	if err != nil {
+		return primitive.NilObjectID, 0, 0, errors.New("invalid location ID format")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+	if err != nil {
+		return primitive.NilObjectID, 0, 0, errors.New("lng must be a number")
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+	if err != nil {
+		return primitive.NilObjectID, 0, 0, errors.New("lat must be a number")
+	}
+	pos := []float64{lng, lat}
+	if err := validatePosition(pos); err != nil {
+		return primitive.NilObjectID, 0, 0, err
+	}
+	return id, pos[0], pos[1], nil
+}
+
+// bulkCoordinatesHandler memperbarui koordinat banyak lokasi sekaligus dari CSV id,lng,lat.
+// Baris yang tidak valid dilaporkan per baris; dengan ?dryRun=true tidak ada yang ditulis.
+func (s *Server) bulkCoordinatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []coordinateRow
+	rowErrors := []RowError{}
+	for line := 1; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+			return
+		}
+		// Header opsional di baris pertama
+		if line == 1 && len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "id") {
+			continue
+		}
+		if len(rows)+len(rowErrors) >= maxBulkCoordinateRows {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("CSV exceeds the maximum of %d rows", maxBulkCoordinateRows))
+			return
+		}
+		id, lng, lat, err := parseCoordinateRecord(rec)
+		if err != nil {
+			rowErr := RowError{Row: line, Error: err.Error()}
+			if len(rec) > 0 {
+				rowErr.ID = strings.TrimSpace(rec[0])
+			}
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		rows = append(rows, coordinateRow{Row: line, ID: id, Lng: lng, Lat: lat})
+	}
+
+	// ID yang tidak ada di koleksi juga dilaporkan per baris, termasuk saat dry-run
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	existing := map[primitive.ObjectID]Location{}
+	if len(ids) > 0 {
+		existing, err = s.findLocationsByIDs(ctx, ids)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	var models []mongo.WriteModel
+	var audits []AuditEvent
+	for _, row := range rows {
+		before, ok := existing[row.ID]
+		if !ok {
+			rowErrors = append(rowErrors, RowError{Row: row.Row, ID: row.ID.Hex(), Error: "location not found"})
+			continue
+		}
+		after := before
+		after.Location = Geometry{Type: "Point", Coordinates: []float64{row.Lng, row.Lat}}
+		after.UpdatedAt = now
+		after.Revision++
+		audits = append(audits, newAuditEvent(auditUpdate, &before, &after))
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(forTenant(ctx, bson.M{"_id": row.ID})).
+			SetUpdate(bson.M{"$set": bson.M{
+				"location":   Point{Type: "Point", Coordinates: []float64{row.Lng, row.Lat}},
+				"updated_at": now,
+			}, "$inc": bson.M{"revision": 1}}))
+	}
+
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	var updated int64
+	if !dryRun && len(models) > 0 {
+		inTxn, err := s.runInTransaction(r, func(ctx context.Context) error {
+			result, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+			if err != nil {
+				return err
+			}
+			updated = result.ModifiedCount
+			if updated == 0 {
+				return nil
+			}
+			return s.auditInTransaction(ctx, r, audits...)
+		})
+		if err != nil {
+			s.writeWriteError(w, r, err)
+			return
+		}
+		if updated > 0 {
+			s.bumpCollectionVersion(ctx)
+			if !inTxn {
+				s.recordAudit(r, audits...)
+			}
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"dryRun":  dryRun,
+		"valid":   len(models),
+		"updated": updated,
+		"errors":  rowErrors,
+	})
+}
+
+// maxBulkCreateItems membatasi jumlah lokasi dalam satu request bulk create
+const maxBulkCreateItems = 1000
+
+// bulkCreateHandler membuat banyak lokasi sekaligus dari array JSON dengan satu InsertMany.
+// Semua item divalidasi dulu; jika ada satu yang gagal, tidak ada yang ditulis dan index item pertama
+// yang gagal dilaporkan, sehingga import bersifat semua-atau-tidak-sama-sekali dari sisi client. Pada replica set
+// InsertMany berjalan dalam transaksi, sehingga bentrok index unique di tengah jalan pun tidak menulis apa pun.
+func (s *Server) bulkCreateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var locs []Location
+	if err := decodeBody(w, r, &locs); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(locs) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "body must be a non-empty array of locations")
+		return
+	}
+	if len(locs) > maxBulkCreateItems {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d locations can be created per request", maxBulkCreateItems))
+		return
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(locs))
+	ids := make([]primitive.ObjectID, len(locs))
+	for i := range locs {
+		loc := &locs[i]
+		prepareNewLocation(ctx, loc, now)
+
+		if errs := loc.validate(); len(errs) > 0 {
+			writeErrorDetails(w, http.StatusUnprocessableEntity, "validation_failed", fmt.Sprintf("Validation failed for item %d", i),
+				map[string]interface{}{"index": i, "fields": errs})
+			return
+		}
+		if err := validateBusinessRules(*loc, nil); err != nil {
+			writeErrorDetails(w, http.StatusUnprocessableEntity, "rule_violated", err.Error(), map[string]interface{}{"index": i})
+			return
+		}
+		if pos, ok := loc.Location.Position(); ok && rejectCoincidentPointsEnabled() {
+			existing, err := s.findNearestLocation(ctx, pos[0], pos[1], coincidentEpsilonMeters())
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			if existing != nil {
+				writeErrorDetails(w, http.StatusConflict, "coincident_location", "A location already exists at these coordinates",
+					map[string]interface{}{"index": i, "existingId": existing.ID.Hex()})
+				return
+			}
+		}
+		docs[i] = loc
+		ids[i] = loc.ID
+	}
+
+	// Nama dicek di depan (di dalam batch dan terhadap database) agar batch ditolak utuh sebelum ada yang
+	// tertulis; index unique tetap menjadi pengaman terakhir untuk request yang berjalan bersamaan
+	if index, ok := duplicateNameIndex(locs); ok {
+		writeErrorDetails(w, http.StatusConflict, "duplicate_name", fmt.Sprintf("Location name %q appears more than once in the batch", locs[index].Name),
+			map[string]interface{}{"index": index})
+		return
+	}
+	names := make([]string, len(locs))
+	for i, loc := range locs {
+		names[i] = loc.Name
+	}
+	var taken struct {
+		Name string `bson:"name"`
+	}
+	err = s.collection.FindOne(ctx, forTenant(ctx, bson.M{"name": bson.M{"$in": names}}), options.FindOne().SetProjection(bson.M{"name": 1})).Decode(&taken)
+	if err == nil {
+		index := indexOfName(locs, taken.Name)
+		writeErrorDetails(w, http.StatusConflict, "duplicate_name", fmt.Sprintf("A location named %q already exists", taken.Name),
+			map[string]interface{}{"index": index})
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// InsertMany sendiri tidak atomik: tanpa transaksi, jika gagal di tengah jalan dokumen sebelumnya sudah
+	// tertulis dan dilaporkan lewat inserted
+	inTxn, err := s.runInTransaction(r, func(ctx context.Context) error {
+		if _, err := coll.InsertMany(ctx, docs); err != nil {
+			return err
+		}
+		return s.auditInTransaction(ctx, r, createAuditEvents(locs)...)
+	})
+	if err != nil {
+		var bwe mongo.BulkWriteException
+		if mongo.IsDuplicateKeyError(err) && errors.As(err, &bwe) && len(bwe.WriteErrors) > 0 {
+			index := bwe.WriteErrors[0].Index
+			inserted := index
+			if inTxn {
+				inserted = 0
+			}
+			if inserted > 0 {
+				s.bumpCollectionVersion(ctx)
+				for i := range locs[:inserted] {
+					s.notifyLocationChange(nil, &locs[i])
+				}
+				s.recordAudit(r, createAuditEvents(locs[:inserted])...)
+			}
+			if isDuplicateExternalID(bwe.WriteErrors[0]) {
+				writeErrorDetails(w, http.StatusConflict, "duplicate_external_id", fmt.Sprintf("A location with external_id %q already exists", locs[index].ExternalID),
+					map[string]interface{}{"index": index, "inserted": inserted})
+				return
+			}
+			writeErrorDetails(w, http.StatusConflict, "duplicate_name", fmt.Sprintf("A location named %q already exists", locs[index].Name),
+				map[string]interface{}{"index": index, "inserted": inserted})
+			return
+		}
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+	for i := range locs {
+		s.notifyLocationChange(nil, &locs[i])
+	}
+	if !inTxn {
+		s.recordAudit(r, createAuditEvents(locs)...)
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"count": len(ids),
+		"ids":   ids,
+	})
+}
+
+// duplicateNameIndex mengembalikan index item kedua yang namanya sama persis dengan item sebelumnya
+func duplicateNameIndex(locs []Location) (int, bool) {
+	seen := make(map[string]bool, len(locs))
+	for i, loc := range locs {
+		if seen[loc.Name] {
+			return i, true
+		}
+		seen[loc.Name] = true
+	}
+	return 0, false
+}
+
+// indexOfName mengembalikan index item pertama dengan nama tersebut, atau -1 jika tidak ada
+func indexOfName(locs []Location, name string) int {
+	for i, loc := range locs {
+		if loc.Name == name {
+			return i
+		}
+	}
+	return -1
+}
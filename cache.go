@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// cacheMaxAge adalah max-age (detik) Cache-Control untuk response baca; 0 berarti client boleh menyimpan
+// response tetapi wajib memvalidasi ulang lewat ETag sebelum memakainya
+var cacheMaxAge = 0
+
+// loadCacheMaxAge membaca CACHE_MAX_AGE dari environment (kosong berarti 0)
+func loadCacheMaxAge() {
+	raw := os.Getenv("CACHE_MAX_AGE")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		fatal("CACHE_MAX_AGE must be a non-negative integer (seconds)", "value", raw)
+	}
+	cacheMaxAge = n
+	slog.Info("read responses may be cached", "max_age_seconds", n)
+}
+
+// readCacheControl mengembalikan nilai Cache-Control untuk response baca. Response ditandai private jika
+// membutuhkan credential atau datanya dipisah per tenant, agar proxy atau CDN bersama tidak menyajikannya
+// ke client lain.
+func readCacheControl(r *http.Request) string {
+	scope := "public"
+	if protectReadsEnabled() || multiTenantEnabled() || r.Header.Get("Authorization") != "" ||
+		r.Header.Get("X-API-Key") != "" || r.Header.Get("X-Admin-Key") != "" {
+		scope = "private"
+	}
+	if cacheMaxAge == 0 {
+		return scope + ", no-cache"
+	}
+	return fmt.Sprintf("%s, max-age=%d, must-revalidate", scope, cacheMaxAge)
+}
+
+// cacheControlMiddleware memberi Cache-Control default pada response GET. Handler dengan kebijakan sendiri
+// (stream SSE, download foto) menimpanya; response error diberi no-store agar kegagalan sementara tidak
+// ikut disimpan selama max-age.
+func cacheControlMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", readCacheControl(r))
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w}, r)
+	})
+}
+
+// cacheControlWriter membungkus ResponseWriter untuk mengganti Cache-Control response error
+type cacheControlWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code >= http.StatusBadRequest {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	if code >= http.StatusOK {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// setLastModified menulis header Last-Modified; waktu kosong (dokumen lama tanpa timestamp) diabaikan
+func setLastModified(w http.ResponseWriter, t time.Time) {
+	if !t.IsZero() {
+		w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModifiedSince mengecek If-Modified-Since terhadap t. Header ini hanya dipakai jika client tidak
+// mengirim If-None-Match, karena ETag lebih presisi daripada HTTP-date yang hanya sampai detik.
+func notModifiedSince(r *http.Request, t time.Time) bool {
+	if t.IsZero() || r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !t.Truncate(time.Second).After(since)
+}
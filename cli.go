@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// cliActor adalah pelaku write di audit log untuk semua perintah CLI
+	cliActor = "cli"
+	// defaultPurgeTrashAge adalah umur minimum lokasi di trash sebelum dihapus permanen oleh purge-trash
+	defaultPurgeTrashAge = 30 * 24 * time.Hour
+	// purgeTrashBatchSize adalah jumlah lokasi trash yang dibaca per putaran purge-trash
+	purgeTrashBatchSize = 500
+)
+
+// seedFiles berisi data contoh untuk perintah seed
+//
+//go:embed seed
+var seedFiles embed.FS
+
+// cliCommand adalah satu subcommand admin. run menerima argumen setelah nama subcommand dan Server yang
+// sudah terhubung ke MongoDB, sehingga perintah CLI memakai repository dan aturan yang sama dengan API.
+type cliCommand struct {
+	summary string
+	run     func(ctx context.Context, s *Server, args []string) error
+}
+
+// cliCommands adalah daftar subcommand selain serve
+var cliCommands = map[string]cliCommand{
+	"seed":        {summary: "load sample locations, or a GeoJSON file with -file", run: runSeed},
+	"reindex":     {summary: "recreate the 2dsphere and text indexes and verify all other indexes", run: runReindex},
+	"export":      {summary: "write all locations as GeoJSON or CSV to stdout or -o", run: runExport},
+	"import":      {summary: "import a GeoJSON FeatureCollection file like POST /locations/import", run: runImport},
+	"purge-trash": {summary: "permanently delete locations that have been in the trash for -older-than", run: runPurgeTrash},
+}
+
+// printUsage menulis daftar subcommand
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-mongo-railway [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintf(w, "  %-12s %s\n", "serve", "run the HTTP API (default when no command is given)")
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, cliCommands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run go-mongo-railway <command> -h for the flags of a command.")
+}
+
+// isHelpFlag mengecek apakah argumen meminta bantuan
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// runCLI menghubungkan ke MongoDB lalu menjalankan satu subcommand dan mengembalikan exit code-nya.
+// Ctrl-C atau SIGTERM membatalkan context sehingga perintah yang panjang berhenti dengan rapi.
+func runCLI(name string, cmd cliCommand, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := NewServer(initDB(config))
+	if config.PhotoStorage == "s3" {
+		s.photos = newS3PhotoStore(config)
+	}
+	defer func() {
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := s.client.Disconnect(disconnectCtx); err != nil {
+			slog.Warn("MongoDB disconnect failed", "error", err)
+		}
+	}()
+
+	err := cmd.run(ctx, s, args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		slog.Error(name+" failed", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage menandai argumen yang salah; pesannya sudah ditulis oleh FlagSet
+var errUsage = errors.New("invalid usage")
+
+// newCLIFlags membuat FlagSet untuk satu subcommand
+func newCLIFlags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-mongo-railway %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// tenantFlag menambahkan flag -tenant untuk perintah yang membaca atau menulis lokasi
+func tenantFlag(fs *flag.FlagSet) *string {
+	return fs.String("tenant", "", "tenant ID to act on; empty means locations without a tenant")
+}
+
+// parseCLIFlags mem-parse argumen dan menolak argumen posisional yang tidak dikenal
+func parseCLIFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %q\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// cliRequest menyiapkan context dan request sintetis untuk perintah CLI, seperti grpcRequest untuk gRPC,
+// agar helper yang membutuhkan *http.Request (audit log, pembersihan foto) bisa dipakai ulang. Tenant
+// disimpan di context bila diisi, dan pelaku di audit log selalu cli.
+func cliRequest(ctx context.Context, name, tenant string) (*http.Request, error) {
+	if tenant != "" && !validTenantID(tenant) {
+		return nil, fmt.Errorf("-tenant must be 1-%d lowercase letters, digits, - or _", maxTenantIDLength)
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, newRequestID())
+	ctx = context.WithValue(ctx, auditActorKey{}, cliActor)
+	if tenant != "" {
+		ctx = context.WithValue(ctx, tenantKey{}, tenant)
+	}
+	return http.NewRequestWithContext(ctx, "CLI", "/"+name, nil)
+}
+
+// readFeatureCollection membaca file GeoJSON FeatureCollection; "-" berarti stdin
+func readFeatureCollection(path string) (importCollection, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return importCollection{}, err
+		}
+		defer f.Close()
+		in = f
+	}
+	var fc importCollection
+	if err := json.NewDecoder(in).Decode(&fc); err != nil {
+		return importCollection{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return importCollection{}, fmt.Errorf("%s must be a GeoJSON FeatureCollection", path)
+	}
+	return fc, nil
+}
+
+// importFeatures menyimpan feature dengan aturan POST /locations/import lalu mencatat hasilnya di log.
+// Feature yang ditolak (termasuk nama yang sudah ada) tidak menggagalkan perintah.
+func (s *Server) importFeatures(r *http.Request, features []importFeature) error {
+	ctx := r.Context()
+	locs, indexes, featureErrors, err := s.prepareImport(ctx, features, time.Now())
+	if err != nil {
+		return err
+	}
+	inserted, insertErrors, err := insertImport(ctx, s.collection, locs, indexes)
+	featureErrors = append(featureErrors, insertErrors...)
+	if len(inserted) > 0 {
+		s.bumpCollectionVersion(ctx)
+		s.recordAudit(r, createAuditEvents(inserted)...)
+	}
+	if err != nil {
+		return err
+	}
+	sort.Slice(featureErrors, func(i, j int) bool { return featureErrors[i].Index < featureErrors[j].Index })
+	for _, ferr := range featureErrors {
+		requestLogger(r).Warn("feature skipped", "index", ferr.Index, "reason", ferr.Error, "fields", ferr.Fields)
+	}
+	requestLogger(r).Info("import finished", "total", len(features), "inserted", len(inserted), "skipped", len(featureErrors))
+	return nil
+}
+
+// runSeed memuat data contoh yang ter-embed (atau file -file). Lokasi yang namanya sudah ada dilewati,
+// sehingga seed aman dijalankan berulang kali.
+func runSeed(ctx context.Context, s *Server, args []string) error {
+	fs := newCLIFlags("seed", "[-file locations.geojson] [-tenant id]")
+	tenant := tenantFlag(fs)
+	file := fs.String("file", "", "GeoJSON FeatureCollection to load instead of the built-in sample data")
+	if err := parseCLIFlags(fs, args); err != nil {
+		return err
+	}
+	r, err := cliRequest(ctx, "seed", *tenant)
+	if err != nil {
+		return err
+	}
+
+	var fc importCollection
+	if *file != "" {
+		if fc, err = readFeatureCollection(*file); err != nil {
+			return err
+		}
+	} else {
+		data, err := seedFiles.ReadFile("seed/locations.geojson")
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &fc); err != nil {
+			return err
+		}
+	}
+	return s.importFeatures(r, fc.Features)
+}
+
+// runImport mengimport file GeoJSON FeatureCollection tanpa batas ukuran body HTTP
+func runImport(ctx context.Context, s *Server, args []string) error {
+	fs := newCLIFlags("import", "-file locations.geojson [-tenant id]")
+	tenant := tenantFlag(fs)
+	file := fs.String("file", "", "GeoJSON FeatureCollection to import, or - for stdin (required)")
+	if err := parseCLIFlags(fs, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fmt.Fprintln(fs.Output(), "-file is required")
+		fs.Usage()
+		return errUsage
+	}
+	r, err := cliRequest(ctx, "import", *tenant)
+	if err != nil {
+		return err
+	}
+	fc, err := readFeatureCollection(*file)
+	if err != nil {
+		return err
+	}
+	if len(fc.Features) == 0 {
+		return errors.New("features must not be empty")
+	}
+	return s.importFeatures(r, fc.Features)
+}
+
+// runExport menulis semua lokasi aktif dengan format yang sama seperti GET /locations/export
+func runExport(ctx context.Context, s *Server, args []string) error {
+	fs := newCLIFlags("export", "[-format geojson|csv] [-o file] [-tenant id]")
+	tenant := tenantFlag(fs)
+	format := fs.String("format", formatGeoJSON, "output format: geojson or csv")
+	output := fs.String("o", "-", "file to write, or - for stdout")
+	if err := parseCLIFlags(fs, args); err != nil {
+		return err
+	}
+	if *format != formatGeoJSON && *format != formatCSV {
+		fmt.Fprintf(fs.Output(), "-format must be either %s or %s\n", formatGeoJSON, formatCSV)
+		return errUsage
+	}
+	r, err := cliRequest(ctx, "export", *tenant)
+	if err != nil {
+		return err
+	}
+	ctx = r.Context()
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if *output != "-" {
+		if file, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	cursor, err := s.exportCursor(ctx)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	if *format == formatCSV {
+		err = streamLocationsCSV(ctx, out, cursor)
+	} else {
+		err = streamLocationsGeoJSON(ctx, out, cursor)
+	}
+	if err != nil {
+		return err
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
+
+// runReindex menghapus lalu membuat ulang index 2dsphere dan text, misalnya setelah index rusak atau bahasa
+// index text perlu diganti, kemudian memastikan semua index lain ada. Selama dibangun ulang, query geo dan
+// search pada server yang berjalan gagal, jadi jalankan di luar jam sibuk.
+func runReindex(ctx context.Context, s *Server, args []string) error {
+	fs := newCLIFlags("reindex", "")
+	if err := parseCLIFlags(fs, args); err != nil {
+		return err
+	}
+	s.ctx = ctx
+	if err := s.client.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("MongoDB is not reachable: %w", err)
+	}
+
+	specs, err := s.collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if !isRebuiltIndex(spec.KeysDocument) {
+			continue
+		}
+		if _, err := s.collection.Indexes().DropOne(ctx, spec.Name); err != nil {
+			return fmt.Errorf("drop index %s: %w", spec.Name, err)
+		}
+		slog.Info("index dropped", "name", spec.Name)
+	}
+	return errors.Join(s.ensurePrimaryIndexes(), s.createSecondaryIndexes())
+}
+
+// isRebuiltIndex mengecek apakah index memakai tipe 2dsphere atau text, yaitu index yang dibuat ulang reindex
+func isRebuiltIndex(keys bson.Raw) bool {
+	elems, err := keys.Elements()
+	if err != nil {
+		return false
+	}
+	for _, elem := range elems {
+		if kind, ok := elem.Value().StringValueOK(); ok && (kind == "2dsphere" || kind == "text") {
+			return true
+		}
+	}
+	return false
+}
+
+// runPurgeTrash menghapus permanen lokasi yang sudah lebih lama dari -older-than di trash, satu per satu lewat
+// repository seperti DELETE /locations/{id}/purge, sehingga audit log dan file foto ikut dibereskan
+func runPurgeTrash(ctx context.Context, s *Server, args []string) error {
+	fs := newCLIFlags("purge-trash", "[-older-than 720h] [-dry-run] [-tenant id]")
+	tenant := tenantFlag(fs)
+	olderThan := fs.Duration("older-than", defaultPurgeTrashAge, "minimum time a location has been in the trash")
+	dryRun := fs.Bool("dry-run", false, "only count the locations that would be purged")
+	if err := parseCLIFlags(fs, args); err != nil {
+		return err
+	}
+	if *olderThan < 0 {
+		fmt.Fprintln(fs.Output(), "-older-than must not be negative")
+		return errUsage
+	}
+	r, err := cliRequest(ctx, "purge-trash", *tenant)
+	if err != nil {
+		return err
+	}
+	ctx = r.Context()
+	log := requestLogger(r)
+
+	cutoff := time.Now().Add(-*olderThan)
+	filter := bson.M{"deleted_at": bson.M{"$ne": nil, "$lte": cutoff}}
+	if *dryRun {
+		count, err := s.locations.Count(ctx, filter)
+		if err != nil {
+			return err
+		}
+		log.Info("purge-trash dry run", "would_purge", count, "deleted_before", cutoff.Format(time.RFC3339))
+		return nil
+	}
+
+	purged := 0
+	after := primitive.NilObjectID
+	for {
+		page := bson.M{"deleted_at": filter["deleted_at"], "_id": bson.M{"$gt": after}}
+		locations, skipped, err := s.locations.List(ctx, page, bson.D{{Key: "_id", Value: 1}}, purgeTrashBatchSize, 0)
+		if err != nil {
+			return err
+		}
+		for _, msg := range skipped {
+			log.Warn("trashed location cannot be decoded and was not purged", "warning", msg)
+		}
+		if len(locations) == 0 {
+			break
+		}
+		for _, loc := range locations {
+			deleted, err := s.locations.Purge(ctx, loc.ID)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				// Sudah di-restore atau di-purge oleh request lain sejak dibaca
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			purged++
+			s.recordAudit(r, newAuditEvent(auditPurge, &deleted, nil))
+			s.deletePhotoFiles(r, deleted.Photos...)
+			s.deleteFavoritesOf(r, deleted.ID)
+		}
+		after = locations[len(locations)-1].ID
+	}
+	if purged > 0 {
+		s.bumpCollectionVersion(ctx)
+	}
+	log.Info("purge-trash finished", "purged", purged, "deleted_before", cutoff.Format(time.RFC3339))
+	return nil
+}
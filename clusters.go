@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// clusterCellsPerTile adalah jumlah sel per sisi satu tile 256 px, sehingga satu cluster kira-kira 64 px
+	clusterCellsPerTile = 4
+	// maxClusterPoints adalah jumlah titik maksimum yang dikirim satu per satu pada zoom tinggi
+	maxClusterPoints = 5000
+)
+
+// parseZoom membaca query param zoom sebagai level zoom peta 0..maxTileZoom
+func parseZoom(r *http.Request) (int, error) {
+	zoom, err := strconv.Atoi(r.URL.Query().Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxTileZoom {
+		return 0, fmt.Errorf("zoom must be an integer between 0 and %d", maxTileZoom)
+	}
+	return zoom, nil
+}
+
+// clustersHandler mengembalikan lokasi di dalam ?bbox= sebagai GeoJSON yang siap digambar untuk ?zoom= tertentu.
+// Sampai maxClusterZoom, titik dikelompokkan per sel grid global (lebar sel mengikuti zoom, sehingga cluster
+// tidak berpindah saat peta digeser) dan sel berisi satu lokasi dikirim sebagai lokasi itu sendiri; di atasnya
+// semua lokasi dikirim satu per satu, paling banyak maxClusterPoints.
+// Sel dihitung dalam derajat, bukan Mercator, sehingga di lintang tinggi sel tampak lebih tinggi daripada lebar.
+func (s *Server) clustersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	bbox, err := parseBBox(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	zoom, err := parseZoom(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.checkCollectionETag(w, r) {
+		return
+	}
+
+	if zoom > maxClusterZoom {
+		cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, bbox.geoWithinFilter()), options.Find().SetLimit(maxClusterPoints+1))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer cursor.Close(ctx)
+		locations, skipped, err := decodeLocations(ctx, cursor)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if len(locations) > maxClusterPoints {
+			locations = locations[:maxClusterPoints]
+			skipped = append(skipped, fmt.Sprintf("only the first %d locations in the bbox are returned, zoom in to see the rest", maxClusterPoints))
+		}
+		setWarningHeaders(w, skipped)
+		w.Header().Set("Content-Type", "application/geo+json")
+		writeJSON(w, http.StatusOK, locationsToFeatureCollection(locations))
+		return
+	}
+
+	cellSize := 360 / float64(int(1)<<zoom*clusterCellsPerTile)
+	cells, err := s.gridCells(ctx, bbox, -180, -90, cellSize, cellSize)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	var singles []primitive.ObjectID
+	for _, c := range cells {
+		if c.Count == 1 {
+			singles = append(singles, c.FirstID)
+		}
+	}
+	byID := map[primitive.ObjectID]Location{}
+	if len(singles) > 0 {
+		byID, err = s.findLocationsByIDs(ctx, singles)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+
+	features := make([]Feature, 0, len(cells))
+	for _, c := range cells {
+		if loc, ok := byID[c.FirstID]; ok && c.Count == 1 {
+			features = append(features, locationToFeature(loc))
+			continue
+		}
+		features = append(features, clusterFeature(c))
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	writeJSON(w, http.StatusOK, newFeatureCollection(features))
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"compress/gzip"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultCompressionMinBytes adalah ukuran response minimum (byte) yang dikompres; di bawahnya header gzip
+// dan biaya CPU tidak sebanding dengan penghematannya
+const defaultCompressionMinBytes = 1024
+
+// compressionMinBytes adalah ambang kompresi aktif; 0 berarti kompresi dimatikan
+var compressionMinBytes = defaultCompressionMinBytes
+
+// loadCompression membaca COMPRESSION_MIN_BYTES dari environment; "0" mematikan kompresi, misalnya jika
+// proxy di depan aplikasi sudah mengompres response
+func loadCompression() {
+	raw := os.Getenv("COMPRESSION_MIN_BYTES")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		fatal("COMPRESSION_MIN_BYTES must be a non-negative integer", "value", raw)
+	}
+	compressionMinBytes = n
+	if n == 0 {
+		slog.Info("response compression disabled")
+		return
+	}
+	slog.Info("response compression configured", "min_bytes", n)
+}
+
+// gzipWriterPool menyimpan gzip.Writer agar buffer kompresi (ratusan KB per writer) tidak dialokasikan per request
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// compressibleTypes adalah prefix Content-Type yang layak dikompres. Gambar sudah terkompresi, dan
+// text/event-stream sengaja tidak ada agar setiap event SSE langsung sampai tanpa menunggu buffer gzip.
+var compressibleTypes = []string{
+	"application/json",
+	"application/geo+json",
+	"application/msgpack",
+	"text/csv",
+	"text/html",
+}
+
+// acceptsGzip mengecek apakah header Accept-Encoding mengizinkan gzip. "gzip;q=0" menolak gzip walaupun ada
+// "*"; "*" dengan q > 0 berarti semua encoding diterima.
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if name == "gzip" {
+			gzipQ = q
+		} else {
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// compressionMiddleware mengompres response dengan gzip jika client mengizinkannya lewat Accept-Encoding.
+// Hanya gzip yang didukung karena didukung semua client HTTP dan tersedia di library standar. ETag tidak diubah,
+// agar ETag dari GET yang dikompres tetap bisa dipakai sebagai If-Match saat update.
+func compressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if compressionMinBytes == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, accepts: acceptsGzip(r.Header.Get("Accept-Encoding"))}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter menahan awal body hingga compressionMinBytes sebelum memutuskan apakah response dikompres,
+// sehingga response kecil (error, satu lokasi) tetap dikirim apa adanya. Status code juga ditahan karena header
+// Content-Encoding harus sudah diset sebelum WriteHeader diteruskan.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	accepts bool
+	// checked dan ok menyimpan hasil compressible, yang hanya dihitung sekali dari header pertama
+	checked bool
+	ok      bool
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	// Status informasional (1xx) dan status setelah keputusan diambil langsung diteruskan
+	if w.decided || code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	// Response tanpa body tidak perlu ditahan; 304 tetap membawa Vary yang sama dengan response 200-nya
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		w.compressible()
+		w.start(false)
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.start(false)
+		} else {
+			w.buf = append(w.buf, b...)
+			if len(w.buf) < compressionMinBytes {
+				return len(b), nil
+			}
+			if err := w.start(true); err != nil {
+				return 0, err
+			}
+			return len(b), nil
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush mengirim data yang sudah ditulis ke client. Response yang di-flush sebelum mencapai ambang tetap
+// dikompres jika tipenya cocok, karena handler yang melakukan flush biasanya streaming data besar (export).
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.start(w.compressible() && len(w.buf) > 0)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap membuat http.ResponseController bisa mencapai ResponseWriter asli
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible mengecek apakah response boleh dikompres berdasarkan header yang sudah diset handler. Vary
+// ditambahkan untuk semua response bertipe kompresibel, termasuk yang tidak jadi dikompres, agar cache
+// menyimpan versi gzip dan versi biasa secara terpisah.
+func (w *gzipResponseWriter) compressible() bool {
+	if w.checked {
+		return w.ok
+	}
+	w.checked = true
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			h.Add("Vary", "Accept-Encoding")
+			w.ok = w.accepts
+			return w.ok
+		}
+	}
+	return false
+}
+
+// start meneruskan status code yang ditahan beserta body yang sudah di-buffer, dengan atau tanpa gzip
+func (w *gzipResponseWriter) start(compress bool) error {
+	w.decided = true
+	if compress {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		// Content-Length yang diset handler adalah panjang sebelum dikompres
+		h.Del("Content-Length")
+		w.gz = gzipWriterPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close mengirim sisa buffer setelah handler selesai dan menutup stream gzip
+func (w *gzipResponseWriter) close() {
+	if !w.decided && (w.status != 0 || len(w.buf) > 0) {
+		w.start(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+	}
+}
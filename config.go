@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultDatabase dan defaultCollection hanya untuk pengembangan lokal; staging dan production yang
+	// berbagi satu cluster harus mengatur MONGO_DB dan/atau MONGO_COLLECTION sendiri-sendiri
+	defaultDatabase   = "test"
+	defaultCollection = "locations"
+	// defaultPort dipakai jika PORT tidak diset (Railway selalu mengisinya)
+	defaultPort = "8080"
+	// defaultRequestTimeout adalah batas waktu default setiap request jika REQUEST_TIMEOUT tidak diset
+	defaultRequestTimeout = 5 * time.Second
+	// defaultServerSelectionTimeout adalah waktu tunggu driver mencari server MongoDB yang sehat per operasi
+	defaultServerSelectionTimeout = 5 * time.Second
+	// defaultMaxPoolSize adalah jumlah koneksi MongoDB maksimum per instance
+	defaultMaxPoolSize = 100
+)
+
+// Config berisi pengaturan inti aplikasi yang dibaca sekali saat startup. Pengaturan tuning yang opsional
+// (presisi koordinat, batas body, dan sejenisnya) tetap dibaca oleh load* masing-masing.
+type Config struct {
+	MongoURI   string
+	Database   string
+	Collection string
+	// ServerSelectionTimeout dan MaxPoolSize diteruskan ke client MongoDB
+	ServerSelectionTimeout time.Duration
+	MaxPoolSize            uint64
+
+	Port string
+	// GRPCPort adalah port server gRPC LocationService; kosong berarti server gRPC tidak dijalankan
+	GRPCPort    string
+	TLSCertFile string
+	TLSKeyFile  string
+	// RequestTimeout adalah batas waktu default setiap request (termasuk operasi database di dalamnya)
+	RequestTimeout time.Duration
+	CORS           corsConfig
+
+	APIKey      string
+	JWTSecret   string
+	AdminAPIKey string
+	// TenantKeys memetakan API key ke ID tenant (TENANT_API_KEYS); kosong berarti multi-tenancy dimatikan
+	TenantKeys map[string]string
+
+	// ReadCacheTTL adalah umur maksimum entri read cache, sekaligus batas data usang jika event change stream
+	// terlambat; 0 berarti read cache dimatikan
+	ReadCacheTTL        time.Duration
+	ReadCacheMaxEntries int
+
+	// WebhookURLs menerima event geofence; kosong berarti evaluasi geofence dimatikan
+	WebhookURLs   []string
+	WebhookSecret string
+
+	// Geocoder adalah provider geocoding (nominatim, google, atau mapbox); kosong berarti dimatikan.
+	// GeocoderAPIKey wajib untuk google dan mapbox; GeocoderURL hanya dipakai nominatim.
+	Geocoder           string
+	GeocoderAPIKey     string
+	GeocoderURL        string
+	AutoGeocodeAddress bool
+
+	// PhotoStorage adalah tempat file foto disimpan: gridfs (default) atau s3. S3Endpoint kosong berarti AWS;
+	// isi untuk layanan S3-compatible seperti MinIO atau R2.
+	PhotoStorage string
+	S3Endpoint   string
+	S3Bucket     string
+	S3Region     string
+	S3AccessKey  string
+	S3SecretKey  string
+
+	LogLevel  slog.Level
+	LogFormat string
+}
+
+// config adalah konfigurasi aktif, diisi oleh main sebelum server dibuat
+var config = Config{RequestTimeout: defaultRequestTimeout}
+
+// loadConfig membaca dan memvalidasi konfigurasi dari environment. Semua masalah dikumpulkan lalu dikembalikan
+// sekaligus, agar deploy yang salah konfigurasi cukup diperbaiki sekali, bukan satu variabel per restart.
+func loadConfig() (Config, []string) {
+	cfg := Config{
+		MongoURI:               os.Getenv("MONGO_PUBLIC_URL"),
+		Database:               envOr("MONGO_DB", defaultDatabase),
+		Collection:             envOr("MONGO_COLLECTION", defaultCollection),
+		ServerSelectionTimeout: defaultServerSelectionTimeout,
+		MaxPoolSize:            defaultMaxPoolSize,
+		Port:                   envOr("PORT", defaultPort),
+		GRPCPort:               os.Getenv("GRPC_PORT"),
+		TLSCertFile:            os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:             os.Getenv("TLS_KEY_FILE"),
+		RequestTimeout:         defaultRequestTimeout,
+		ReadCacheMaxEntries:    defaultReadCacheMaxEntries,
+		CORS:                   loadCORSConfig(),
+		APIKey:                 os.Getenv("API_KEY"),
+		JWTSecret:              os.Getenv("JWT_SECRET"),
+		AdminAPIKey:            os.Getenv("ADMIN_API_KEY"),
+		WebhookURLs:            envList("GEOFENCE_WEBHOOK_URLS"),
+		WebhookSecret:          os.Getenv("GEOFENCE_WEBHOOK_SECRET"),
+		Geocoder:               strings.ToLower(os.Getenv("GEOCODER")),
+		GeocoderAPIKey:         os.Getenv("GEOCODER_API_KEY"),
+		GeocoderURL:            strings.TrimSuffix(envOr("GEOCODER_URL", nominatimDefaultURL), "/"),
+		AutoGeocodeAddress:     os.Getenv("AUTO_GEOCODE_ADDRESS") == "true",
+		PhotoStorage:           strings.ToLower(envOr("PHOTO_STORAGE", "gridfs")),
+		S3Bucket:               os.Getenv("S3_BUCKET"),
+		S3Region:               envOr("S3_REGION", defaultS3Region),
+		S3AccessKey:            os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretKey:            os.Getenv("S3_SECRET_ACCESS_KEY"),
+		LogFormat:              envOr("LOG_FORMAT", "json"),
+	}
+
+	var problems []string
+	if cfg.MongoURI == "" {
+		problems = append(problems, "MONGO_PUBLIC_URL is required")
+	}
+	if raw := os.Getenv("MONGO_SERVER_SELECTION_TIMEOUT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			problems = append(problems, fmt.Sprintf("MONGO_SERVER_SELECTION_TIMEOUT must be a positive duration, got %q", raw))
+		} else {
+			cfg.ServerSelectionTimeout = d
+		}
+	}
+	if raw := os.Getenv("MONGO_MAX_POOL_SIZE"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || n == 0 {
+			problems = append(problems, fmt.Sprintf("MONGO_MAX_POOL_SIZE must be a positive integer, got %q", raw))
+		} else {
+			cfg.MaxPoolSize = n
+		}
+	}
+	if n, err := strconv.Atoi(cfg.Port); err != nil || n < 1 || n > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT must be a number between 1 and 65535, got %q", cfg.Port))
+	}
+	if cfg.GRPCPort != "" {
+		if n, err := strconv.Atoi(cfg.GRPCPort); err != nil || n < 1 || n > 65535 {
+			problems = append(problems, fmt.Sprintf("GRPC_PORT must be a number between 1 and 65535, got %q", cfg.GRPCPort))
+		} else if cfg.GRPCPort == cfg.Port {
+			problems = append(problems, "GRPC_PORT must differ from PORT")
+		}
+	}
+	tenantKeys, tenantProblems := parseTenantKeys(envList("TENANT_API_KEYS"))
+	cfg.TenantKeys = tenantKeys
+	problems = append(problems, tenantProblems...)
+	if _, ok := cfg.TenantKeys[cfg.APIKey]; ok && cfg.APIKey != "" {
+		problems = append(problems, "TENANT_API_KEYS must not reuse API_KEY, which may act for any tenant")
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if raw := os.Getenv("REQUEST_TIMEOUT"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > maxRequestTimeout {
+			problems = append(problems, fmt.Sprintf("REQUEST_TIMEOUT must be a positive duration no longer than %s, got %q", maxRequestTimeout, raw))
+		} else {
+			cfg.RequestTimeout = d
+		}
+	}
+	if raw := os.Getenv("READ_CACHE_TTL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			problems = append(problems, fmt.Sprintf("READ_CACHE_TTL must be a non-negative duration, got %q", raw))
+		} else {
+			cfg.ReadCacheTTL = d
+		}
+	}
+	if raw := os.Getenv("READ_CACHE_MAX_ENTRIES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			problems = append(problems, fmt.Sprintf("READ_CACHE_MAX_ENTRIES must be a positive integer, got %q", raw))
+		} else {
+			cfg.ReadCacheMaxEntries = n
+		}
+	}
+	for _, raw := range cfg.WebhookURLs {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("GEOFENCE_WEBHOOK_URLS must contain absolute http(s) URLs, got %q", raw))
+		}
+	}
+	switch cfg.Geocoder {
+	case "", "nominatim":
+	case "google", "mapbox":
+		if cfg.GeocoderAPIKey == "" {
+			problems = append(problems, fmt.Sprintf("GEOCODER_API_KEY is required when GEOCODER=%s", cfg.Geocoder))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("GEOCODER must be one of nominatim, google or mapbox, got %q", cfg.Geocoder))
+	}
+	if cfg.AutoGeocodeAddress && cfg.Geocoder == "" {
+		problems = append(problems, "AUTO_GEOCODE_ADDRESS requires GEOCODER to be set")
+	}
+	switch cfg.PhotoStorage {
+	case "gridfs":
+	case "s3":
+		if cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			problems = append(problems, "S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when PHOTO_STORAGE=s3")
+		}
+		endpoint, err := parseS3Endpoint(os.Getenv("S3_ENDPOINT"), cfg.S3Region)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		cfg.S3Endpoint = endpoint
+	default:
+		problems = append(problems, fmt.Sprintf("PHOTO_STORAGE must be either gridfs or s3, got %q", cfg.PhotoStorage))
+	}
+	if raw := os.Getenv("LOG_LEVEL"); raw != "" {
+		if err := cfg.LogLevel.UnmarshalText([]byte(raw)); err != nil {
+			problems = append(problems, fmt.Sprintf("LOG_LEVEL must be one of debug, info, warn or error, got %q", raw))
+		}
+	}
+	if cfg.LogFormat != "json" && cfg.LogFormat != "text" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be either json or text, got %q", cfg.LogFormat))
+	}
+	return cfg, problems
+}
+
+// envOr membaca variabel environment, atau def jika kosong
+func envOr(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// logConfig mencatat ringkasan konfigurasi tanpa membocorkan secret maupun kredensial di URI MongoDB
+func (c Config) logConfig() {
+	if c.Database == defaultDatabase || c.Collection == defaultCollection {
+		slog.Warn("using a default database or collection name, set MONGO_DB and MONGO_COLLECTION outside local development",
+			"database", c.Database, "collection", c.Collection)
+	}
+	slog.Info("configuration loaded",
+		"database", c.Database,
+		"collection", c.Collection,
+		"server_selection_timeout", c.ServerSelectionTimeout,
+		"max_pool_size", c.MaxPoolSize,
+		"port", c.Port,
+		"grpc_port", c.GRPCPort,
+		"tls", c.TLSCertFile != "",
+		"request_timeout", c.RequestTimeout,
+		"read_cache_ttl", c.ReadCacheTTL,
+		"cors_origins", c.CORS.Origins,
+		"api_key_set", c.APIKey != "",
+		"jwt_secret_set", c.JWTSecret != "",
+		"admin_api_key_set", c.AdminAPIKey != "",
+		"multi_tenant", len(c.TenantKeys) > 0,
+		"photo_storage", c.PhotoStorage,
+		"log_level", c.LogLevel.String())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go-mongo-railway/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// rawDocumentID mengambil _id dari dokumen mentah sebagai string untuk pesan error, "unknown" jika tidak ada
+func rawDocumentID(raw bson.Raw) string {
+	val, err := raw.LookupErr("_id")
+	if err != nil {
+		return "unknown"
+	}
+	if oid, ok := val.ObjectIDOK(); ok {
+		return oid.Hex()
+	}
+	return val.String()
+}
+
+// decodeLocations membaca semua dokumen dari cursor. Dokumen yang bentuknya tidak cocok dengan Location
+// (misalnya sisa schema lama) dilewati dan dilaporkan sebagai peringatan, alih-alih menggagalkan seluruh request.
+// Error selain decode (koneksi, timeout) tetap dikembalikan sebagai error.
+func decodeLocations(ctx context.Context, cursor *mongo.Cursor) ([]Location, []string, error) {
+	locations := []Location{}
+	var skipped []string
+	for cursor.Next(ctx) {
+		var loc Location
+		if err := bson.Unmarshal(cursor.Current, &loc); err != nil {
+			id := rawDocumentID(cursor.Current)
+			slog.Warn("skipping location that cannot be decoded", "id", id, "error", err)
+			skipped = append(skipped, fmt.Sprintf("skipped location %s that could not be decoded", id))
+			continue
+		}
+		locations = append(locations, loc)
+	}
+	return locations, skipped, cursor.Err()
+}
+
+// decodeError menandakan dokumen ada di database tetapi bentuknya tidak cocok dengan Location
+type decodeError = repository.DecodeError
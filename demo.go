@@ -0,0 +1,27 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+)
+
+// demoFiles berisi halaman peta demo statis yang ikut ter-embed di dalam binary
+//
+//go:embed demo
+var demoFiles embed.FS
+
+// demoEnabled mengecek apakah halaman demo diaktifkan lewat SERVE_DEMO=true (nonaktif secara default)
+func demoEnabled() bool {
+	return os.Getenv("SERVE_DEMO") == "true"
+}
+
+// demoHandler menyajikan halaman peta demo Leaflet yang memanggil endpoint GeoJSON per tile
+func demoHandler() http.Handler {
+	sub, err := fs.Sub(demoFiles, "demo")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(sub))
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxMatrixCells adalah batas jumlah sel (len(from) * len(to)) pada distance matrix
+const maxMatrixCells = 2500
+
+// matrixPoint adalah satu titik distance matrix: ID lokasi tersimpan (string) atau koordinat ad-hoc [lng, lat]
+type matrixPoint struct {
+	ID       string
+	Position []float64
+}
+
+// UnmarshalJSON menerima string sebagai ID lokasi dan array dua angka sebagai koordinat
+func (p *matrixPoint) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.ID); err == nil {
+		return nil
+	}
+	var pos []float64
+	if err := json.Unmarshal(data, &pos); err != nil {
+		return errors.New("each point must be a location ID or a [lng, lat] pair")
+	}
+	if err := validatePosition(pos); err != nil {
+		return err
+	}
+	p.Position = pos
+	return nil
+}
+
+// MarshalJSON mengembalikan titik dalam bentuk yang sama seperti dikirim client
+func (p matrixPoint) MarshalJSON() ([]byte, error) {
+	if p.Position != nil {
+		return json.Marshal(p.Position)
+	}
+	return json.Marshal(p.ID)
+}
+
+// distanceMatrixRequest adalah body request untuk POST /distance-matrix
+type distanceMatrixRequest struct {
+	From []matrixPoint `json:"from"`
+	To   []matrixPoint `json:"to"`
+}
+
+// metersToKilometers membulatkan jarak meter ke kilometer dengan tiga desimal (presisi meter)
+func metersToKilometers(m float64) float64 {
+	return math.Round(m) / 1000
+}
+
+// parseObjectIDs mengubah daftar string hex menjadi ObjectID, mengembalikan error untuk ID pertama yang tidak valid
+func parseObjectIDs(hexes []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(hexes))
+	for _, h := range hexes {
+		id, err := primitive.ObjectIDFromHex(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid location ID format: %q", h)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// findLocationsByIDs mengambil semua lokasi dengan ID yang diberikan dalam satu query $in, dipetakan per ID
+func (s *Server) findLocationsByIDs(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]Location, error) {
+	cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var locations []Location
+	if err = cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]Location, len(locations))
+	for _, loc := range locations {
+		byID[loc.ID] = loc
+	}
+	return byID, nil
+}
+
+// anchorPositions mengambil posisi wakil setiap lokasi (lihat Geometry.anchorPosition), sekaligus mengembalikan
+// ID lokasi yang tidak memiliki koordinat yang bisa dipakai
+func anchorPositions(sets ...map[primitive.ObjectID]Location) (map[primitive.ObjectID][]float64, []string) {
+	positions := map[primitive.ObjectID][]float64{}
+	invalid := []string{}
+	for _, set := range sets {
+		for id, loc := range set {
+			if _, seen := positions[id]; seen {
+				continue
+			}
+			pos, ok := loc.Location.anchorPosition()
+			if !ok {
+				invalid = append(invalid, id.Hex())
+				continue
+			}
+			positions[id] = pos
+		}
+	}
+	return positions, invalid
+}
+
+// writeInvalidPositions menulis 422 untuk lokasi yang geometrinya tidak bisa dipakai menghitung jarak
+func writeInvalidPositions(w http.ResponseWriter, invalid []string) {
+	writeErrorDetails(w, http.StatusUnprocessableEntity, "invalid_coordinates", "Some locations have no valid coordinates",
+		map[string]interface{}{"invalid": invalid})
+}
+
+// distanceMatrixHandler menghitung matriks jarak haversine dari setiap titik "from" ke setiap titik "to".
+// Titik boleh berupa ID lokasi atau koordinat [lng, lat]; hasilnya dalam meter (matrix) dan kilometer.
+func (s *Server) distanceMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var req distanceMatrixRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(req.From) == 0 || len(req.To) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "from and to must both contain at least one point")
+		return
+	}
+	if len(req.From)*len(req.To) > maxMatrixCells {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("distance matrix is limited to %d cells (from x to)", maxMatrixCells))
+		return
+	}
+
+	// Semua ID dari kedua sisi diambil dengan satu query
+	var hexes []string
+	for _, p := range append(append([]matrixPoint{}, req.From...), req.To...) {
+		if p.Position == nil {
+			hexes = append(hexes, p.ID)
+		}
+	}
+	ids, err := parseObjectIDs(hexes)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	locations := map[primitive.ObjectID]Location{}
+	if len(ids) > 0 {
+		locations, err = s.findLocationsByIDs(ctx, ids)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+
+	missing := []string{}
+	for _, id := range ids {
+		if _, ok := locations[id]; !ok {
+			missing = append(missing, id.Hex())
+		}
+	}
+	if len(missing) > 0 {
+		writeErrorDetails(w, http.StatusNotFound, "not_found", "Some locations were not found",
+			map[string]interface{}{"missing": missing})
+		return
+	}
+
+	positions, invalid := anchorPositions(locations)
+	if len(invalid) > 0 {
+		writeInvalidPositions(w, invalid)
+		return
+	}
+	resolve := func(points []matrixPoint) [][]float64 {
+		out := make([][]float64, len(points))
+		for i, p := range points {
+			if p.Position != nil {
+				out[i] = p.Position
+				continue
+			}
+			id, _ := primitive.ObjectIDFromHex(p.ID)
+			out[i] = positions[id]
+		}
+		return out
+	}
+	from, to := resolve(req.From), resolve(req.To)
+
+	// matrix[i][j] adalah jarak dari from[i] ke to[j]
+	matrix := make([][]float64, len(from))
+	kilometers := make([][]float64, len(from))
+	for i := range from {
+		matrix[i] = make([]float64, len(to))
+		kilometers[i] = make([]float64, len(to))
+		for j := range to {
+			matrix[i][j] = haversineMeters(from[i], to[j])
+			kilometers[i][j] = metersToKilometers(matrix[i][j])
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"from":       req.From,
+		"to":         req.To,
+		"unit":       "meters",
+		"matrix":     matrix,
+		"kilometers": kilometers,
+	})
+}
+
+// locationDistanceHandler mengembalikan jarak great-circle dari lokasi {id} ke lokasi ?to=
+func (s *Server) locationDistanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		writeJSONError(w, http.StatusBadRequest, "to query parameter is required")
+		return
+	}
+	ids, err := parseObjectIDs([]string{mux.Vars(r)["id"], to})
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	locations, err := s.findLocationsByIDs(ctx, ids)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	missing := []string{}
+	for _, id := range ids {
+		if _, ok := locations[id]; !ok {
+			missing = append(missing, id.Hex())
+		}
+	}
+	if len(missing) > 0 {
+		writeErrorDetails(w, http.StatusNotFound, "not_found", "Some locations were not found",
+			map[string]interface{}{"missing": missing})
+		return
+	}
+
+	positions, invalid := anchorPositions(locations)
+	if len(invalid) > 0 {
+		writeInvalidPositions(w, invalid)
+		return
+	}
+	meters := haversineMeters(positions[ids[0]], positions[ids[1]])
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"from":       ids[0].Hex(),
+		"to":         ids[1].Hex(),
+		"meters":     meters,
+		"kilometers": metersToKilometers(meters),
+	})
+}
+
+// bearingHandler mengembalikan arah awal dan jarak great-circle dari lokasi from ke lokasi to
+func (s *Server) bearingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	if q.Get("from") == "" || q.Get("to") == "" {
+		writeJSONError(w, http.StatusBadRequest, "from and to query parameters are required")
+		return
+	}
+	ids, err := parseObjectIDs([]string{q.Get("from"), q.Get("to")})
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	locations, err := s.findLocationsByIDs(ctx, ids)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	missing := []string{}
+	for _, id := range ids {
+		if _, ok := locations[id]; !ok {
+			missing = append(missing, id.Hex())
+		}
+	}
+	if len(missing) > 0 {
+		writeErrorDetails(w, http.StatusNotFound, "not_found", "Some locations were not found",
+			map[string]interface{}{"missing": missing})
+		return
+	}
+
+	positions, invalid := anchorPositions(locations)
+	if len(invalid) > 0 {
+		writeInvalidPositions(w, invalid)
+		return
+	}
+	from, to := positions[ids[0]], positions[ids[1]]
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"bearing":  initialBearing(from, to),
+		"distance": haversineMeters(from, to),
+	})
+}
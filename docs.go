@@ -0,0 +1,44 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec adalah dokumen OpenAPI 3 yang ditulis tangan; perbarui setiap kali route atau bentuk payload berubah
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIVersion adalah versi swagger-ui-dist yang dimuat halaman /docs dari CDN
+const swaggerUIVersion = "5.17.14"
+
+// docsPage memuat Swagger UI dari CDN agar aset-nya tidak perlu ikut di-embed ke dalam binary
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Locations API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// openAPIHandler menyajikan dokumen OpenAPI
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// docsHandler menyajikan dokumentasi interaktif Swagger UI untuk /openapi.json
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
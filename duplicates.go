@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultCoincidentEpsilonMeters adalah jarak (meter) di mana dua titik dianggap berimpit saat REJECT_COINCIDENT_POINTS aktif
+const defaultCoincidentEpsilonMeters = 0.1
+
+// rejectCoincidentPointsEnabled mengecek apakah pembuatan lokasi di koordinat yang sudah terpakai harus ditolak
+func rejectCoincidentPointsEnabled() bool {
+	return os.Getenv("REJECT_COINCIDENT_POINTS") == "true"
+}
+
+// coincidentEpsilonMeters membaca COINCIDENT_EPSILON_METERS, memakai default jika kosong atau tidak valid
+func coincidentEpsilonMeters() float64 {
+	if f, err := strconv.ParseFloat(os.Getenv("COINCIDENT_EPSILON_METERS"), 64); err == nil && f > 0 {
+		return f
+	}
+	return defaultCoincidentEpsilonMeters
+}
+
+// ExactDuplicate adalah sekelompok lokasi yang memiliki koordinat persis sama.
+// IDs terurut dari yang paling lama dibuat, sehingga IDs[0] adalah dokumen yang dipertahankan saat merge.
+type ExactDuplicate struct {
+	Coordinates []float64            `bson:"_id" json:"coordinates"`
+	Count       int64                `bson:"count" json:"count"`
+	IDs         []primitive.ObjectID `bson:"ids" json:"ids"`
+}
+
+// findExactDuplicates mengelompokkan lokasi berdasarkan koordinat dan mengembalikan kelompok yang berisi lebih dari satu
+func (s *Server) findExactDuplicates(ctx context.Context) ([]ExactDuplicate, error) {
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, nil)},
+		// Urutkan dulu agar $push menyimpan ID dari yang paling lama
+		bson.M{"$sort": bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		bson.M{"$group": bson.M{
+			"_id":   "$location.coordinates",
+			"count": bson.M{"$sum": 1},
+			"ids":   bson.M{"$push": "$_id"},
+		}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	duplicates := []ExactDuplicate{}
+	if err = cursor.All(ctx, &duplicates); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// exactDuplicatesHandler mengembalikan setiap pasangan koordinat yang dipakai lebih dari satu lokasi
+func (s *Server) exactDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	duplicates, err := s.findExactDuplicates(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, duplicates)
+}
+
+// mergeExactDuplicatesHandler menghapus duplikat koordinat dan mempertahankan lokasi yang paling lama.
+// Dengan ?dryRun=true hanya menampilkan ID yang akan dihapus.
+func (s *Server) mergeExactDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	duplicates, err := s.findExactDuplicates(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	kept := []primitive.ObjectID{}
+	removed := []primitive.ObjectID{}
+	for _, d := range duplicates {
+		kept = append(kept, d.IDs[0])
+		removed = append(removed, d.IDs[1:]...)
+	}
+
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	var deleted int64
+	if !dryRun && len(removed) > 0 {
+		// Snapshot dokumen yang dihapus dibaca dulu untuk audit log, karena penghapusannya permanen
+		befores, err := s.findLocationsByIDs(ctx, removed)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		audits := make([]AuditEvent, 0, len(befores))
+		for _, id := range removed {
+			if before, ok := befores[id]; ok {
+				audits = append(audits, newAuditEvent(auditPurge, &before, nil))
+			}
+		}
+		inTxn, err := s.runInTransaction(r, func(ctx context.Context) error {
+			result, err := coll.DeleteMany(ctx, withoutDeleted(ctx, bson.M{"_id": bson.M{"$in": removed}}))
+			if err != nil {
+				return err
+			}
+			deleted = result.DeletedCount
+			if deleted == 0 {
+				return nil
+			}
+			return s.auditInTransaction(ctx, r, audits...)
+		})
+		if err != nil {
+			s.writeWriteError(w, r, err)
+			return
+		}
+		if deleted > 0 {
+			s.bumpCollectionVersion(ctx)
+			for _, id := range removed {
+				if before, ok := befores[id]; ok {
+					s.deletePhotoFiles(r, before.Photos...)
+				}
+			}
+			if !inTxn {
+				s.recordAudit(r, audits...)
+			}
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"dryRun":  dryRun,
+		"groups":  len(duplicates),
+		"kept":    kept,
+		"removed": removed,
+		"deleted": deleted,
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExampleNewServer memasang handler Server pada router dengan repository tiruan, sehingga handler CRUD bisa
+// diuji tanpa MongoDB. Client MongoDB dibuat tanpa pernah terhubung; hanya s.locations yang dipakai.
+func ExampleNewServer() {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		panic(err)
+	}
+	defer client.Disconnect(context.Background())
+	s := NewServer(client.Database("example").Collection("locations"))
+
+	id, _ := primitive.ObjectIDFromHex("6650a1b2c3d4e5f601234567")
+	repo := &fakeLocationRepository{}
+	repo.Create(context.Background(), Location{
+		ID:        id,
+		Name:      "Monas",
+		Location:  Geometry{Type: "Point", Coordinates: []float64{106.8272, -6.1754}},
+		Revision:  1,
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	})
+	s.locations = repo
+
+	r := mux.NewRouter()
+	r.HandleFunc("/locations/{id}", s.getLocationHandler).Methods("GET")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/locations/6650a1b2c3d4e5f601234567", nil))
+
+	var got Location
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	fmt.Println(rec.Code, rec.Header().Get("ETag"), got.Name)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/locations/6650a1b2c3d4e5f601234568", nil))
+	fmt.Println(rec.Code)
+	// Output:
+	// 200 "r1-0" Monas
+	// 404
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// includeExpiredKey adalah key context penanda request yang meminta lokasi kedaluwarsa ikut dikembalikan
+type includeExpiredKey struct{}
+
+// includeExpiredMiddleware membaca ?include_expired=true. Index TTL baru menghapus lokasi hingga ~60 detik
+// setelah expires_at lewat; tanpa parameter ini lokasi tersebut sudah disembunyikan sejak expires_at.
+func includeExpiredMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("include_expired") != "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), includeExpiredKey{}, true)))
+	})
+}
+
+// includeExpired mengecek apakah request meminta lokasi kedaluwarsa ikut dikembalikan
+func includeExpired(ctx context.Context) bool {
+	include, _ := ctx.Value(includeExpiredKey{}).(bool)
+	return include
+}
+
+// notExpiredFilter mencocokkan lokasi tanpa expires_at atau yang expires_at-nya masih di depan now.
+// $not dipakai alih-alih $or agar tidak bentrok dengan $or milik filter lain.
+func notExpiredFilter(now time.Time) bson.M {
+	return bson.M{"$not": bson.M{"$lte": now}}
+}
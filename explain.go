@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxDocsExamined adalah batas dokumen yang boleh diperiksa query tanpa index saat ?estimate=true
+var maxDocsExamined int64 = 10000
+
+// loadMaxDocsExamined membaca MAX_DOCS_EXAMINED dari environment (kosong berarti memakai default)
+func loadMaxDocsExamined() {
+	raw := os.Getenv("MAX_DOCS_EXAMINED")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		fatal("MAX_DOCS_EXAMINED must be a positive integer", "value", raw)
+	}
+	maxDocsExamined = n
+	slog.Info("unindexed queries examining too many documents will be refused", "max_docs_examined", n)
+}
+
+// QueryEstimate adalah ringkasan hasil explain untuk satu query find
+type QueryEstimate struct {
+	DocsExamined int64 `json:"docsExamined"`
+	UsesIndex    bool  `json:"usesIndex"`
+}
+
+// explainFind menjalankan explain (executionStats) untuk query find dengan filter, sort, limit dan skip yang sama
+// seperti query aslinya; sort boleh nil dan limit/skip 0 berarti tidak dipakai
+func (s *Server) explainFind(ctx context.Context, filter, sort interface{}, limit, skip int64) (QueryEstimate, error) {
+	find := bson.D{
+		{Key: "find", Value: s.collection.Name()},
+		{Key: "filter", Value: filter},
+	}
+	if sort != nil {
+		find = append(find, bson.E{Key: "sort", Value: sort})
+	}
+	if limit > 0 {
+		find = append(find, bson.E{Key: "limit", Value: limit})
+	}
+	if skip > 0 {
+		find = append(find, bson.E{Key: "skip", Value: skip})
+	}
+	explain := bson.D{
+		{Key: "explain", Value: find},
+		{Key: "verbosity", Value: "executionStats"},
+	}
+
+	var plan bson.Raw
+	if err := s.collection.Database().RunCommand(ctx, explain).Decode(&plan); err != nil {
+		return QueryEstimate{}, err
+	}
+	var stats struct {
+		ExecutionStats struct {
+			TotalDocsExamined int64 `bson:"totalDocsExamined"`
+		} `bson:"executionStats"`
+	}
+	if err := bson.Unmarshal(plan, &stats); err != nil {
+		return QueryEstimate{}, err
+	}
+	return QueryEstimate{
+		DocsExamined: stats.ExecutionStats.TotalDocsExamined,
+		UsesIndex:    !strings.Contains(plan.String(), "COLLSCAN"),
+	}, nil
+}
+
+// guardQueryCost dipanggil saat ?estimate=true: query di-explain dulu dan ditolak dengan 422 jika men-scan
+// koleksi tanpa index melebihi maxDocsExamined. Mengembalikan false jika response sudah ditulis.
+func (s *Server) guardQueryCost(w http.ResponseWriter, r *http.Request, filter, sort interface{}, limit, skip int64) bool {
+	if r.URL.Query().Get("estimate") != "true" {
+		return true
+	}
+	est, err := s.explainFind(r.Context(), filter, sort, limit, skip)
+	if err != nil {
+		writeDBError(w, r, err)
+		return false
+	}
+	w.Header().Set("X-Docs-Examined", strconv.FormatInt(est.DocsExamined, 10))
+	if !est.UsesIndex && est.DocsExamined > maxDocsExamined {
+		writeErrorDetails(w, http.StatusUnprocessableEntity, "query_too_expensive",
+			fmt.Sprintf("Query would examine %d documents without an index (limit %d)", est.DocsExamined, maxDocsExamined),
+			map[string]interface{}{"estimate": est})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// formatCSV hanya tersedia pada GET /locations/export
+	formatCSV = "csv"
+	// exportFlushEvery adalah jumlah lokasi yang ditulis sebelum response di-flush ke client
+	exportFlushEvery = 500
+)
+
+// csvExportHeader adalah kolom CSV export; lng/lat untuk LineString dan Polygon adalah titik wakilnya
+var csvExportHeader = []string{"id", "name", "description", "category", "geometry_type", "lng", "lat", "created_at"}
+
+// exportLocationsHandler mengalirkan seluruh koleksi sebagai GeoJSON FeatureCollection (default) atau CSV.
+// Dokumen dibaca satu per satu dari cursor dan langsung ditulis, sehingga memori tidak bergantung pada
+// ukuran koleksi. Karena status 200 sudah terkirim, error di tengah jalan hanya dicatat di log dan
+// response berhenti (JSON yang terpotong tidak akan lolos parse di client).
+func (s *Server) exportLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = formatGeoJSON
+	case formatGeoJSON, formatCSV:
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("format must be either %s or %s", formatGeoJSON, formatCSV))
+		return
+	}
+	if !s.checkCollectionETag(w, r) {
+		return
+	}
+
+	cursor, err := s.exportCursor(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if format == formatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="locations.csv"`)
+		err = streamLocationsCSV(ctx, w, cursor)
+	} else {
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Header().Set("Content-Disposition", `attachment; filename="locations.geojson"`)
+		err = streamLocationsGeoJSON(ctx, w, cursor)
+	}
+	if err != nil {
+		requestLogger(r).Error("export aborted after partial response", "error", err)
+	}
+}
+
+// exportCursor membuka cursor semua lokasi aktif milik tenant request, urut berdasarkan _id
+func (s *Server) exportCursor(ctx context.Context) (*mongo.Cursor, error) {
+	return s.collection.Find(ctx, withoutDeleted(ctx, nil), options.Find().SetSort(bson.M{"_id": 1}))
+}
+
+// eachLocation memanggil fn untuk setiap lokasi dari cursor dan mem-flush response secara berkala bila w
+// adalah response HTTP. Dokumen yang tidak bisa di-decode dilewati seperti pada decodeLocations.
+func eachLocation(ctx context.Context, w io.Writer, cursor *mongo.Cursor, fn func(Location) error) error {
+	flush := func() {}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		// ResponseController menembus wrapper middleware; Flush gagal jika writer asli tidak mendukungnya
+		rc := http.NewResponseController(rw)
+		flush = func() { rc.Flush() }
+	}
+	n := 0
+	for cursor.Next(ctx) {
+		var loc Location
+		if err := bson.Unmarshal(cursor.Current, &loc); err != nil {
+			slog.Warn("skipping location that cannot be decoded", "id", rawDocumentID(cursor.Current), "error", err)
+			continue
+		}
+		if err := fn(loc); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			flush()
+		}
+	}
+	return cursor.Err()
+}
+
+// streamLocationsGeoJSON menulis FeatureCollection feature demi feature
+func streamLocationsGeoJSON(ctx context.Context, w io.Writer, cursor *mongo.Cursor) error {
+	if _, err := io.WriteString(w, `{"type":"FeatureCollection","features":[`); err != nil {
+		return err
+	}
+	first := true
+	err := eachLocation(ctx, w, cursor, func(loc Location) error {
+		feature, err := json.Marshal(locationToFeature(loc))
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(feature)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// streamLocationsCSV menulis satu baris CSV per lokasi dengan kolom csvExportHeader
+func streamLocationsCSV(ctx context.Context, w io.Writer, cursor *mongo.Cursor) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvExportHeader); err != nil {
+		return err
+	}
+	err := eachLocation(ctx, w, cursor, func(loc Location) error {
+		lng, lat := "", ""
+		if pos, ok := loc.Location.anchorPosition(); ok {
+			lng = strconv.FormatFloat(roundCoord(pos[0]), 'f', -1, 64)
+			lat = strconv.FormatFloat(roundCoord(pos[1]), 'f', -1, 64)
+		}
+		// csv.Writer memakai buffer kecil sendiri, sehingga memori tetap terbatas tanpa flush per baris
+		return cw.Write([]string{
+			loc.ID.Hex(), loc.Name, loc.Description, loc.Category,
+			loc.Location.Type, lng, lat, loc.CreatedAt.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
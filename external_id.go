@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxExternalIDLength adalah panjang maksimum external_id, ID lokasi di sistem sumber (misalnya ERP)
+const maxExternalIDLength = 128
+
+// validateExternalID memastikan external_id tidak berisi spasi di awal/akhir maupun karakter kontrol, agar
+// ID yang sama dari sistem sumber selalu cocok dengan yang tersimpan
+func validateExternalID(id string) error {
+	if len(id) > maxExternalIDLength {
+		return fmt.Errorf("external_id must be at most %d characters", maxExternalIDLength)
+	}
+	if strings.TrimSpace(id) != id || strings.IndexFunc(id, unicode.IsControl) >= 0 {
+		return errors.New("external_id must not contain control characters or leading/trailing spaces")
+	}
+	return nil
+}
+
+// ensureExternalIDIndex membuat index unique pada tenant_id dan external_id. Index-nya partial agar lokasi
+// tanpa external_id (yang dibuat langsung lewat API) tidak saling bentrok.
+func (s *Server) ensureExternalIDIndex(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "external_id", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"external_id": bson.M{"$type": "string"}}),
+	})
+	return err
+}
+
+// isDuplicateExternalID membedakan pelanggaran index unique external_id dari index unique name; nama index
+// yang dilanggar hanya tersedia di pesan error server
+func isDuplicateExternalID(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "external_id")
+}
+
+// upsertByExternalIDHandler membuat atau mengganti seluruh lokasi dengan external_id tertentu, untuk
+// sinkronisasi dari sistem lain yang hanya mengenal ID-nya sendiri: 201 jika lokasi baru dibuat, 200 jika
+// lokasi lama diganti. Field yang ditentukan server (ID, pemilik, foto, created_at) dipertahankan dari lokasi
+// lama, dan If-Match opsional dihormati bila dikirim.
+func (s *Server) upsertByExternalIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	externalID := mux.Vars(r)["externalId"]
+	if err := validateExternalID(externalID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_external_id", err.Error())
+		return
+	}
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var loc Location
+	if err := decodeBody(w, r, &loc); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	// Lokasi di trash ikut dicari, agar sinkronisasi tidak diam-diam membuat duplikat atau memulihkannya
+	var existing Location
+	err = s.collection.FindOne(ctx, forTenant(ctx, bson.M{"external_id": externalID})).Decode(&existing)
+	found := err == nil
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		writeDBError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	if found {
+		if existing.DeletedAt != nil {
+			writeError(w, http.StatusConflict, "in_trash", "The location with this external_id is in the trash; restore or purge it first")
+			return
+		}
+		if !canModify(ctx, existing) {
+			writeNotOwner(w)
+			return
+		}
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !etagMatches(ifMatch, existing.ETag(), false) {
+			writePreconditionFailed(w, existing)
+			return
+		}
+		loc.ID = existing.ID
+		loc.TenantID = existing.TenantID
+		loc.OwnerID = existing.OwnerID
+		loc.Photos = existing.Photos
+		loc.CreatedAt = existing.CreatedAt
+		loc.Revision = existing.Revision + 1
+		loc.DeletedAt = nil
+		loc.NameNormalized = normalizeName(loc.Name)
+		loc.Tags = normalizeTags(loc.Tags)
+		loc.UpdatedAt = now
+	} else {
+		prepareNewLocation(ctx, &loc, now)
+	}
+	loc.ExternalID = externalID
+
+	if errs := loc.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if err := validateBusinessRules(loc, nil); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	s.fillAddress(ctx, &loc)
+
+	// _id ikut di filter: lokasi baru tidak pernah cocok dengan dokumen lain, sehingga dua sinkronisasi
+	// bersamaan untuk external_id yang sama berakhir dengan 409 dari index unique, bukan menimpa _id
+	filter := forTenant(ctx, bson.M{"external_id": externalID, "_id": loc.ID})
+	if _, err := coll.ReplaceOne(ctx, filter, loc, options.Replace().SetUpsert(true)); err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+
+	status := http.StatusCreated
+	if found {
+		status = http.StatusOK
+		s.notifyLocationChange(&existing, &loc)
+		s.recordAudit(r, newAuditEvent(auditUpdate, &existing, &loc))
+	} else {
+		s.notifyLocationChange(nil, &loc)
+		s.recordAudit(r, newAuditEvent(auditCreate, nil, &loc))
+	}
+
+	w.Header().Set("ETag", loc.ETag())
+	writeJSON(w, status, loc)
+}
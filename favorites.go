@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// favoriteCleanupTimeout membatasi penghapusan favorit lokasi yang di-purge, yang tetap berjalan walaupun
+// client sudah memutus koneksi
+const favoriteCleanupTimeout = 10 * time.Second
+
+// Favorite menandai satu lokasi sebagai favorit satu pengguna. Satu pasangan pengguna dan lokasi hanya
+// tersimpan sekali per tenant (lihat ensureFavoriteIndexes).
+type Favorite struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	TenantID   string             `bson:"tenant_id,omitempty"`
+	UserID     string             `bson:"user_id"`
+	LocationID primitive.ObjectID `bson:"location_id"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+// ensureFavoriteIndexes membuat index unique yang mencegah favorit ganda, index untuk GET /me/favorites
+// yang urut waktu, dan index untuk membersihkan favorit lokasi yang di-purge
+func (s *Server) ensureFavoriteIndexes(ctx context.Context) error {
+	_, err := s.favorites.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "location_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "location_id", Value: 1}}},
+	})
+	return err
+}
+
+// requireUser mengembalikan pengguna request untuk endpoint yang datanya per pengguna. Request dengan API key
+// tidak punya pengguna, sehingga endpoint ini hanya bisa dipakai dengan bearer token yang berisi klaim sub.
+func requireUser(w http.ResponseWriter, r *http.Request) (requestUser, bool) {
+	user := userFromContext(r.Context())
+	if user.ID == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="locations"`)
+		writeError(w, http.StatusUnauthorized, "user_required", "This endpoint requires a bearer token with a sub claim")
+		return user, false
+	}
+	return user, true
+}
+
+// favoriteLocationHandler menambahkan lokasi ke favorit pengguna: 201 jika baru ditambahkan, 200 jika
+// sudah ada sebelumnya, sehingga request ini aman diulang
+func (s *Server) favoriteLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+
+	loc, err := s.locations.GetByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	filter := forTenant(ctx, bson.M{"user_id": user.ID, "location_id": id})
+	result, err := s.favorites.UpdateOne(ctx, filter,
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.Update().SetUpsert(true))
+	// Dua request bersamaan untuk pasangan yang sama bisa sama-sama mencoba insert; yang kalah berarti
+	// favoritnya sudah ada
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	status := http.StatusOK
+	if result != nil && result.UpsertedCount > 0 {
+		status = http.StatusCreated
+	}
+	writeResponse(w, r, status, loc)
+}
+
+// unfavoriteLocationHandler menghapus lokasi dari favorit pengguna
+func (s *Server) unfavoriteLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+
+	result, err := s.favorites.DeleteOne(ctx, forTenant(ctx, bson.M{"user_id": user.ID, "location_id": id}))
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	if result.DeletedCount == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Location with ID %s is not in your favorites", vars["id"]))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Location with ID %s was removed from your favorites", vars["id"]),
+	})
+}
+
+// myLocationsHandler mengembalikan lokasi milik pengguna per halaman, yang terbaru lebih dulu
+func (s *Server) myLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := bson.M{"owner_id": user.ID}
+	total, err := s.locations.Count(ctx, filter)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	sort := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	locations, skipped, err := s.locations.List(ctx, filter, sort, int64(limit), int64((page-1)*limit))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	warns = append(warns, skipped...)
+	setWarningHeaders(w, warns)
+
+	response := map[string]interface{}{
+		"data":  locations,
+		"limit": limit,
+		"page":  page,
+		"total": total,
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// myFavoritesHandler mengembalikan lokasi favorit pengguna per halaman, yang terakhir difavoritkan lebih dulu.
+// total menghitung semua favorit; lokasi yang sedang di trash atau sudah kedaluwarsa tidak ikut di data,
+// sehingga satu halaman bisa berisi kurang dari limit.
+func (s *Server) myFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := forTenant(ctx, bson.M{"user_id": user.ID})
+	total, err := s.favorites.CountDocuments(ctx, filter)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64((page - 1) * limit))
+	cursor, err := s.favorites.Find(ctx, filter, opts)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	var favorites []Favorite
+	if err := cursor.All(ctx, &favorites); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(favorites))
+	for i, f := range favorites {
+		ids[i] = f.LocationID
+	}
+	locations := []Location{}
+	if len(ids) > 0 {
+		byID, err := s.findLocationsByIDs(ctx, ids)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		for _, id := range ids {
+			if loc, ok := byID[id]; ok {
+				locations = append(locations, loc)
+			}
+		}
+	}
+	setWarningHeaders(w, warns)
+
+	response := map[string]interface{}{
+		"data":  locations,
+		"limit": limit,
+		"page":  page,
+		"total": total,
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// deleteFavoritesOf menghapus semua favorit lokasi yang sudah di-purge. ID lokasi unik di semua tenant,
+// sehingga filter tidak perlu menyebut tenant (perintah CLI berjalan tanpa tenant). Kegagalan hanya dicatat:
+// favorit yang tertinggal tidak ikut di GET /me/favorites karena lokasinya sudah tidak ada.
+func (s *Server) deleteFavoritesOf(r *http.Request, id primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), favoriteCleanupTimeout)
+	defer cancel()
+	if _, err := s.favorites.DeleteMany(ctx, bson.M{"location_id": id}); err != nil {
+		requestLogger(r).Warn("favorites of purged location could not be deleted", "location_id", id.Hex(), "error", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// locationFields memetakan nama field JSON Location yang boleh dipilih lewat ?fields= ke nama field BSON-nya.
+// Dibangun dari tag struct agar field baru di Location otomatis bisa dipilih; field dengan json:"-" tidak
+// pernah dikirim ke client sehingga tidak termasuk.
+var locationFields = func() map[string]string {
+	fields := map[string]string{}
+	t := reflect.TypeOf(Location{})
+	for i := 0; i < t.NumField(); i++ {
+		jsonName, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		bsonName, _, _ := strings.Cut(t.Field(i).Tag.Get("bson"), ",")
+		if jsonName == "" || jsonName == "-" || bsonName == "" || bsonName == "-" {
+			continue
+		}
+		fields[jsonName] = bsonName
+	}
+	return fields
+}()
+
+// projectionInternalFields selalu diambil dari database walaupun tidak diminta, karena dipakai server untuk
+// ETag, Last-Modified, dan cursor halaman berikutnya
+var projectionInternalFields = []string{"_id", "revision", "created_at", "updated_at"}
+
+// nearComputedFields adalah field hasil $geoNear yang boleh dipilih di GET /locations/near
+var nearComputedFields = []string{"distance_m", "distanceMeters"}
+
+// fieldSelection adalah hasil ?fields=: nama field JSON yang dikirim ke client dan projection MongoDB-nya
+type fieldSelection struct {
+	names      []string
+	projection bson.M
+}
+
+// parseFields membaca ?fields=name,location. Selection nil berarti parameter tidak diisi dan semua field
+// dikirim. computed adalah field tambahan yang dihitung query (bukan disimpan), seperti jarak pada near.
+func parseFields(raw string, computed ...string) (*fieldSelection, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	sel := &fieldSelection{projection: bson.M{}}
+	for _, name := range projectionInternalFields {
+		sel.projection[name] = 1
+	}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if bsonName, ok := locationFields[name]; ok {
+			sel.projection[bsonName] = 1
+		} else if !slices.Contains(computed, name) {
+			return nil, fmt.Errorf("fields contains unknown field %q; valid fields are %s", name, strings.Join(selectableFields(computed), ", "))
+		}
+		sel.names = append(sel.names, name)
+	}
+	if len(sel.names) == 0 {
+		return nil, fmt.Errorf("fields must list at least one of %s", strings.Join(selectableFields(computed), ", "))
+	}
+	return sel, nil
+}
+
+// selectableFields mengembalikan semua nama field yang boleh dipilih secara urut, untuk pesan error
+func selectableFields(computed []string) []string {
+	names := append([]string{}, computed...)
+	for name := range locationFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// projectionKey adalah key context untuk projection dari ?fields=
+type projectionKey struct{}
+
+// withProjection menyimpan projection di context, sehingga LocationRepository hanya mengambil field tersebut
+// dari database. Dipasang handler baca sendiri, bukan middleware, agar write dalam request yang sama tetap
+// membaca dokumen lengkap.
+func withProjection(ctx context.Context, sel *fieldSelection) context.Context {
+	if sel == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, projectionKey{}, sel.projection)
+}
+
+// projectionFromContext mengembalikan projection request, atau nil jika semua field diambil
+func projectionFromContext(ctx context.Context) bson.M {
+	projection, _ := ctx.Value(projectionKey{}).(bson.M)
+	return projection
+}
+
+// sparse mengubah loc menjadi object JSON yang hanya berisi id dan field yang dipilih. Field yang dipilih
+// tetapi kosong di dokumen ini tidak dikirim, sama seperti field omitempty.
+func (sel *fieldSelection) sparse(loc Location) (map[string]interface{}, error) {
+	data, err := json.Marshal(loc)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]interface{}
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+	return sel.pick(full), nil
+}
+
+// sparseList seperti sparse untuk slice lokasi ([]Location atau []NearLocation)
+func (sel *fieldSelection) sparseList(locations interface{}) ([]map[string]interface{}, error) {
+	data, err := json.Marshal(locations)
+	if err != nil {
+		return nil, err
+	}
+	var full []map[string]interface{}
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+	out := make([]map[string]interface{}, len(full))
+	for i, m := range full {
+		out[i] = sel.pick(m)
+	}
+	return out, nil
+}
+
+// pick menyalin id dan field yang dipilih dari object JSON lengkap
+func (sel *fieldSelection) pick(full map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"id": full["id"]}
+	for _, name := range sel.names {
+		if value, ok := full[name]; ok {
+			out[name] = value
+		}
+	}
+	return out
+}
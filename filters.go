@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxNameFilterLength membatasi panjang ?name= karena regex yang panjang mahal dievaluasi di setiap dokumen
+const maxNameFilterLength = 200
+
+// parseNameFilter mengubah ?name= menjadi filter regex tanpa membedakan huruf besar/kecil. Nilai biasa dicari
+// sebagai substring; nilai berbentuk /pola/ dipakai apa adanya sebagai regex.
+func parseNameFilter(raw string) (bson.M, error) {
+	if len(raw) > maxNameFilterLength {
+		return nil, errors.New("name must be at most 200 characters")
+	}
+	pattern := regexp.QuoteMeta(raw)
+	if len(raw) > 2 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/") {
+		pattern = raw[1 : len(raw)-1]
+		// Sintaks regexp Go tidak persis sama dengan PCRE milik MongoDB, tetapi cukup untuk menolak pola yang rusak
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, errors.New("name is not a valid regular expression")
+		}
+	}
+	return bson.M{"$regex": pattern, "$options": "i"}, nil
+}
+
+// parseListFilter membaca filter GET /locations dan GET /locations/count: name (substring atau /regex/), q (full
+// text lewat index text), created_after dan created_before (RFC3339), tags, serta bbox (west,south,east,north).
+// Semua filter digabung dengan AND.
+func parseListFilter(r *http.Request) (bson.M, error) {
+	q := r.URL.Query()
+	filter := bson.M{}
+
+	if raw := q.Get("name"); raw != "" {
+		nameFilter, err := parseNameFilter(raw)
+		if err != nil {
+			return nil, err
+		}
+		filter["name"] = nameFilter
+	}
+	if text := strings.TrimSpace(q.Get("q")); text != "" {
+		filter["$text"] = bson.M{"$search": text}
+	}
+
+	created := bson.M{}
+	var after, before time.Time
+	if raw := q.Get("created_after"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, errors.New("created_after must be an RFC3339 timestamp")
+		}
+		after = t
+		created["$gt"] = t
+	}
+	if raw := q.Get("created_before"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, errors.New("created_before must be an RFC3339 timestamp")
+		}
+		before = t
+		created["$lt"] = t
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return nil, errors.New("created_after must be earlier than created_before")
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	tags, err := parseTagsFilter(r)
+	if err != nil {
+		return nil, err
+	}
+	if tags != nil {
+		filter["tags"] = tags
+	}
+
+	if q.Get("bbox") != "" {
+		bbox, err := parseBBox(r)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range bbox.geoWithinFilter() {
+			filter[k] = v
+		}
+	}
+	return filter, nil
+}
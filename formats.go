@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Format output daftar lokasi yang didukung lewat query param ?format
+const (
+	formatJSON    = "json"
+	formatGeoJSON = "geojson"
+	formatMapbox  = "mapbox"
+	formatGoogle  = "google"
+)
+
+// GoogleMarker adalah bentuk marker yang langsung bisa dipakai google.maps.Marker
+type GoogleMarker struct {
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Position LatLngPair `json:"position"`
+}
+
+// LatLngPair adalah posisi dalam urutan {lat, lng} seperti yang dipakai Google Maps
+type LatLngPair struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// parseFormat membaca query param format. Tanpa param, header Accept: application/geo+json memilih GeoJSON;
+// selain itu def yang dipakai.
+func parseFormat(r *http.Request, def string) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		if strings.Contains(r.Header.Get("Accept"), "application/geo+json") {
+			return formatGeoJSON, nil
+		}
+		return def, nil
+	}
+	switch format {
+	case formatJSON, formatGeoJSON, formatMapbox, formatGoogle:
+		return format, nil
+	}
+	return "", fmt.Errorf("format must be one of %s, %s, %s or %s", formatJSON, formatGeoJSON, formatMapbox, formatGoogle)
+}
+
+// formatLocations mengubah daftar lokasi ke bentuk yang diharapkan library peta tertentu,
+// mengembalikan payload beserta Content-Type-nya. Semua transformasi format dikumpulkan di sini.
+func formatLocations(format string, locations []Location) (interface{}, string) {
+	switch format {
+	case formatGeoJSON:
+		return locationsToFeatureCollection(locations), "application/geo+json"
+	case formatMapbox:
+		// Bentuk source yang bisa langsung diberikan ke map.addSource(id, source)
+		return map[string]interface{}{
+			"type": "geojson",
+			"data": locationsToFeatureCollection(locations),
+		}, "application/json"
+	case formatGoogle:
+		markers := make([]GoogleMarker, 0, len(locations))
+		for _, loc := range locations {
+			pos, ok := loc.Location.anchorPosition()
+			if !ok {
+				continue
+			}
+			markers = append(markers, GoogleMarker{
+				ID:       loc.ID.Hex(),
+				Title:    loc.Name,
+				Position: LatLngPair{Lat: roundCoord(pos[1]), Lng: roundCoord(pos[0])},
+			})
+		}
+		return markers, "application/json"
+	}
+	return locations, "application/json"
+}
@@ -0,0 +1,942 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// defaultSnapMeters adalah radius default (meter) untuk endpoint snap jika maxMeters tidak diisi
+	defaultSnapMeters = 100
+	// defaultNearMeters adalah radius default (meter) untuk pencarian lokasi di sekitar sebuah titik
+	defaultNearMeters = 5000
+	// maxNearMeters adalah radius maksimum (meter); nilai yang lebih besar dipangkas dengan peringatan
+	maxNearMeters = 50000
+	// defaultAreaLimit adalah jumlah hasil default query area yang mengembalikan array (within, circle, dan
+	// sejenisnya) jika limit tidak diisi
+	defaultAreaLimit = 500
+	// maxAreaLimit adalah limit maksimum query area; nilai yang lebih besar dipangkas dengan peringatan
+	maxAreaLimit = 5000
+)
+
+// parseAreaLimit membaca query param limit untuk query area, memakai defaultAreaLimit jika kosong dan
+// memangkasnya ke maxAreaLimit (dengan peringatan) jika terlalu besar
+func parseAreaLimit(r *http.Request, warns *[]string) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultAreaLimit, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if n > maxAreaLimit {
+		addLimitWarning(warns, "limit was reduced from %d to the server maximum of %d", n, maxAreaLimit)
+		n = maxAreaLimit
+	}
+	return n, nil
+}
+
+// findAreaLocations menjalankan query area lewat LocationRepository dengan batas limit, urut _id agar bisa
+// dilanjutkan dengan after=<ID terakhir>. Jika hasilnya terpotong, Warning dan X-Next-Cursor dikirim; response
+// tetap berupa array seperti sebelumnya. Error ditulis langsung ke w, dan false berarti handler harus berhenti.
+func (s *Server) findAreaLocations(w http.ResponseWriter, r *http.Request, filter bson.M) ([]Location, bool) {
+	ctx := r.Context()
+	var warns []string
+	limit, err := parseAreaLimit(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if raw := r.URL.Query().Get("after"); raw != "" {
+		after, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id", "after must be a location ID")
+			return nil, false
+		}
+		filter["_id"] = bson.M{"$gt": after}
+	}
+
+	// Satu dokumen lebih untuk mengetahui apakah masih ada hasil berikutnya
+	locations, skipped, err := s.locations.List(ctx, filter, bson.D{{Key: "_id", Value: 1}}, int64(limit)+1, 0)
+	if err != nil {
+		writeDBError(w, r, err)
+		return nil, false
+	}
+	warns = append(warns, skipped...)
+	if len(locations)+len(skipped) > limit {
+		locations = locations[:min(len(locations), limit)]
+		if len(locations) > 0 {
+			next := locations[len(locations)-1].ID.Hex()
+			w.Header().Set("X-Next-Cursor", next)
+			addLimitWarning(&warns, "results were truncated to %d locations, pass after=%s for the next page", limit, next)
+		}
+	}
+	setWarningHeaders(w, warns)
+	return locations, true
+}
+
+// LocationWithDistance adalah Location yang dilengkapi jarak (meter) dari titik query
+type LocationWithDistance struct {
+	Location `bson:",inline"`
+	Distance float64 `bson:"distance" json:"distance"`
+}
+
+// NearLocation adalah hasil GET /locations/near: lokasi beserta jaraknya (meter) dari titik query. DistanceM
+// berisi nilai yang sama dengan DistanceMeters dengan nama field snake_case; distanceMeters tetap dikirim
+// untuk client lama.
+type NearLocation struct {
+	Location       `bson:",inline"`
+	DistanceMeters float64 `bson:"distanceMeters" json:"distanceMeters"`
+	DistanceM      float64 `bson:"-" json:"distance_m"`
+}
+
+// CategoryCount adalah jumlah lokasi untuk satu kategori
+type CategoryCount struct {
+	Category string `bson:"_id" json:"category"`
+	Count    int64  `bson:"count" json:"count"`
+}
+
+// assignCategoryRequest adalah body request untuk mengubah kategori semua lokasi di dalam polygon
+type assignCategoryRequest struct {
+	Polygon  Polygon `json:"polygon"`
+	Category string  `json:"category"`
+	DryRun   bool    `json:"dryRun"`
+}
+
+// parseLngLat membaca query param lng dan lat lalu memvalidasi rentangnya
+func parseLngLat(r *http.Request) (float64, float64, error) {
+	q := r.URL.Query()
+
+	lng, err := strconv.ParseFloat(q.Get("lng"), 64)
+	if err != nil {
+		return 0, 0, errors.New("lng must be a valid number")
+	}
+	lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+	if err != nil {
+		return 0, 0, errors.New("lat must be a valid number")
+	}
+
+	if lng < -180 || lng > 180 {
+		return 0, 0, errors.New("lng must be between -180 and 180")
+	}
+	if lat < -90 || lat > 90 {
+		return 0, 0, errors.New("lat must be between -90 and 90")
+	}
+	return lng, lat, nil
+}
+
+// parseMaxMeters membaca query param maxMeters, memakai nilai default jika kosong
+// dan memangkasnya ke maxNearMeters (dengan peringatan) jika terlalu besar
+func parseMaxMeters(r *http.Request, def float64, warns *[]string) (float64, error) {
+	// maxDistance diterima sebagai nama lain maxMeters
+	name := "maxMeters"
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		name = "maxDistance"
+		raw = r.URL.Query().Get(name)
+	}
+	if raw == "" {
+		return def, nil
+	}
+	meters, err := strconv.ParseFloat(raw, 64)
+	if err != nil || meters <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	if meters > maxNearMeters {
+		addLimitWarning(warns, "%s was reduced from %g to the server maximum of %d", name, meters, maxNearMeters)
+		meters = maxNearMeters
+	}
+	return meters, nil
+}
+
+// parseBBox membaca query param bbox dengan format "west,south,east,north" dan memvalidasinya
+func parseBBox(r *http.Request) (BBox, error) {
+	parts := strings.Split(r.URL.Query().Get("bbox"), ",")
+	if len(parts) != 4 {
+		return BBox{}, errors.New("bbox must be in the form west,south,east,north")
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BBox{}, errors.New("bbox values must be valid numbers")
+		}
+		v[i] = f
+	}
+	if err := validatePosition(v[0:2]); err != nil {
+		return BBox{}, err
+	}
+	if err := validatePosition(v[2:4]); err != nil {
+		return BBox{}, err
+	}
+	b := BBox{West: v[0], South: v[1], East: v[2], North: v[3]}
+	if b.West >= b.East || b.South >= b.North {
+		return BBox{}, errors.New("bbox must have west < east and south < north")
+	}
+	return b, nil
+}
+
+// parseCornerBBox membaca query param swLng, swLat, neLng dan neLat (sudut barat daya dan timur laut) sebagai BBox.
+// minLng, minLat, maxLng dan maxLat diterima sebagai nama lain.
+func parseCornerBBox(r *http.Request) (BBox, error) {
+	q := r.URL.Query()
+	names := []string{"swLng", "swLat", "neLng", "neLat"}
+	if q.Get("minLng") != "" || q.Get("minLat") != "" {
+		names = []string{"minLng", "minLat", "maxLng", "maxLat"}
+	}
+	var v [4]float64
+	for i, name := range names {
+		f, err := strconv.ParseFloat(q.Get(name), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("%s must be a valid number", name)
+		}
+		v[i] = f
+	}
+	if err := validatePosition(v[0:2]); err != nil {
+		return BBox{}, fmt.Errorf("southwest corner: %v", err)
+	}
+	if err := validatePosition(v[2:4]); err != nil {
+		return BBox{}, fmt.Errorf("northeast corner: %v", err)
+	}
+	b := BBox{West: v[0], South: v[1], East: v[2], North: v[3]}
+	if b.West >= b.East || b.South >= b.North {
+		return BBox{}, errors.New("southwest corner must be strictly below and left of the northeast corner")
+	}
+	return b, nil
+}
+
+const (
+	// relationWithin mencocokkan lokasi yang geometrinya seluruhnya berada di dalam area query
+	relationWithin = "within"
+	// relationIntersects mencocokkan lokasi yang geometrinya bersinggungan dengan area query, termasuk
+	// zona atau rute yang hanya sebagian berada di dalamnya
+	relationIntersects = "intersects"
+)
+
+// parseSpatialRelation membaca query param relation (within atau intersects), default within
+func parseSpatialRelation(r *http.Request) (string, error) {
+	switch rel := r.URL.Query().Get("relation"); rel {
+	case "", relationWithin:
+		return relationWithin, nil
+	case relationIntersects:
+		return rel, nil
+	}
+	return "", errors.New("relation must be either within or intersects")
+}
+
+// findNearLocations mencari hingga limit lokasi terdekat dari titik (lng, lat), terurut dari yang paling dekat.
+// maxMeters 0 berarti tanpa batas radius; query (boleh nil) adalah filter tambahan untuk $geoNear.
+// Lokasi di trash tidak pernah ikut.
+func (s *Server) findNearLocations(ctx context.Context, lng, lat, maxMeters float64, limit int, query bson.M) ([]LocationWithDistance, error) {
+	geoNear := bson.M{
+		"near":          Point{Type: "Point", Coordinates: []float64{lng, lat}},
+		"distanceField": "distance",
+		"spherical":     true,
+	}
+	if maxMeters > 0 {
+		geoNear["maxDistance"] = maxMeters
+	}
+	geoNear["query"] = withoutDeleted(ctx, query)
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$geoNear": geoNear},
+		bson.M{"$limit": limit},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []LocationWithDistance{}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// findNearestLocation mencari satu lokasi terdekat dari titik (lng, lat); maxMeters 0 berarti tanpa batas radius.
+// Mengembalikan nil jika tidak ada lokasi yang ditemukan.
+func (s *Server) findNearestLocation(ctx context.Context, lng, lat, maxMeters float64) (*LocationWithDistance, error) {
+	results, err := s.findNearLocations(ctx, lng, lat, maxMeters, 1, nil)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// nearLocationsHandler mengembalikan lokasi dalam radius maxMeters dari titik (lng, lat) beserta jaraknya,
+// terurut dari yang paling dekat, opsional hanya yang cocok dengan ?name= dan ?tags=
+func (s *Server) nearLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	maxMeters, err := parseMaxMeters(r, defaultNearMeters, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Tanpa limit semua lokasi dalam radius dikembalikan, seperti sebelum limit didukung
+	var limit int64
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if n > maxPageLimit {
+			addLimitWarning(&warns, "limit was reduced from %d to the server maximum of %d", n, maxPageLimit)
+			n = maxPageLimit
+		}
+		limit = int64(n)
+	}
+	// Semua filter ikut di query $geoNear, sehingga limit berlaku setelah filter dan client tidak perlu
+	// mengambil lebih banyak lalu menyaring sendiri
+	query := bson.M{}
+	if raw := r.URL.Query().Get("name"); raw != "" {
+		nameFilter, err := parseNameFilter(raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		query["name"] = nameFilter
+	}
+	tags, err := parseTagsFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if tags != nil {
+		query["tags"] = tags
+	}
+	fields, err := parseFields(r.URL.Query().Get("fields"), nearComputedFields...)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	near := Point{Type: "Point", Coordinates: []float64{lng, lat}}
+	// Explain hanya tersedia untuk find, jadi biaya diperkirakan dari query $near yang setara;
+	// keduanya memakai index 2dsphere yang sama
+	filter := withoutDeleted(ctx, query)
+	filter["location"] = bson.M{"$near": bson.M{"$geometry": near, "$maxDistance": maxMeters}}
+	if !s.guardQueryCost(w, r, filter, nil, limit, 0) {
+		return
+	}
+
+	locations, err := s.locations.Near(withProjection(ctx, fields), lng, lat, maxMeters, query, limit)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if fields != nil {
+		sparse, err := fields.sparseList(locations)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Locations could not be encoded")
+			return
+		}
+		writeResponse(w, r, http.StatusOK, sparse)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, locations)
+}
+
+// withinLocationsHandler mengembalikan lokasi di dalam kotak viewport peta (sw/ne), paling banyak limit per
+// request (lihat findAreaLocations). Dengan relation=intersects, zona dan rute yang hanya sebagian terlihat di
+// viewport ikut dikembalikan.
+func (s *Server) withinLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bbox, err := parseCornerBBox(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	relation, err := parseSpatialRelation(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	locations, ok := s.findAreaLocations(w, r, bbox.spatialFilter(relation))
+	if !ok {
+		return
+	}
+	writeResponse(w, r, http.StatusOK, locations)
+}
+
+// withinPolygonHandler mengembalikan semua lokasi di dalam GeoJSON Polygon yang dikirim sebagai body request,
+// untuk area yang tidak berbentuk kotak. Foreign member GeoJSON (misalnya bbox) diabaikan.
+// relation=intersects juga mengembalikan lokasi yang hanya sebagian berada di dalam polygon. Hasil dibatasi
+// limit seperti GET /locations/within.
+func (s *Server) withinPolygonHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	body, err := readBody(w, r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	var polygon Polygon
+	if err := json.Unmarshal(body, &polygon); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validatePolygon(polygon); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	relation, err := parseSpatialRelation(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	operator := "$geoWithin"
+	if relation == relationIntersects {
+		operator = "$geoIntersects"
+	}
+
+	locations, ok := s.findAreaLocations(w, r, bson.M{"location": bson.M{operator: bson.M{"$geometry": polygon}}})
+	if !ok {
+		return
+	}
+	writeResponse(w, r, http.StatusOK, locations)
+}
+
+// intersectsLocationsHandler mengembalikan lokasi yang geometrinya bersinggungan dengan geometri GeoJSON di body
+// (Point, LineString, atau Polygon). Dengan Point, hasilnya adalah zona yang memuat titik tersebut, misalnya
+// zona pengiriman untuk satu alamat; dengan LineString, zona yang dilewati rute. Hasil dibatasi limit seperti
+// GET /locations/within.
+func (s *Server) intersectsLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	body, err := readBody(w, r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	var geometry Geometry
+	if err := json.Unmarshal(body, &geometry); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateGeometry(geometry); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := bson.M{"location": bson.M{"$geoIntersects": bson.M{"$geometry": geometry}}}
+	if t := r.URL.Query().Get("type"); t != "" {
+		if newCoordinates(t) == nil {
+			writeJSONError(w, http.StatusBadRequest, "type must be one of Point, LineString or Polygon")
+			return
+		}
+		filter["location.type"] = t
+	}
+	locations, ok := s.findAreaLocations(w, r, filter)
+	if !ok {
+		return
+	}
+	writeResponse(w, r, http.StatusOK, locations)
+}
+
+// containingLocationsHandler mengembalikan zona (lokasi Polygon) yang memuat titik lng/lat, misalnya zona
+// pengiriman untuk alamat pelanggan. Sama dengan POST /locations/intersects dengan body Point dan type=Polygon,
+// tetapi lewat GET sehingga bisa di-cache dan dipanggil langsung dari URL. Titik di tepi polygon ikut cocok.
+// Hasil dibatasi limit seperti GET /locations/within.
+func (s *Server) containingLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tags, err := parseTagsFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := bson.M{
+		"location.type": "Polygon",
+		"location":      bson.M{"$geoIntersects": bson.M{"$geometry": Point{Type: "Point", Coordinates: []float64{lng, lat}}}},
+	}
+	if tags != nil {
+		filter["tags"] = tags
+	}
+	locations, ok := s.findAreaLocations(w, r, filter)
+	if !ok {
+		return
+	}
+	writeResponse(w, r, http.StatusOK, locations)
+}
+
+// mongoEarthRadiusMeters adalah radius bumi yang dipakai MongoDB secara internal untuk geometri sferis;
+// konversi meter ke radian untuk $centerSphere harus memakai nilai ini agar konsisten dengan $near
+const mongoEarthRadiusMeters = 6378100
+
+// circleLocationsHandler mengembalikan lokasi di dalam lingkaran (lng, lat, radiusMeters) tanpa diurutkan,
+// lebih murah dari $near jika urutan jarak tidak dibutuhkan. Radius dipangkas ke maxNearMeters dan hasil
+// dibatasi limit seperti GET /locations/within.
+func (s *Server) circleLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	radius, err := strconv.ParseFloat(r.URL.Query().Get("radiusMeters"), 64)
+	if err != nil || radius <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "radiusMeters must be a positive number")
+		return
+	}
+	var warns []string
+	if radius > maxNearMeters {
+		addLimitWarning(&warns, "radiusMeters was reduced from %g to the server maximum of %d", radius, maxNearMeters)
+		radius = maxNearMeters
+	}
+	setWarningHeaders(w, warns)
+
+	filter := bson.M{"location": bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{bson.A{lng, lat}, radius / mongoEarthRadiusMeters},
+	}}}
+	locations, ok := s.findAreaLocations(w, r, filter)
+	if !ok {
+		return
+	}
+	writeResponse(w, r, http.StatusOK, locations)
+}
+
+// snapLocationHandler mengembalikan satu lokasi terdekat dalam radius maxMeters, atau 204 jika tidak ada
+func (s *Server) snapLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	maxMeters, err := parseMaxMeters(r, defaultSnapMeters, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	nearest, err := s.findNearestLocation(ctx, lng, lat, maxMeters)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// Tidak ada lokasi yang cukup dekat: kembalikan 204 agar UI tahu tidak ada kecocokan
+	if nearest == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, nearest)
+}
+
+// nearCategoriesHandler mengembalikan jumlah lokasi per kategori di sekitar sebuah titik
+func (s *Server) nearCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	maxMeters, err := parseMaxMeters(r, defaultNearMeters, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	// $geoNear wajib menjadi stage pertama, sehingga filter radius diterapkan sebelum $group
+	pipeline := bson.A{
+		bson.M{"$geoNear": bson.M{
+			"near":          Point{Type: "Point", Coordinates: []float64{lng, lat}},
+			"query":         withoutDeleted(ctx, nil),
+			"distanceField": "distance",
+			"maxDistance":   maxMeters,
+			"spherical":     true,
+		}},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$category", "uncategorized"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	categories := []CategoryCount{}
+	if err = cursor.All(ctx, &categories); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, categories)
+}
+
+// assignCategoryHandler mengubah kategori semua lokasi yang berada di dalam polygon (mendukung dry-run)
+func (s *Server) assignCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req assignCategoryRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if req.Category == "" {
+		writeJSONError(w, http.StatusBadRequest, "category is required")
+		return
+	}
+	if err := validatePolygon(req.Polygon); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := withoutDeleted(ctx, bson.M{"location": bson.M{"$geoWithin": bson.M{"$geometry": req.Polygon}}})
+
+	// Dry-run hanya menghitung dokumen yang cocok dan yang akan berubah, tanpa menulis apa pun
+	if req.DryRun {
+		matched, err := s.collection.CountDocuments(ctx, filter)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		modified, err := s.collection.CountDocuments(ctx, withoutDeleted(ctx, bson.M{
+			"location": filter["location"],
+			"category": bson.M{"$ne": req.Category},
+		}))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"dryRun":   true,
+			"matched":  matched,
+			"modified": modified,
+		})
+		return
+	}
+
+	// Dokumen yang akan berganti kategori dibaca dulu sebagai snapshot "before" untuk audit log
+	changing := withoutDeleted(ctx, bson.M{"location": filter["location"], "category": bson.M{"$ne": req.Category}})
+	cursor, err := s.collection.Find(ctx, changing)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	befores, _, err := decodeLocations(ctx, cursor)
+	cursor.Close(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// Update berbentuk pipeline agar updated_at hanya berubah pada dokumen yang kategorinya benar-benar berganti,
+	// sehingga modified tetap berarti jumlah dokumen yang berubah
+	now := time.Now()
+	update := bson.A{bson.M{"$set": bson.M{
+		"updated_at": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$category", bson.M{"$literal": req.Category}}},
+			"$updated_at",
+			now,
+		}},
+		"category": bson.M{"$literal": req.Category},
+	}}}
+	result, err := coll.UpdateMany(ctx, filter, update)
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	if result.ModifiedCount > 0 {
+		s.bumpCollectionVersion(ctx)
+		audits := make([]AuditEvent, len(befores))
+		for i := range befores {
+			after := befores[i]
+			after.Category = req.Category
+			after.UpdatedAt = now
+			audits[i] = newAuditEvent(auditUpdate, &befores[i], &after)
+		}
+		s.recordAudit(r, audits...)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"dryRun":   false,
+		"matched":  result.MatchedCount,
+		"modified": result.ModifiedCount,
+	})
+}
+
+// mostCentralLocationHandler mengembalikan lokasi yang paling dekat dengan centroid (rata-rata koordinat) seluruh koleksi
+func (s *Server) mostCentralLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, bson.M{"location.type": "Point"})},
+		bson.M{"$group": bson.M{
+			"_id": nil,
+			"lng": bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}},
+			"lat": bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}},
+		}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var centroids []struct {
+		Lng float64 `bson:"lng"`
+		Lat float64 `bson:"lat"`
+	}
+	if err = cursor.All(ctx, &centroids); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// Koleksi kosong tidak memiliki centroid
+	if len(centroids) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	centroid := centroids[0]
+
+	nearest, err := s.findNearestLocation(ctx, centroid.Lng, centroid.Lat, 0)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if nearest == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"centroid": Point{Type: "Point", Coordinates: []float64{centroid.Lng, centroid.Lat}},
+		"location": nearest,
+	})
+}
+
+const (
+	// defaultRankedLimit adalah jumlah hasil default pada endpoint near/ranked
+	defaultRankedLimit = 20
+	// maxRankedLimit adalah jumlah hasil maksimum pada endpoint near/ranked
+	maxRankedLimit = 100
+	// defaultRecencyDays adalah rentang umur (hari) yang dipakai untuk menormalisasi skor kebaruan
+	defaultRecencyDays = 30
+)
+
+// RankedLocation adalah lokasi hasil near/ranked beserta jarak dan skor gabungannya
+type RankedLocation struct {
+	LocationWithDistance `bson:",inline"`
+	Score                float64 `bson:"score" json:"score"`
+}
+
+// parseNonNegativeFloat membaca query param float >= 0, memakai nilai default jika kosong
+func parseNonNegativeFloat(r *http.Request, name string, def float64) (float64, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return v, nil
+}
+
+// rankedNearHandler mengurutkan lokasi di sekitar titik berdasarkan skor gabungan jarak dan kebaruan.
+// Skor = distanceWeight*(1 - distance/maxMeters) + recencyWeight*(1 - umur/recencyDays), dipangkas ke [0, 1] per komponen.
+func (s *Server) rankedNearHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	maxMeters, err := parseMaxMeters(r, defaultNearMeters, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	distanceWeight, err := parseNonNegativeFloat(r, "distanceWeight", 0.5)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recencyWeight, err := parseNonNegativeFloat(r, "recencyWeight", 0.5)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recencyDays, err := parseNonNegativeFloat(r, "recencyDays", defaultRecencyDays)
+	if err != nil || recencyDays == 0 {
+		writeJSONError(w, http.StatusBadRequest, "recencyDays must be a positive number")
+		return
+	}
+
+	limit := defaultRankedLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if n > maxRankedLimit {
+			addLimitWarning(&warns, "limit was reduced from %d to the server maximum of %d", n, maxRankedLimit)
+			n = maxRankedLimit
+		}
+		limit = n
+	}
+	setWarningHeaders(w, warns)
+
+	recencyWindowMs := recencyDays * 24 * float64(time.Hour/time.Millisecond)
+
+	// Komponen jarak: 1 untuk titik tepat di lokasi query, 0 untuk titik di batas radius
+	distanceScore := bson.M{"$subtract": bson.A{1, bson.M{"$divide": bson.A{"$distance", maxMeters}}}}
+	// Komponen kebaruan: 1 untuk dokumen yang baru dibuat, 0 untuk dokumen yang lebih tua dari recencyDays
+	recencyScore := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{1, bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", "$created_at"}},
+		recencyWindowMs,
+	}}}}}}
+
+	pipeline := bson.A{
+		bson.M{"$geoNear": bson.M{
+			"near":          Point{Type: "Point", Coordinates: []float64{lng, lat}},
+			"query":         withoutDeleted(ctx, nil),
+			"distanceField": "distance",
+			"maxDistance":   maxMeters,
+			"spherical":     true,
+		}},
+		bson.M{"$addFields": bson.M{"score": bson.M{"$add": bson.A{
+			bson.M{"$multiply": bson.A{distanceWeight, distanceScore}},
+			bson.M{"$multiply": bson.A{recencyWeight, recencyScore}},
+		}}}},
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	results := []RankedLocation{}
+	if err = cursor.All(ctx, &results); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, results)
+}
+
+const (
+	// defaultNeighborCount adalah jumlah tetangga default pada endpoint with-neighbors
+	defaultNeighborCount = 5
+	// maxNeighborCount adalah jumlah tetangga maksimum pada endpoint with-neighbors
+	maxNeighborCount = 50
+)
+
+// locationWithNeighborsHandler mengembalikan satu lokasi beserta N tetangga terdekatnya (tanpa dirinya sendiri)
+func (s *Server) locationWithNeighborsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+
+	var warns []string
+	count := defaultNeighborCount
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "count must be a positive integer")
+			return
+		}
+		if n > maxNeighborCount {
+			addLimitWarning(&warns, "count was reduced from %d to the server maximum of %d", n, maxNeighborCount)
+			n = maxNeighborCount
+		}
+		count = n
+	}
+	setWarningHeaders(w, warns)
+
+	loc, err := s.locations.GetByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	pos, ok := loc.Location.anchorPosition()
+	if !ok {
+		writeJSONError(w, http.StatusUnprocessableEntity, "Location has no valid coordinates")
+		return
+	}
+
+	neighbors, err := s.findNearLocations(ctx, pos[0], pos[1], 0, count,
+		bson.M{"_id": bson.M{"$ne": id}})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"location":  loc,
+		"neighbors": neighbors,
+	})
+}
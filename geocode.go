@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// geocodeCacheTTL adalah lama hasil geocoding disimpan; alamat jarang berubah, dan provider gratis
+	// seperti Nominatim membatasi satu request per detik
+	geocodeCacheTTL = 24 * time.Hour
+	// maxGeocodeCacheEntries membatasi memori cache; entri yang kedaluwarsa dibuang lebih dulu
+	maxGeocodeCacheEntries = 10000
+	// geocodeTimeout adalah batas waktu satu request ke provider
+	geocodeTimeout = 5 * time.Second
+	// maxGeocodeResults adalah jumlah hasil maksimum GET /geocode
+	maxGeocodeResults = 10
+	// maxAddressLength adalah panjang maksimum field address pada lokasi
+	maxAddressLength = 500
+	// nominatimDefaultURL adalah server Nominatim publik; instance sendiri bisa dipakai lewat GEOCODER_URL
+	nominatimDefaultURL = "https://nominatim.openstreetmap.org"
+)
+
+// errGeocoderNotConfigured dikembalikan endpoint geocoding jika GEOCODER tidak diset
+var errGeocoderNotConfigured = errors.New("geocoding is not configured (set GEOCODER)")
+
+// GeocodeResult adalah satu alamat beserta koordinatnya
+type GeocodeResult struct {
+	Address string  `json:"address"`
+	Lng     float64 `json:"lng"`
+	Lat     float64 `json:"lat"`
+}
+
+// geocoder adalah provider geocoding. Reverse mengembalikan nil tanpa error jika tidak ada alamat di titik tersebut.
+type geocoder interface {
+	Geocode(ctx context.Context, query string, limit int) ([]GeocodeResult, error)
+	Reverse(ctx context.Context, lng, lat float64) (*GeocodeResult, error)
+}
+
+// newGeocoder membuat provider sesuai nama di GEOCODER, dibungkus cache in-process. Nama yang kosong berarti
+// geocoding dimatikan (nil); nama yang tidak dikenal sudah ditolak oleh loadConfig.
+func newGeocoder(cfg Config) geocoder {
+	client := &http.Client{Timeout: geocodeTimeout, Transport: tracedTransport()}
+	var provider geocoder
+	switch cfg.Geocoder {
+	case "nominatim":
+		provider = nominatimGeocoder{baseURL: cfg.GeocoderURL, client: client}
+	case "google":
+		provider = googleGeocoder{apiKey: cfg.GeocoderAPIKey, client: client}
+	case "mapbox":
+		provider = mapboxGeocoder{token: cfg.GeocoderAPIKey, client: client}
+	default:
+		return nil
+	}
+	return newCachingGeocoder(provider)
+}
+
+// getProviderJSON melakukan GET ke provider dan men-decode body JSON ke dst
+func getProviderJSON(ctx context.Context, client *http.Client, endpoint string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	// Kebijakan Nominatim mewajibkan User-Agent yang mengidentifikasi aplikasi
+	req.Header.Set("User-Agent", "go-mongo-railway-geocoder")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("geocoder responded with status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// nominatimGeocoder memakai API search dan reverse milik Nominatim (OpenStreetMap)
+type nominatimGeocoder struct {
+	baseURL string
+	client  *http.Client
+}
+
+// nominatimPlace adalah bagian hasil Nominatim yang dipakai; koordinat dikirim sebagai string
+type nominatimPlace struct {
+	DisplayName string `json:"display_name"`
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	Error       string `json:"error"`
+}
+
+func (p nominatimPlace) result() (GeocodeResult, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return GeocodeResult{}, fmt.Errorf("geocoder returned an invalid latitude %q", p.Lat)
+	}
+	lng, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return GeocodeResult{}, fmt.Errorf("geocoder returned an invalid longitude %q", p.Lon)
+	}
+	return GeocodeResult{Address: p.DisplayName, Lng: lng, Lat: lat}, nil
+}
+
+func (g nominatimGeocoder) Geocode(ctx context.Context, query string, limit int) ([]GeocodeResult, error) {
+	params := url.Values{"q": {query}, "format": {"jsonv2"}, "limit": {strconv.Itoa(limit)}}
+	var places []nominatimPlace
+	if err := getProviderJSON(ctx, g.client, g.baseURL+"/search?"+params.Encode(), &places); err != nil {
+		return nil, err
+	}
+	results := make([]GeocodeResult, 0, len(places))
+	for _, p := range places {
+		res, err := p.result()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (g nominatimGeocoder) Reverse(ctx context.Context, lng, lat float64) (*GeocodeResult, error) {
+	params := url.Values{
+		"lat":    {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon":    {strconv.FormatFloat(lng, 'f', -1, 64)},
+		"format": {"jsonv2"},
+	}
+	var place nominatimPlace
+	if err := getProviderJSON(ctx, g.client, g.baseURL+"/reverse?"+params.Encode(), &place); err != nil {
+		return nil, err
+	}
+	// Titik di tengah laut dijawab 200 dengan field error
+	if place.Error != "" {
+		return nil, nil
+	}
+	res, err := place.result()
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// googleGeocoder memakai Google Geocoding API
+type googleGeocoder struct {
+	apiKey string
+	client *http.Client
+}
+
+// googleResponse adalah bagian response Google Geocoding API yang dipakai
+type googleResponse struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message"`
+	Results      []struct {
+		FormattedAddress string `json:"formatted_address"`
+		Geometry         struct {
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+		} `json:"geometry"`
+	} `json:"results"`
+}
+
+func (g googleGeocoder) lookup(ctx context.Context, params url.Values, limit int) ([]GeocodeResult, error) {
+	params.Set("key", g.apiKey)
+	var resp googleResponse
+	if err := getProviderJSON(ctx, g.client, "https://maps.googleapis.com/maps/api/geocode/json?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	switch resp.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return []GeocodeResult{}, nil
+	default:
+		return nil, fmt.Errorf("geocoder returned %s: %s", resp.Status, resp.ErrorMessage)
+	}
+	results := make([]GeocodeResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, GeocodeResult{Address: r.FormattedAddress, Lng: r.Geometry.Location.Lng, Lat: r.Geometry.Location.Lat})
+	}
+	return results, nil
+}
+
+func (g googleGeocoder) Geocode(ctx context.Context, query string, limit int) ([]GeocodeResult, error) {
+	return g.lookup(ctx, url.Values{"address": {query}}, limit)
+}
+
+func (g googleGeocoder) Reverse(ctx context.Context, lng, lat float64) (*GeocodeResult, error) {
+	results, err := g.lookup(ctx, url.Values{"latlng": {fmt.Sprintf("%v,%v", lat, lng)}}, 1)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// mapboxGeocoder memakai Mapbox Geocoding API v5
+type mapboxGeocoder struct {
+	token  string
+	client *http.Client
+}
+
+// mapboxResponse adalah bagian response Mapbox yang dipakai; center berurutan [lng, lat]
+type mapboxResponse struct {
+	Features []struct {
+		PlaceName string    `json:"place_name"`
+		Center    []float64 `json:"center"`
+	} `json:"features"`
+}
+
+func (g mapboxGeocoder) lookup(ctx context.Context, search string, limit int) ([]GeocodeResult, error) {
+	params := url.Values{"access_token": {g.token}, "limit": {strconv.Itoa(limit)}}
+	endpoint := "https://api.mapbox.com/geocoding/v5/mapbox.places/" + url.PathEscape(search) + ".json?" + params.Encode()
+	var resp mapboxResponse
+	if err := getProviderJSON(ctx, g.client, endpoint, &resp); err != nil {
+		return nil, err
+	}
+	results := make([]GeocodeResult, 0, len(resp.Features))
+	for _, f := range resp.Features {
+		if len(f.Center) != 2 {
+			continue
+		}
+		results = append(results, GeocodeResult{Address: f.PlaceName, Lng: f.Center[0], Lat: f.Center[1]})
+	}
+	return results, nil
+}
+
+func (g mapboxGeocoder) Geocode(ctx context.Context, query string, limit int) ([]GeocodeResult, error) {
+	return g.lookup(ctx, query, limit)
+}
+
+func (g mapboxGeocoder) Reverse(ctx context.Context, lng, lat float64) (*GeocodeResult, error) {
+	results, err := g.lookup(ctx, fmt.Sprintf("%v,%v", lng, lat), 1)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// geocodeCacheEntry adalah satu hasil yang di-cache (results untuk geocode, reverse untuk reverse)
+type geocodeCacheEntry struct {
+	results []GeocodeResult
+	reverse *GeocodeResult
+	expires time.Time
+}
+
+// cachingGeocoder menyimpan hasil provider di memori selama geocodeCacheTTL. Error tidak di-cache.
+type cachingGeocoder struct {
+	provider geocoder
+	mu       sync.Mutex
+	entries  map[string]geocodeCacheEntry
+}
+
+func newCachingGeocoder(provider geocoder) *cachingGeocoder {
+	return &cachingGeocoder{provider: provider, entries: map[string]geocodeCacheEntry{}}
+}
+
+func (c *cachingGeocoder) get(key string) (geocodeCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return geocodeCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put menyimpan entri; jika cache penuh, entri kedaluwarsa dibuang dulu, lalu satu entri sembarang
+func (c *cachingGeocoder) put(key string, entry geocodeCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxGeocodeCacheEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < maxGeocodeCacheEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	entry.expires = time.Now().Add(geocodeCacheTTL)
+	c.entries[key] = entry
+}
+
+func (c *cachingGeocoder) Geocode(ctx context.Context, query string, limit int) ([]GeocodeResult, error) {
+	key := fmt.Sprintf("geocode:%d:%s", limit, strings.ToLower(query))
+	if entry, ok := c.get(key); ok {
+		return entry.results, nil
+	}
+	results, err := c.provider.Geocode(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, geocodeCacheEntry{results: results})
+	return results, nil
+}
+
+// Reverse memakai koordinat yang dibulatkan ke 5 desimal (sekitar 1 m) sebagai key cache
+func (c *cachingGeocoder) Reverse(ctx context.Context, lng, lat float64) (*GeocodeResult, error) {
+	key := fmt.Sprintf("reverse:%.5f,%.5f", lng, lat)
+	if entry, ok := c.get(key); ok {
+		return entry.reverse, nil
+	}
+	res, err := c.provider.Reverse(ctx, lng, lat)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, geocodeCacheEntry{reverse: res})
+	return res, nil
+}
+
+// writeGeocoderError menjawab 503 jika geocoding tidak dikonfigurasi atau 502 jika provider gagal
+func writeGeocoderError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errGeocoderNotConfigured) {
+		writeError(w, http.StatusServiceUnavailable, "geocoder_not_configured", "Geocoding is not configured on this server")
+		return
+	}
+	requestLogger(r).Error("geocoder request failed", "error", err)
+	writeError(w, http.StatusBadGateway, "geocoder_failed", "The geocoding provider could not be reached, try again later")
+}
+
+// geocodeHandler mengubah alamat (?q=) menjadi daftar kandidat koordinat, yang paling relevan lebih dulu
+func (s *Server) geocodeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.geocoder == nil {
+		writeGeocoderError(w, r, errGeocoderNotConfigured)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit := 5
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxGeocodeResults {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxGeocodeResults))
+			return
+		}
+		limit = n
+	}
+
+	results, err := s.geocoder.Geocode(r.Context(), query, limit)
+	if err != nil {
+		writeGeocoderError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, results)
+}
+
+// reverseGeocodeHandler mengubah koordinat (?lng=&lat=) menjadi alamat
+func (s *Server) reverseGeocodeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.geocoder == nil {
+		writeGeocoderError(w, r, errGeocoderNotConfigured)
+		return
+	}
+	lng, lat, err := parseLngLat(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := s.geocoder.Reverse(r.Context(), lng, lat)
+	if err != nil {
+		writeGeocoderError(w, r, err)
+		return
+	}
+	if res == nil {
+		writeJSONError(w, http.StatusNotFound, "No address was found at these coordinates")
+		return
+	}
+	writeResponse(w, r, http.StatusOK, res)
+}
+
+// fillAddress mengisi address lokasi Point yang dibuat tanpa alamat, jika AUTO_GEOCODE_ADDRESS aktif.
+// Kegagalan provider hanya dicatat: lokasi tetap disimpan tanpa alamat.
+func (s *Server) fillAddress(ctx context.Context, loc *Location) {
+	if s.geocoder == nil || !config.AutoGeocodeAddress || loc.Address != "" {
+		return
+	}
+	pos, ok := loc.Location.Position()
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, geocodeTimeout)
+	defer cancel()
+	res, err := s.geocoder.Reverse(ctx, pos[0], pos[1])
+	if err != nil {
+		slog.Warn("could not fill the address of a new location", "error", err)
+		return
+	}
+	if res != nil {
+		loc.Address = truncateRunes(res.Address, maxAddressLength)
+	}
+}
+
+// truncateRunes memotong s menjadi paling banyak n karakter
+func truncateRunes(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
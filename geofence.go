@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxGeofenceNameLength adalah panjang maksimum nama geofence
+	maxGeofenceNameLength = 100
+	// geofenceCheckTimeout adalah batas waktu query geofence untuk satu perubahan lokasi
+	geofenceCheckTimeout = 5 * time.Second
+)
+
+// Geofence adalah area polygon yang dipantau: lokasi yang masuk atau keluar dari area ini dilaporkan ke webhook.
+// Geofence hanya berlaku untuk lokasi dengan tenant yang sama.
+type Geofence struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	TenantID  string             `bson:"tenant_id,omitempty" json:"-"`
+	Name      string             `bson:"name" json:"name"`
+	Geometry  Polygon            `bson:"geometry" json:"geometry"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// geofenceInput adalah body POST dan PUT /geofences
+type geofenceInput struct {
+	Name     string  `json:"name"`
+	Geometry Polygon `json:"geometry"`
+}
+
+// validate memeriksa nama dan polygon geofence
+func (g geofenceInput) validate() []FieldError {
+	var errs []FieldError
+	name := strings.TrimSpace(g.Name)
+	if name == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "name is required"})
+	} else if len([]rune(name)) > maxGeofenceNameLength {
+		errs = append(errs, FieldError{Field: "name", Message: fmt.Sprintf("name must be at most %d characters", maxGeofenceNameLength)})
+	}
+	if err := validatePolygon(g.Geometry); err != nil {
+		errs = append(errs, FieldError{Field: "geometry", Message: err.Error()})
+	}
+	return errs
+}
+
+// GeofenceEvent dikirim ke webhook saat lokasi masuk (enter) atau keluar (exit) dari geofence. TenantID
+// kosong tanpa multi-tenancy; webhook dipakai bersama semua tenant, sehingga penerima memilahnya dari field ini.
+type GeofenceEvent struct {
+	Type         string    `json:"type"`
+	TenantID     string    `json:"tenantId,omitempty"`
+	GeofenceID   string    `json:"geofenceId"`
+	GeofenceName string    `json:"geofenceName"`
+	LocationID   string    `json:"locationId"`
+	Location     *Location `json:"location"`
+	OccurredAt   time.Time `json:"occurredAt"`
+}
+
+// ensureGeofenceIndex membuat index 2dsphere pada geometry geofence agar evaluasi per write tetap murah
+func (s *Server) ensureGeofenceIndex(ctx context.Context) error {
+	_, err := s.geofences.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.M{"geometry": "2dsphere"}})
+	return err
+}
+
+// geofencesContaining mengembalikan geofence yang memuat titik lokasi, dikunci per ID. Hanya lokasi Point
+// yang aktif (tidak di trash) yang dievaluasi; geometri lain tidak punya arti masuk/keluar yang jelas.
+func (s *Server) geofencesContaining(ctx context.Context, loc *Location) (map[primitive.ObjectID]Geofence, error) {
+	found := map[primitive.ObjectID]Geofence{}
+	if loc == nil || loc.DeletedAt != nil {
+		return found, nil
+	}
+	pos, ok := loc.Location.Position()
+	if !ok {
+		return found, nil
+	}
+	point := bson.M{"type": "Point", "coordinates": pos}
+	// Worker tidak berjalan di context request, sehingga tenant diambil dari lokasinya sendiri
+	var tenant interface{}
+	if loc.TenantID != "" {
+		tenant = loc.TenantID
+	}
+	cursor, err := s.geofences.Find(ctx, bson.M{"tenant_id": tenant, "geometry": bson.M{"$geoIntersects": bson.M{"$geometry": point}}},
+		options.Find().SetProjection(bson.M{"geometry": 0}))
+	if err != nil {
+		return nil, err
+	}
+	var fences []Geofence
+	if err := cursor.All(ctx, &fences); err != nil {
+		return nil, err
+	}
+	for _, f := range fences {
+		found[f.ID] = f
+	}
+	return found, nil
+}
+
+// geofenceTransitions membandingkan geofence yang memuat lokasi sebelum dan sesudah perubahan, lalu
+// menghasilkan event exit untuk yang ditinggalkan dan enter untuk yang baru dimasuki
+func (s *Server) geofenceTransitions(ctx context.Context, before, after *Location) ([]GeofenceEvent, error) {
+	was, err := s.geofencesContaining(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	now, err := s.geofencesContaining(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	occurred := time.Now().UTC()
+	var events []GeofenceEvent
+	for id, f := range was {
+		if _, still := now[id]; !still {
+			events = append(events, GeofenceEvent{Type: "exit", TenantID: subject.TenantID, GeofenceID: id.Hex(), GeofenceName: f.Name,
+				LocationID: subject.ID.Hex(), Location: subject, OccurredAt: occurred})
+		}
+	}
+	for id, f := range now {
+		if _, already := was[id]; !already {
+			events = append(events, GeofenceEvent{Type: "enter", TenantID: subject.TenantID, GeofenceID: id.Hex(), GeofenceName: f.Name,
+				LocationID: subject.ID.Hex(), Location: subject, OccurredAt: occurred})
+		}
+	}
+	return events, nil
+}
+
+// evaluateGeofences menghitung transisi geofence satu perubahan lokasi dan menyerahkannya ke webhook.
+// Dipanggil dari worker webhook, bukan dari request, sehingga latensi write tidak bertambah.
+func (s *Server) evaluateGeofences(change locationChange) {
+	ctx, cancel := context.WithTimeout(s.ctx, geofenceCheckTimeout)
+	defer cancel()
+	events, err := s.geofenceTransitions(ctx, change.before, change.after)
+	if err != nil {
+		slog.Error("geofence evaluation failed", "error", err)
+		return
+	}
+	for _, evt := range events {
+		s.webhooks.deliverAll(evt)
+	}
+}
+
+// listGeofencesHandler mengembalikan semua geofence per halaman, urut dari yang paling lama dibuat
+func (s *Server) listGeofencesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	total, err := s.geofences.CountDocuments(ctx, forTenant(ctx, nil))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64((page - 1) * limit))
+	cursor, err := s.geofences.Find(ctx, forTenant(ctx, nil), opts)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	fences := []Geofence{}
+	if err := cursor.All(ctx, &fences); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":  fences,
+		"limit": limit,
+		"page":  page,
+		"total": total,
+	})
+}
+
+// getGeofenceHandler mengembalikan satu geofence berdasarkan ID
+func (s *Server) getGeofenceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid geofence ID format")
+		return
+	}
+
+	var fence Geofence
+	err = s.geofences.FindOne(ctx, forTenant(ctx, bson.M{"_id": id})).Decode(&fence)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Geofence not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, fence)
+}
+
+// createGeofenceHandler menyimpan geofence baru. Lokasi yang sudah berada di dalamnya tidak memicu event;
+// event hanya muncul saat lokasi dibuat atau dipindahkan sesudahnya.
+func (s *Server) createGeofenceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var input geofenceInput
+	if err := decodeBody(w, r, &input); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if errs := input.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	now := time.Now()
+	fence := Geofence{
+		ID:        primitive.NewObjectID(),
+		TenantID:  tenantFromContext(ctx),
+		Name:      strings.TrimSpace(input.Name),
+		Geometry:  input.Geometry,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := s.geofences.InsertOne(ctx, fence); err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, fence)
+}
+
+// updateGeofenceHandler mengganti nama dan polygon geofence
+func (s *Server) updateGeofenceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid geofence ID format")
+		return
+	}
+
+	var input geofenceInput
+	if err := decodeBody(w, r, &input); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if errs := input.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	var updated Geofence
+	err = s.geofences.FindOneAndUpdate(ctx, forTenant(ctx, bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"name": strings.TrimSpace(input.Name), "geometry": input.Geometry, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Geofence not found")
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, updated)
+}
+
+// deleteGeofenceHandler menghapus geofence secara permanen
+func (s *Server) deleteGeofenceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid geofence ID format")
+		return
+	}
+
+	result, err := s.geofences.DeleteOne(ctx, forTenant(ctx, bson.M{"_id": id}))
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	if result.DeletedCount == 0 {
+		writeJSONError(w, http.StatusNotFound, "Geofence not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Geofence with ID %s was deleted", vars["id"]),
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import "time"
+
+// Feature adalah satu GeoJSON Feature
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// FeatureCollection adalah GeoJSON FeatureCollection yang dipahami Leaflet, Mapbox, QGIS, dll.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// newFeatureCollection membungkus daftar feature ke dalam FeatureCollection (features tidak pernah null)
+func newFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// locationToFeature mengubah Location menjadi GeoJSON Feature dengan atribut di properties
+func locationToFeature(loc Location) Feature {
+	props := map[string]interface{}{
+		"id":         loc.ID.Hex(),
+		"name":       loc.Name,
+		"created_at": loc.CreatedAt.Format(time.RFC3339),
+	}
+	if loc.Description != "" {
+		props["description"] = loc.Description
+	}
+	if loc.Category != "" {
+		props["category"] = loc.Category
+	}
+	if len(loc.Tags) > 0 {
+		props["tags"] = loc.Tags
+	}
+	if loc.Address != "" {
+		props["address"] = loc.Address
+	}
+	return Feature{
+		Type:       "Feature",
+		ID:         loc.ID.Hex(),
+		Geometry:   loc.Location,
+		Properties: props,
+	}
+}
+
+// locationsToFeatureCollection mengubah daftar Location menjadi FeatureCollection
+func locationsToFeatureCollection(locations []Location) FeatureCollection {
+	features := make([]Feature, 0, len(locations))
+	for _, loc := range locations {
+		features = append(features, locationToFeature(loc))
+	}
+	return newFeatureCollection(features)
+}
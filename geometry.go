@@ -0,0 +1,545 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// coordPrecision adalah jumlah digit desimal koordinat pada response JSON dan msgpack; -1 berarti tidak dibulatkan
+var coordPrecision = -1
+
+// loadCoordPrecision membaca COORD_PRECISION dari environment (kosong berarti presisi penuh)
+func loadCoordPrecision() {
+	raw := os.Getenv("COORD_PRECISION")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 || n > 15 {
+		fatal("COORD_PRECISION must be an integer between 0 and 15", "value", raw)
+	}
+	coordPrecision = n
+	slog.Info("coordinates in responses will be rounded", "decimal_places", n)
+}
+
+// roundCoord membulatkan satu nilai koordinat sesuai coordPrecision
+func roundCoord(v float64) float64 {
+	if coordPrecision < 0 {
+		return v
+	}
+	factor := math.Pow(10, float64(coordPrecision))
+	return math.Round(v*factor) / factor
+}
+
+// rounded mengembalikan salinan titik dengan koordinat dibulatkan sesuai COORD_PRECISION. Dipakai oleh
+// MarshalJSON dan EncodeMsgpack agar kedua format response sama; data yang tersimpan di database tidak ikut berubah.
+func (p Point) rounded() Point {
+	if coordPrecision < 0 {
+		return p
+	}
+	coords := make([]float64, len(p.Coordinates))
+	for i, c := range p.Coordinates {
+		coords[i] = roundCoord(c)
+	}
+	return Point{Type: p.Type, Coordinates: coords}
+}
+
+// MarshalJSON membulatkan koordinat sesuai COORD_PRECISION
+func (p Point) MarshalJSON() ([]byte, error) {
+	// Tipe alias dipakai agar json.Marshal tidak memanggil MarshalJSON ini secara rekursif
+	type rawPoint Point
+	return json.Marshal(rawPoint(p.rounded()))
+}
+
+// EncodeMsgpack membulatkan koordinat seperti MarshalJSON untuk response application/msgpack
+func (p Point) EncodeMsgpack(enc *msgpack.Encoder) error {
+	type rawPoint Point
+	return enc.Encode(rawPoint(p.rounded()))
+}
+
+// Geometry adalah geometri GeoJSON pada field location: Point, LineString, atau Polygon.
+// Coordinates berisi []float64, [][]float64, atau [][][]float64 sesuai Type; nil untuk Type yang tidak dikenal.
+type Geometry struct {
+	Type        string      `bson:"type" json:"type"`
+	Coordinates interface{} `bson:"coordinates" json:"coordinates"`
+}
+
+// newCoordinates mengembalikan pointer ke slice koordinat dengan kedalaman yang sesuai Type, atau nil
+func newCoordinates(geomType string) interface{} {
+	switch geomType {
+	case "Point":
+		return &[]float64{}
+	case "LineString":
+		return &[][]float64{}
+	case "Polygon":
+		return &[][][]float64{}
+	}
+	return nil
+}
+
+// derefCoordinates mengambil nilai slice dari pointer hasil newCoordinates
+func derefCoordinates(ptr interface{}) interface{} {
+	switch c := ptr.(type) {
+	case *[]float64:
+		return *c
+	case *[][]float64:
+		return *c
+	case *[][][]float64:
+		return *c
+	}
+	return nil
+}
+
+// UnmarshalJSON men-decode coordinates sesuai kedalaman yang diharapkan untuk Type
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	g.Type, g.Coordinates = raw.Type, nil
+	ptr := newCoordinates(raw.Type)
+	if ptr == nil || len(raw.Coordinates) == 0 || string(raw.Coordinates) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Coordinates, ptr); err != nil {
+		return fmt.Errorf("invalid coordinates for %s: %v", raw.Type, err)
+	}
+	g.Coordinates = derefCoordinates(ptr)
+	return nil
+}
+
+// UnmarshalBSON men-decode coordinates dari database sesuai kedalaman yang diharapkan untuk Type
+func (g *Geometry) UnmarshalBSON(data []byte) error {
+	var raw struct {
+		Type        string        `bson:"type"`
+		Coordinates bson.RawValue `bson:"coordinates"`
+	}
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	g.Type, g.Coordinates = raw.Type, nil
+	ptr := newCoordinates(raw.Type)
+	if ptr == nil || raw.Coordinates.Type == bson.TypeNull || len(raw.Coordinates.Value) == 0 {
+		return nil
+	}
+	if err := raw.Coordinates.Unmarshal(ptr); err != nil {
+		return fmt.Errorf("invalid coordinates for %s: %v", raw.Type, err)
+	}
+	g.Coordinates = derefCoordinates(ptr)
+	return nil
+}
+
+// rounded mengembalikan salinan geometri dengan koordinat dibulatkan sesuai COORD_PRECISION, sama seperti Point
+func (g Geometry) rounded() Geometry {
+	if coordPrecision < 0 {
+		return g
+	}
+	roundPositions := func(positions [][]float64) [][]float64 {
+		out := make([][]float64, len(positions))
+		for i, pos := range positions {
+			out[i] = make([]float64, len(pos))
+			for j, c := range pos {
+				out[i][j] = roundCoord(c)
+			}
+		}
+		return out
+	}
+	coords := g.Coordinates
+	switch c := g.Coordinates.(type) {
+	case []float64:
+		coords = roundPositions([][]float64{c})[0]
+	case [][]float64:
+		coords = roundPositions(c)
+	case [][][]float64:
+		rings := make([][][]float64, len(c))
+		for i, ring := range c {
+			rings[i] = roundPositions(ring)
+		}
+		coords = rings
+	}
+	return Geometry{Type: g.Type, Coordinates: coords}
+}
+
+// MarshalJSON membulatkan koordinat sesuai COORD_PRECISION
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	type rawGeometry Geometry
+	return json.Marshal(rawGeometry(g.rounded()))
+}
+
+// EncodeMsgpack membulatkan koordinat seperti MarshalJSON untuk response application/msgpack
+func (g Geometry) EncodeMsgpack(enc *msgpack.Encoder) error {
+	type rawGeometry Geometry
+	return enc.Encode(rawGeometry(g.rounded()))
+}
+
+// Position mengembalikan koordinat [lng, lat] jika geometri adalah Point yang lengkap
+func (g Geometry) Position() ([]float64, bool) {
+	pos, ok := g.Coordinates.([]float64)
+	if g.Type != "Point" || !ok || len(pos) != 2 {
+		return nil, false
+	}
+	return pos, true
+}
+
+// anchorPosition mengembalikan satu posisi wakil untuk perhitungan jarak di Go: titiknya sendiri untuk Point,
+// rata-rata vertex untuk LineString, dan rata-rata vertex ring luar (tanpa titik penutup) untuk Polygon
+func (g Geometry) anchorPosition() ([]float64, bool) {
+	average := func(positions [][]float64) ([]float64, bool) {
+		if len(positions) == 0 {
+			return nil, false
+		}
+		var lng, lat float64
+		for _, pos := range positions {
+			if len(pos) != 2 {
+				return nil, false
+			}
+			lng += pos[0]
+			lat += pos[1]
+		}
+		n := float64(len(positions))
+		return []float64{lng / n, lat / n}, true
+	}
+	switch c := g.Coordinates.(type) {
+	case []float64:
+		return g.Position()
+	case [][]float64:
+		return average(c)
+	case [][][]float64:
+		if len(c) == 0 || len(c[0]) < 2 {
+			return nil, false
+		}
+		return average(c[0][:len(c[0])-1])
+	}
+	return nil, false
+}
+
+// validateGeometry memeriksa kedalaman dan rentang coordinates sesuai Type
+func validateGeometry(g Geometry) error {
+	switch g.Type {
+	case "Point":
+		pos, ok := g.Coordinates.([]float64)
+		if !ok {
+			return errors.New("coordinates must be a position [lng, lat]")
+		}
+		return validatePosition(pos)
+	case "LineString":
+		line, ok := g.Coordinates.([][]float64)
+		if !ok {
+			return errors.New("coordinates must be an array of positions")
+		}
+		return validateLineString(LineString{Type: g.Type, Coordinates: line})
+	case "Polygon":
+		rings, ok := g.Coordinates.([][][]float64)
+		if !ok {
+			return errors.New("coordinates must be an array of linear rings")
+		}
+		return validatePolygon(Polygon{Type: g.Type, Coordinates: rings})
+	}
+	return errors.New("type must be one of \"Point\", \"LineString\" or \"Polygon\"")
+}
+
+// Polygon mendefinisikan struktur GeoJSON Polygon (ring luar diikuti ring lubang, jika ada)
+type Polygon struct {
+	Type        string        `bson:"type" json:"type"`
+	Coordinates [][][]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// coordClampEpsilon adalah selisih maksimum (derajat, kira-kira 10 cm) di luar rentang yang masih dianggap
+// noise pembulatan float dan dipangkas saat COORD_ON_OUT_OF_RANGE=clamp
+const coordClampEpsilon = 1e-6
+
+// clampOutOfRange aktif jika COORD_ON_OUT_OF_RANGE=clamp; default (reject) menolak semua koordinat di luar rentang
+var clampOutOfRange = false
+
+// loadCoordOutOfRange membaca COORD_ON_OUT_OF_RANGE (reject atau clamp) dari environment
+func loadCoordOutOfRange() {
+	switch raw := os.Getenv("COORD_ON_OUT_OF_RANGE"); raw {
+	case "", "reject":
+	case "clamp":
+		clampOutOfRange = true
+		slog.Info("slightly out-of-range coordinates will be clamped to the valid bound", "max_degrees", coordClampEpsilon)
+	default:
+		fatal("COORD_ON_OUT_OF_RANGE must be either reject or clamp", "value", raw)
+	}
+}
+
+// clampCoord memangkas v ke [-bound, bound] jika kelebihannya tidak lebih dari coordClampEpsilon
+func clampCoord(v, bound float64) (float64, bool) {
+	if v > bound && v-bound <= coordClampEpsilon {
+		return bound, true
+	}
+	if v < -bound && -bound-v <= coordClampEpsilon {
+		return -bound, true
+	}
+	return v, false
+}
+
+// validatePosition memastikan satu posisi [lng, lat] berada dalam rentang yang valid. Jika clampOutOfRange
+// aktif, posisi yang hanya sedikit di luar rentang dipangkas langsung di slice pos.
+func validatePosition(pos []float64) error {
+	if len(pos) != 2 {
+		return errors.New("each position must have exactly 2 coordinates [lng, lat]")
+	}
+	if clampOutOfRange {
+		lng, lngClamped := clampCoord(pos[0], 180)
+		lat, latClamped := clampCoord(pos[1], 90)
+		if lngClamped || latClamped {
+			slog.Info("clamped out-of-range position", "from", []float64{pos[0], pos[1]}, "to", []float64{lng, lat})
+			pos[0], pos[1] = lng, lat
+		}
+	}
+	if pos[0] < -180 || pos[0] > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if pos[1] < -90 || pos[1] > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	return nil
+}
+
+// validatePolygon memastikan polygon bertipe "Polygon", setiap ring-nya valid serta tertutup,
+// dan lubang (ring kedua dst.) berada di dalam ring luar tanpa saling tumpang tindih
+func validatePolygon(p Polygon) error {
+	if p.Type != "Polygon" {
+		return errors.New("polygon type must be \"Polygon\"")
+	}
+	if len(p.Coordinates) == 0 {
+		return errors.New("polygon must have at least one ring")
+	}
+	for i, ring := range p.Coordinates {
+		if len(ring) < 4 {
+			return fmt.Errorf("ring %d must have at least 4 positions", i)
+		}
+		for _, pos := range ring {
+			if err := validatePosition(pos); err != nil {
+				return fmt.Errorf("ring %d: %v", i, err)
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("ring %d is not closed (first and last positions must be equal)", i)
+		}
+	}
+
+	// Ring berikutnya adalah lubang (hole): harus berada di dalam ring luar dan tidak saling tumpang tindih
+	outer := p.Coordinates[0]
+	holes := p.Coordinates[1:]
+	for i, hole := range holes {
+		for _, pos := range hole[:len(hole)-1] {
+			if !pointInRing(pos, outer) {
+				return fmt.Errorf("hole ring %d must lie inside the outer ring", i+1)
+			}
+		}
+		if ringsCross(hole, outer) {
+			return fmt.Errorf("hole ring %d crosses the outer ring", i+1)
+		}
+		for j, other := range holes[:i] {
+			if ringsCross(hole, other) || pointInRing(hole[0], other) || pointInRing(other[0], hole) {
+				return fmt.Errorf("hole rings %d and %d overlap", j+1, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// pointInRing mengecek apakah posisi berada di dalam ring (ray casting pada bidang lng/lat)
+func pointInRing(pos []float64, ring [][]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > pos[1]) != (yj > pos[1]) && pos[0] < (xj-xi)*(pos[1]-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ringsCross mengecek apakah ada sisi ring a yang memotong sisi ring b
+func ringsCross(a, b [][]float64) bool {
+	for i := 0; i < len(a)-1; i++ {
+		for j := 0; j < len(b)-1; j++ {
+			if segmentsIntersect(a[i], a[i+1], b[j], b[j+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect mengecek apakah segmen p1-p2 dan q1-q2 saling berpotongan
+func segmentsIntersect(p1, p2, q1, q2 []float64) bool {
+	orient := func(a, b, c []float64) float64 {
+		return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	}
+	d1 := orient(q1, q2, p1)
+	d2 := orient(q1, q2, p2)
+	d3 := orient(p1, p2, q1)
+	d4 := orient(p1, p2, q2)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// BBox adalah kotak pembatas dalam derajat lng/lat
+type BBox struct {
+	West  float64 `json:"west"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	North float64 `json:"north"`
+}
+
+// bboxEdgeStep adalah jarak (derajat) antar titik bantu di sepanjang sisi horizontal bbox
+const bboxEdgeStep = 1.0
+
+// geoWithinFilter membangun filter $geoWithin untuk field location di dalam bbox
+func (b BBox) geoWithinFilter() bson.M {
+	// bbox selebar seluruh bumi tidak bisa dinyatakan sebagai ring, cukup batasi lintangnya
+	if b.East-b.West >= 360 {
+		return bson.M{"location.coordinates.1": bson.M{"$gte": b.South, "$lte": b.North}}
+	}
+	return bson.M{"location": bson.M{"$geoWithin": bson.M{"$geometry": b.polygon()}}}
+}
+
+// geoIntersectsFilter membangun filter $geoIntersects untuk field location yang bersinggungan dengan bbox,
+// sehingga LineString dan Polygon yang hanya sebagian berada di dalam bbox ikut cocok
+func (b BBox) geoIntersectsFilter() bson.M {
+	// Filter lintang hanya berlaku untuk Point, jadi bbox selebar bumi dibagi dua bagian yang masing-masing
+	// masih bisa dinyatakan sebagai ring
+	if b.East-b.West >= 360 {
+		mid := b.West + 180
+		west, east := BBox{West: b.West, South: b.South, East: mid, North: b.North}, BBox{West: mid, South: b.South, East: b.West + 360, North: b.North}
+		return bson.M{"$or": bson.A{west.geoIntersectsFilter(), east.geoIntersectsFilter()}}
+	}
+	return bson.M{"location": bson.M{"$geoIntersects": bson.M{"$geometry": b.polygon()}}}
+}
+
+// spatialFilter memilih geoWithinFilter atau geoIntersectsFilter sesuai relasi dari parseSpatialRelation
+func (b BBox) spatialFilter(relation string) bson.M {
+	if relation == relationIntersects {
+		return b.geoIntersectsFilter()
+	}
+	return b.geoWithinFilter()
+}
+
+// polygon mengubah bbox menjadi Polygon GeoJSON. Sisi horizontal dipadatkan dengan titik bantu karena MongoDB
+// memakai sisi geodesik, bukan garis lintang, dan polygon memakai CRS strict winding agar bbox yang lebih besar
+// dari satu hemisfer tetap benar.
+func (b BBox) polygon() bson.M {
+	// Ring berlawanan arah jarum jam: sisi selatan ke timur, lalu sisi utara kembali ke barat
+	ring := [][]float64{}
+	for lng := b.West; lng < b.East; lng += bboxEdgeStep {
+		ring = append(ring, []float64{lng, b.South})
+	}
+	ring = append(ring, []float64{b.East, b.South})
+	for lng := b.East; lng > b.West; lng -= bboxEdgeStep {
+		ring = append(ring, []float64{lng, b.North})
+	}
+	ring = append(ring, []float64{b.West, b.North}, []float64{b.West, b.South})
+
+	return bson.M{
+		"type":        "Polygon",
+		"coordinates": bson.A{ring},
+		"crs": bson.M{
+			"type":       "name",
+			"properties": bson.M{"name": "urn:x-mongodb:crs:strictwinding:EPSG:4326"},
+		},
+	}
+}
+
+// pointsWithinFilter seperti geoWithinFilter tetapi hanya untuk Point, dipakai oleh aggregation
+// yang menghitung langsung dengan location.coordinates.0/1
+func (b BBox) pointsWithinFilter() bson.M {
+	filter := b.geoWithinFilter()
+	filter["location.type"] = "Point"
+	return filter
+}
+
+// earthRadiusMeters adalah radius rata-rata bumi (meter) untuk perhitungan haversine
+const earthRadiusMeters = 6371008.8
+
+// haversineMeters menghitung jarak great-circle (meter) antara dua posisi [lng, lat]
+func haversineMeters(a, b []float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	lat1, lat2 := toRad(a[1]), toRad(b[1])
+	dLat := lat2 - lat1
+	dLng := toRad(b[0] - a[0])
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// initialBearing menghitung arah awal (forward azimuth, derajat 0-360 searah jarum jam dari utara)
+// dari posisi a ke posisi b [lng, lat]
+func initialBearing(a, b []float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	lat1, lat2 := toRad(a[1]), toRad(b[1])
+	dLng := toRad(b[0] - a[0])
+
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	deg := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(deg+360, 360)
+}
+
+// LineString mendefinisikan struktur GeoJSON LineString
+type LineString struct {
+	Type        string      `bson:"type" json:"type"`
+	Coordinates [][]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// validateLineString memastikan line bertipe "LineString" dengan minimal dua posisi yang valid
+func validateLineString(l LineString) error {
+	if l.Type != "LineString" {
+		return errors.New("type must be \"LineString\"")
+	}
+	if len(l.Coordinates) < 2 {
+		return errors.New("a LineString must have at least 2 positions")
+	}
+	for i, pos := range l.Coordinates {
+		if err := validatePosition(pos); err != nil {
+			return fmt.Errorf("position %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// metersPerDegree adalah panjang satu derajat lintang (meter) pada bola dengan earthRadiusMeters
+const metersPerDegree = earthRadiusMeters * math.Pi / 180
+
+// projectOntoLine memproyeksikan posisi ke polyline dan mengembalikan jarak tegak lurus ke line (meter)
+// serta jarak di sepanjang line dari titik awalnya (meter). Tiap segmen dihitung dengan proyeksi
+// equirectangular lokal, cukup akurat untuk segmen rute yang pendek.
+func projectOntoLine(pos []float64, line [][]float64) (float64, float64) {
+	bestDist, bestAlong := math.Inf(1), 0.0
+	traveled := 0.0
+	for i := 0; i < len(line)-1; i++ {
+		a, b := line[i], line[i+1]
+		cosLat := math.Cos((a[1] + b[1]) / 2 * math.Pi / 180)
+
+		// Koordinat lokal (meter) dengan a sebagai titik asal
+		bx, by := (b[0]-a[0])*cosLat*metersPerDegree, (b[1]-a[1])*metersPerDegree
+		px, py := (pos[0]-a[0])*cosLat*metersPerDegree, (pos[1]-a[1])*metersPerDegree
+
+		segLen := math.Hypot(bx, by)
+		t := 0.0
+		if segLen > 0 {
+			t = math.Max(0, math.Min(1, (px*bx+py*by)/(segLen*segLen)))
+		}
+		dist := math.Hypot(px-t*bx, py-t*by)
+		if dist < bestDist {
+			bestDist, bestAlong = dist, traveled+t*segLen
+		}
+		traveled += segLen
+	}
+	return bestDist, bestAlong
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestValidatePolygonHoles(t *testing.T) {
+	square := [][]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	// Ring luar berbentuk U dengan celah di tengah, untuk lubang yang semua titiknya di dalam tetapi sisinya
+	// memotong celah
+	notched := [][]float64{{0, 0}, {10, 0}, {10, 10}, {7, 10}, {7, 3}, {3, 3}, {3, 10}, {0, 10}, {0, 0}}
+
+	tests := []struct {
+		name    string
+		rings   [][][]float64
+		wantErr string
+	}{
+		{
+			name:  "hole inside the shell",
+			rings: [][][]float64{square, {{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}}},
+		},
+		{
+			name:    "hole partly outside the shell",
+			rings:   [][][]float64{square, {{8, 8}, {12, 8}, {12, 12}, {8, 12}, {8, 8}}},
+			wantErr: "hole ring 1 must lie inside the outer ring",
+		},
+		{
+			name:    "hole edge crossing the shell",
+			rings:   [][][]float64{notched, {{1, 5}, {9, 5}, {9, 6}, {1, 6}, {1, 5}}},
+			wantErr: "hole ring 1 crosses the outer ring",
+		},
+		{
+			name:    "hole outside the shell",
+			rings:   [][][]float64{square, {{20, 20}, {22, 20}, {22, 22}, {20, 22}, {20, 20}}},
+			wantErr: "hole ring 1 must lie inside the outer ring",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePolygon(Polygon{Type: "Polygon", Coordinates: tt.rings})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("validatePolygon() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("validatePolygon() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitialBearing(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"due north", []float64{106.8, -6.2}, []float64{106.8, -5.2}, 0},
+		{"due south", []float64{106.8, -6.2}, []float64{106.8, -7.2}, 180},
+		{"due east at the equator", []float64{0, 0}, []float64{1, 0}, 90},
+		{"due west at the equator", []float64{0, 0}, []float64{-1, 0}, 270},
+		// Jalur terpendek melewati antimeridian, bukan memutari bumi ke arah sebaliknya
+		{"eastward across the antimeridian", []float64{179.5, 0}, []float64{-179.5, 0}, 90},
+		{"westward across the antimeridian", []float64{-179.5, 0}, []float64{179.5, 0}, 270},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := initialBearing(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("initialBearing(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoordPrecisionResponses(t *testing.T) {
+	old := coordPrecision
+	coordPrecision = 3
+	t.Cleanup(func() { coordPrecision = old })
+
+	loc := Location{
+		Name:     "Monas",
+		Location: Geometry{Type: "Point", Coordinates: []float64{106.827153, -6.175392}},
+	}
+	want := []interface{}{106.827, -6.175}
+
+	for _, accept := range []string{"application/json", "application/msgpack"} {
+		t.Run(accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/locations/x", nil)
+			r.Header.Set("Accept", accept)
+			rec := httptest.NewRecorder()
+			writeResponse(rec, r, 200, loc)
+
+			var got struct {
+				Location struct {
+					Coordinates []interface{} `json:"coordinates" msgpack:"coordinates"`
+				} `json:"location" msgpack:"location"`
+			}
+			var err error
+			if accept == "application/msgpack" {
+				err = msgpack.Unmarshal(rec.Body.Bytes(), &got)
+			} else {
+				err = json.Unmarshal(rec.Body.Bytes(), &got)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Location.Coordinates, want) {
+				t.Fatalf("coordinates = %v, want %v", got.Location.Coordinates, want)
+			}
+		})
+	}
+}
+
+func TestWithinPolygonExcludesHole(t *testing.T) {
+	repo := &fakeLocationRepository{}
+	point := func(name string, lng, lat float64) {
+		repo.Create(context.Background(), Location{
+			Name:     name,
+			Location: Geometry{Type: "Point", Coordinates: []float64{lng, lat}},
+		})
+	}
+	point("shell", 1, 1)
+	point("hole", 5, 5)
+	point("outside", 20, 20)
+	s := &Server{locations: repo}
+
+	body := `{"type":"Polygon","coordinates":[` +
+		`[[0,0],[10,0],[10,10],[0,10],[0,0]],` +
+		`[[3,3],[7,3],[7,7],[3,7],[3,3]]]}`
+	for _, relation := range []string{"within", "intersects"} {
+		t.Run(relation, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.withinPolygonHandler(rec, httptest.NewRequest("POST", "/locations/within?relation="+relation, strings.NewReader(body)))
+			if rec.Code != 200 {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			var got []Location
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Name != "shell" {
+				t.Fatalf("locations = %+v, want only the point in the shell", got)
+			}
+		})
+	}
+}
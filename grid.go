@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// defaultGridSize adalah jumlah kolom/baris default untuk endpoint berbasis grid
+	defaultGridSize = 20
+	// maxGridSize adalah batas maksimum kolom/baris agar ukuran response dan beban query tetap wajar
+	maxGridSize = 100
+	// maxNearestGridSize adalah batas kolom/baris untuk grid nearest, karena setiap sel berisi dokumen lengkap
+	maxNearestGridSize = 32
+)
+
+// parseGridSize membaca query param cols dan rows, memakai default jika kosong
+// dan memangkasnya ke maxSize (dengan peringatan) jika terlalu besar
+func parseGridSize(r *http.Request, maxSize int, warns *[]string) (int, int, error) {
+	parse := func(name string) (int, error) {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			return defaultGridSize, nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%s must be a positive integer", name)
+		}
+		if n > maxSize {
+			addLimitWarning(warns, "%s was reduced from %d to the server maximum of %d", name, n, maxSize)
+			n = maxSize
+		}
+		return n, nil
+	}
+	cols, err := parse("cols")
+	if err != nil {
+		return 0, 0, err
+	}
+	rows, err := parse("rows")
+	if err != nil {
+		return 0, 0, err
+	}
+	return cols, rows, nil
+}
+
+// heatmapHandler membagi bbox menjadi grid cols x rows dan mengembalikan jumlah lokasi per sel.
+// grid[0] adalah baris paling utara, grid[i][0] adalah kolom paling barat.
+func (s *Server) heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	bbox, err := parseBBox(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	cols, rows, err := parseGridSize(r, maxGridSize, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	cellW := (bbox.East - bbox.West) / float64(cols)
+	cellH := (bbox.North - bbox.South) / float64(rows)
+
+	// Index sel dihitung di database agar hanya jumlah per sel yang dikirim, bukan setiap titik
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, bbox.pointsWithinFilter())},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"col": bson.M{"$floor": bson.M{"$divide": bson.A{
+					bson.M{"$subtract": bson.A{bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}, bbox.West}},
+					cellW,
+				}}},
+				"row": bson.M{"$floor": bson.M{"$divide": bson.A{
+					bson.M{"$subtract": bson.A{bbox.North, bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}}},
+					cellH,
+				}}},
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var cells []struct {
+		ID struct {
+			Col int `bson:"col"`
+			Row int `bson:"row"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err = cursor.All(ctx, &cells); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	grid := make([][]int64, rows)
+	for i := range grid {
+		grid[i] = make([]int64, cols)
+	}
+	for _, c := range cells {
+		// Titik tepat di sisi timur/selatan bbox dimasukkan ke sel terakhir, dan titik yang lolos $geoWithin
+		// karena sisi geodesic bbox melengkung ke kutub dimasukkan ke sel tepi terdekat
+		col, row := max(0, min(c.ID.Col, cols-1)), max(0, min(c.ID.Row, rows-1))
+		grid[row][col] += c.Count
+	}
+
+	response := map[string]interface{}{
+		"bbox":       bbox,
+		"cols":       cols,
+		"rows":       rows,
+		"cellWidth":  cellW,
+		"cellHeight": cellH,
+		"grid":       grid,
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// nearestGridHandler membagi bbox menjadi grid cols x rows dan mengembalikan, untuk setiap sel, lokasi di dalam sel
+// yang paling dekat dengan titik tengahnya (atau null jika sel kosong). Semua sel dihitung dalam satu aggregation;
+// jarak memakai pendekatan equirectangular yang cukup akurat untuk perbandingan di dalam satu sel.
+func (s *Server) nearestGridHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	bbox, err := parseBBox(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var warns []string
+	cols, rows, err := parseGridSize(r, maxNearestGridSize, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	cellW := (bbox.East - bbox.West) / float64(cols)
+	cellH := (bbox.North - bbox.South) / float64(rows)
+	// Selisih bujur diskalakan dengan cos(lintang tengah bbox) agar sebanding dengan selisih lintang
+	lngScale := math.Cos((bbox.North + bbox.South) / 2 * math.Pi / 180)
+
+	lng := bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}
+	lat := bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, bbox.pointsWithinFilter())},
+		// Titik tepat di sisi timur/selatan bbox dimasukkan ke sel terakhir. Sisi polygon bbox berupa geodesic
+		// yang melengkung ke arah kutub, sehingga titik sedikit di luar sisi utara (atau selatan di belahan
+		// selatan) bisa lolos $geoWithin; index-nya dijepit di kedua ujung agar tetap di dalam grid.
+		bson.M{"$set": bson.M{
+			"_cell_col": bson.M{"$max": bson.A{0, bson.M{"$min": bson.A{cols - 1, bson.M{"$floor": bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{lng, bbox.West}}, cellW,
+			}}}}}}},
+			"_cell_row": bson.M{"$max": bson.A{0, bson.M{"$min": bson.A{rows - 1, bson.M{"$floor": bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{bbox.North, lat}}, cellH,
+			}}}}}}},
+		}},
+		bson.M{"$set": bson.M{"_cell_d2": bson.M{"$add": bson.A{
+			bson.M{"$pow": bson.A{bson.M{"$multiply": bson.A{
+				bson.M{"$subtract": bson.A{lng, bson.M{"$add": bson.A{bbox.West, bson.M{"$multiply": bson.A{bson.M{"$add": bson.A{"$_cell_col", 0.5}}, cellW}}}}}},
+				lngScale,
+			}}, 2}},
+			bson.M{"$pow": bson.A{
+				bson.M{"$subtract": bson.A{lat, bson.M{"$subtract": bson.A{bbox.North, bson.M{"$multiply": bson.A{bson.M{"$add": bson.A{"$_cell_row", 0.5}}, cellH}}}}}},
+				2,
+			}},
+		}}}},
+		bson.M{"$sort": bson.D{{Key: "_cell_d2", Value: 1}, {Key: "_id", Value: 1}}},
+		bson.M{"$group": bson.M{
+			"_id":      bson.M{"col": "$_cell_col", "row": "$_cell_row"},
+			"location": bson.M{"$first": "$$ROOT"},
+		}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var cells []struct {
+		ID struct {
+			Col int `bson:"col"`
+			Row int `bson:"row"`
+		} `bson:"_id"`
+		Location Location `bson:"location"`
+	}
+	if err = cursor.All(ctx, &cells); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	grid := make([][]*Location, rows)
+	for i := range grid {
+		grid[i] = make([]*Location, cols)
+	}
+	for i := range cells {
+		c := &cells[i]
+		grid[c.ID.Row][c.ID.Col] = &c.Location
+	}
+
+	response := map[string]interface{}{
+		"bbox":       bbox,
+		"cols":       cols,
+		"rows":       rows,
+		"cellWidth":  cellW,
+		"cellHeight": cellH,
+		"grid":       grid,
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
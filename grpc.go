@@ -0,0 +1,750 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-mongo-railway/locationpb"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcWriteMethods adalah method yang selalu membutuhkan credential, sama seperti endpoint REST yang
+// dibungkus requireAuth. Method lain hanya membutuhkannya jika PROTECT_READS aktif.
+var grpcWriteMethods = map[string]bool{
+	locationpb.LocationService_CreateLocation_FullMethodName: true,
+	locationpb.LocationService_UpdateLocation_FullMethodName: true,
+	locationpb.LocationService_DeleteLocation_FullMethodName: true,
+}
+
+// grpcRequestKey adalah key context untuk request HTTP sintetis milik satu panggilan gRPC
+type grpcRequestKey struct{}
+
+// grpcLocationService mengimplementasikan LocationService di atas Server yang sama dengan REST API,
+// sehingga repository, validasi, audit log, dan notifikasi perubahan tidak diduplikasi
+type grpcLocationService struct {
+	locationpb.UnimplementedLocationServiceServer
+	s *Server
+}
+
+// newGRPCServer membuat server gRPC dengan LocationService dan health check standar. Sertifikat TLS_CERT_FILE
+// dipakai juga di sini, agar kedua port sama-sama terenkripsi bila TLS diaktifkan.
+func (s *Server) newGRPCServer() (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.grpcUnaryInterceptor),
+		grpc.ChainStreamInterceptor(s.grpcStreamInterceptor),
+	}
+	if config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := newTLSConfig()
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	srv := grpc.NewServer(opts...)
+	locationpb.RegisterLocationServiceServer(srv, &grpcLocationService{s: s})
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(locationpb.LocationService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+	return srv, nil
+}
+
+// grpcRequest menyusun request HTTP sintetis dari metadata panggilan gRPC, agar helper yang membaca header
+// (autentikasi, tenant, write concern, aktor audit log) berlaku sama seperti di REST API. Request ID, tenant,
+// dan pengguna disimpan di context seperti requestIDMiddleware, tenantMiddleware, dan userMiddleware.
+// Rate limit diperiksa sebelum autentikasi, sama seperti urutan middleware REST.
+func (s *Server) grpcRequest(ctx context.Context, method string) (context.Context, *http.Request, error) {
+	header := http.Header{}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		// Pseudo-header HTTP/2 (:authority dan sejenisnya) bukan metadata milik client
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, v := range values {
+			header.Add(key, v)
+		}
+	}
+	id := header.Get("X-Request-ID")
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return nil, nil, status.Error(codes.Internal, "request cannot be prepared")
+	}
+	r.Header = header
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+	if err := s.grpcRateLimit(r); err != nil {
+		return nil, nil, err
+	}
+
+	if grpcWriteMethods[method] || protectReadsEnabled() {
+		if err := authenticate(r); err != nil {
+			return nil, nil, status.Error(codes.Unauthenticated, err.msg)
+		}
+	}
+	if multiTenantEnabled() {
+		tenant, httpStatus, err := resolveTenant(r)
+		if err != nil {
+			return nil, nil, status.Error(grpcCodeForStatus(httpStatus), err.Error())
+		}
+		if tenant == "" {
+			return nil, nil, status.Error(codes.Unauthenticated, "Missing tenant API key in x-api-key, tenant_id claim in the bearer token, or x-tenant-id metadata")
+		}
+		ctx = context.WithValue(ctx, tenantKey{}, tenant)
+	}
+	ctx = context.WithValue(ctx, userKey{}, identifyUser(r))
+	r = r.WithContext(ctx)
+	return context.WithValue(ctx, grpcRequestKey{}, r), r, nil
+}
+
+// grpcRateLimit memakai token bucket yang sama dengan rateLimitMiddleware, sehingga satu client berbagi kuota
+// di kedua port. Panggilan yang melebihi limit ditolak dengan ResourceExhausted dan RetryInfo, padanan Retry-After.
+func (s *Server) grpcRateLimit(r *http.Request) error {
+	if s.rateLimiter == nil {
+		return nil
+	}
+	lim, delay := s.rateLimiter.reserve(r, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("Rate limit of %g requests per second exceeded", float64(lim.Limit()))
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
+
+// grpcRequestFrom mengembalikan request sintetis yang disimpan interceptor untuk panggilan ini
+func grpcRequestFrom(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(grpcRequestKey{}).(*http.Request)
+	return r
+}
+
+// grpcCodeForStatus memetakan status HTTP dari helper bersama ke kode gRPC padanannya
+func grpcCodeForStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	}
+	return codes.Internal
+}
+
+// isLocationServiceMethod membedakan method LocationService dari health check, yang harus tetap bisa dipanggil
+// platform tanpa credential maupun tenant
+func isLocationServiceMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+locationpb.LocationService_ServiceDesc.ServiceName+"/")
+}
+
+// grpcUnaryInterceptor menyiapkan request sintetis, memasang batas waktu REQUEST_TIMEOUT (deadline dari client
+// dipangkas ke maxRequestTimeout seperti X-Request-Timeout), lalu mencatat setiap panggilan ke log
+func (s *Server) grpcUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !isLocationServiceMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	start := time.Now()
+	timeout := config.RequestTimeout
+	if _, ok := ctx.Deadline(); ok {
+		timeout = maxRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx, span := startGRPCSpan(ctx, info.FullMethod)
+
+	ctx, r, err := s.grpcRequest(ctx, info.FullMethod)
+	if err != nil {
+		endGRPCSpan(span, err)
+		slog.Info("grpc request", "method", info.FullMethod, "code", status.Code(err).String(), "latency_ms", time.Since(start).Milliseconds())
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	endGRPCSpan(span, err)
+	grpcLogger(r).Info("grpc request", "code", status.Code(err).String(), "latency_ms", time.Since(start).Milliseconds(), "remote_addr", r.RemoteAddr)
+	return resp, err
+}
+
+// grpcStreamInterceptor seperti grpcUnaryInterceptor untuk server streaming, tanpa batas waktu karena
+// stream memang dibiarkan terbuka selama client masih tersambung
+func (s *Server) grpcStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !isLocationServiceMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	start := time.Now()
+	ctx, span := startGRPCSpan(ss.Context(), info.FullMethod)
+	ctx, r, err := s.grpcRequest(ctx, info.FullMethod)
+	if err != nil {
+		endGRPCSpan(span, err)
+		slog.Info("grpc request", "method", info.FullMethod, "code", status.Code(err).String(), "latency_ms", time.Since(start).Milliseconds())
+		return err
+	}
+	err = handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+	endGRPCSpan(span, err)
+	grpcLogger(r).Info("grpc request", "code", status.Code(err).String(), "latency_ms", time.Since(start).Milliseconds(), "remote_addr", r.RemoteAddr)
+	return err
+}
+
+// grpcMetadataCarrier membaca dan menulis trace context (traceparent, baggage) di metadata gRPC
+type grpcMetadataCarrier metadata.MD
+
+func (c grpcMetadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c grpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c grpcMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// startGRPCSpan memulai span server untuk satu panggilan gRPC, melanjutkan trace context dari metadata seperti
+// otelmux untuk header HTTP. Nama dan atribut span mengikuti konvensi semantik OpenTelemetry untuk RPC.
+func startGRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, grpcMetadataCarrier(md))
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, method, _ := strings.Cut(name, "/")
+	return otel.Tracer(tracingServiceName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(method)))
+}
+
+// endGRPCSpan mencatat kode status lalu menutup span. Sesuai konvensi semantik, span server hanya ditandai
+// error untuk kode yang menandakan kegagalan server, bukan kesalahan client seperti NotFound.
+func endGRPCSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+// grpcServerStream mengganti context stream dengan context yang sudah berisi request ID dan tenant
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcLogger seperti requestLogger untuk panggilan gRPC; path berisi nama method lengkap
+func grpcLogger(r *http.Request) *slog.Logger {
+	return withTrace(r.Context(), slog.With("request_id", requestIDFrom(r.Context()), "method", r.URL.Path))
+}
+
+// grpcError memetakan error repository ke status gRPC dengan aturan yang sama seperti writeDBError.
+// Pesan driver hanya dicatat di log karena bisa berisi detail internal.
+func grpcError(ctx context.Context, err error) error {
+	r := grpcRequestFrom(ctx)
+	var decodeErr *decodeError
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return status.Error(codes.Canceled, "Request was cancelled")
+	case errors.As(err, &decodeErr):
+		grpcLogger(r).Error("stored location cannot be decoded", "error", err)
+		return status.Errorf(codes.DataLoss, "Stored location %s has an unexpected shape and cannot be read", decodeErr.ID)
+	case errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err):
+		grpcLogger(r).Error("database operation timed out", "error", err)
+		return status.Error(codes.DeadlineExceeded, "Database operation timed out")
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return status.Error(codes.NotFound, "Location not found")
+	case isDuplicateExternalID(err):
+		return status.Error(codes.AlreadyExists, "A location with this external_id already exists")
+	case mongo.IsDuplicateKeyError(err):
+		return status.Error(codes.AlreadyExists, "A location with this name already exists")
+	}
+	grpcLogger(r).Error("database operation failed", "error", err)
+	return status.Error(codes.Internal, "Internal database error")
+}
+
+// grpcValidationError mengembalikan InvalidArgument beserta detail BadRequest per field, padanan
+// writeValidationErrors
+func grpcValidationError(errs []FieldError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(errs))
+	for i, e := range errs {
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: e.Field, Description: e.Message}
+	}
+	st, err := status.New(codes.InvalidArgument, "Validation failed").WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "Validation failed")
+	}
+	return st.Err()
+}
+
+// parseGRPCID memvalidasi ID lokasi dari request gRPC
+func parseGRPCID(raw string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return primitive.NilObjectID, status.Error(codes.InvalidArgument, "Invalid location ID format")
+	}
+	return id, nil
+}
+
+// toProtoLocation mengonversi Location ke pesan protobuf. Geometri melewati JSON agar koordinat dibulatkan
+// sama seperti response REST.
+func toProtoLocation(loc Location) *locationpb.Location {
+	pb := &locationpb.Location{
+		Id:          loc.ID.Hex(),
+		Name:        loc.Name,
+		Description: loc.Description,
+		Category:    loc.Category,
+		Tags:        loc.Tags,
+		Address:     loc.Address,
+		Location:    toProtoGeometry(loc.Location),
+		Revision:    loc.Revision,
+		CreatedAt:   timestamppb.New(loc.CreatedAt),
+		Etag:        loc.ETag(),
+	}
+	if !loc.UpdatedAt.IsZero() {
+		pb.UpdatedAt = timestamppb.New(loc.UpdatedAt)
+	}
+	if loc.ExpiresAt != nil {
+		pb.ExpiresAt = timestamppb.New(*loc.ExpiresAt)
+	}
+	return pb
+}
+
+// toProtoGeometry mengonversi geometri ke pesan protobuf; nil jika geometrinya tidak bisa di-encode
+func toProtoGeometry(g Geometry) *locationpb.Geometry {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return nil
+	}
+	var raw struct {
+		Type        string        `json:"type"`
+		Coordinates []interface{} `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	coords, err := structpb.NewList(raw.Coordinates)
+	if err != nil {
+		return nil
+	}
+	return &locationpb.Geometry{Type: raw.Type, Coordinates: coords}
+}
+
+// fromProtoLocation mengonversi pesan protobuf ke Location. Field yang ditentukan server (id, revision,
+// created_at, updated_at, etag) diabaikan; geometri di-decode lewat Geometry.UnmarshalJSON sehingga bentuknya
+// diperiksa sama seperti body JSON.
+func fromProtoLocation(pb *locationpb.Location) (Location, error) {
+	loc := Location{
+		Name:        pb.GetName(),
+		Description: pb.GetDescription(),
+		Category:    pb.GetCategory(),
+		Tags:        pb.GetTags(),
+		Address:     pb.GetAddress(),
+	}
+	if pb.GetExpiresAt() != nil {
+		expiresAt := pb.GetExpiresAt().AsTime()
+		loc.ExpiresAt = &expiresAt
+	}
+	if g := pb.GetLocation(); g != nil {
+		data, err := json.Marshal(map[string]interface{}{"type": g.GetType(), "coordinates": g.GetCoordinates().AsSlice()})
+		if err != nil {
+			return Location{}, err
+		}
+		if err := json.Unmarshal(data, &loc.Location); err != nil {
+			return Location{}, fmt.Errorf("location: %w", err)
+		}
+	}
+	return loc, nil
+}
+
+// grpcTagsFilter padanan ?tags= dengan tags_mode=all; nil jika tidak ada tag
+func grpcTagsFilter(raw []string) (bson.M, error) {
+	tags := normalizeTags(raw)
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if len(tags) > maxTagsPerLocation {
+		return nil, status.Errorf(codes.InvalidArgument, "tags accepts at most %d values", maxTagsPerLocation)
+	}
+	return bson.M{"$all": tags}, nil
+}
+
+// grpcPageLimit menerapkan default dan batas maksimum limit seperti parsePagination
+func grpcPageLimit(limit int32) (int32, error) {
+	switch {
+	case limit < 0:
+		return 0, status.Error(codes.InvalidArgument, "limit must be a positive integer")
+	case limit == 0:
+		return defaultPageLimit, nil
+	case limit > maxPageLimit:
+		return maxPageLimit, nil
+	}
+	return limit, nil
+}
+
+func (g *grpcLocationService) GetLocation(ctx context.Context, req *locationpb.GetLocationRequest) (*locationpb.Location, error) {
+	id, err := parseGRPCID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	loc, err := g.s.locations.GetByID(ctx, id)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return toProtoLocation(loc), nil
+}
+
+// ListLocations mengurutkan berdasarkan _id seperti GET /locations agar isi setiap halaman stabil
+func (g *grpcLocationService) ListLocations(ctx context.Context, req *locationpb.ListLocationsRequest) (*locationpb.ListLocationsResponse, error) {
+	limit, err := grpcPageLimit(req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+	page := req.GetPage()
+	if page < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page must be a positive integer")
+	}
+	if page == 0 {
+		page = 1
+	}
+	filter := bson.M{}
+	tags, err := grpcTagsFilter(req.GetTags())
+	if err != nil {
+		return nil, err
+	}
+	if tags != nil {
+		filter["tags"] = tags
+	}
+
+	total, err := g.s.locations.Count(ctx, filter)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	locations, skipped, err := g.s.locations.List(ctx, filter, bson.D{{Key: "_id", Value: 1}}, int64(limit), int64(page-1)*int64(limit))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	if len(skipped) > 0 {
+		grpcLogger(grpcRequestFrom(ctx)).Warn("skipped locations that cannot be decoded", "warnings", skipped)
+	}
+
+	resp := &locationpb.ListLocationsResponse{Total: total, Limit: limit, Page: page}
+	for _, loc := range locations {
+		resp.Locations = append(resp.Locations, toProtoLocation(loc))
+	}
+	return resp, nil
+}
+
+// CreateLocation menjalankan aturan yang sama dengan POST /locations, termasuk cek titik berimpit dan
+// pengisian alamat otomatis
+func (g *grpcLocationService) CreateLocation(ctx context.Context, req *locationpb.CreateLocationRequest) (*locationpb.Location, error) {
+	r := grpcRequestFrom(ctx)
+	repo, err := g.s.writeRepository(r)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	loc, err := fromProtoLocation(req.GetLocation())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	prepareNewLocation(ctx, &loc, time.Now())
+
+	if errs := loc.validate(); len(errs) > 0 {
+		return nil, grpcValidationError(errs)
+	}
+	if err := validateBusinessRules(loc, nil); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if pos, ok := loc.Location.Position(); ok && rejectCoincidentPointsEnabled() {
+		existing, err := g.s.findNearestLocation(ctx, pos[0], pos[1], coincidentEpsilonMeters())
+		if err != nil {
+			return nil, grpcError(ctx, err)
+		}
+		if existing != nil {
+			return nil, status.Errorf(codes.AlreadyExists, "A location already exists at these coordinates (%s)", existing.ID.Hex())
+		}
+	}
+	g.s.fillAddress(ctx, &loc)
+
+	if err := repo.Create(ctx, loc); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	g.s.bumpCollectionVersion(ctx)
+	g.s.notifyLocationChange(nil, &loc)
+	g.s.recordAudit(r, newAuditEvent(auditCreate, nil, &loc))
+	return toProtoLocation(loc), nil
+}
+
+// grpcUpdateFields memetakan path update_mask ke key field yang dipakai locationUpdate
+var grpcUpdateFields = map[string]string{
+	"name":        "name",
+	"description": "description",
+	"category":    "category",
+	"tags":        "tags",
+	"address":     "address",
+	"location":    "location",
+	"expires_at":  "expires_at",
+}
+
+// UpdateLocation padanan PATCH /locations/{id}: hanya field di update_mask yang diubah, dan etag wajib cocok
+// dengan revisi lokasi saat ini agar dua client tidak saling menimpa perubahan
+func (g *grpcLocationService) UpdateLocation(ctx context.Context, req *locationpb.UpdateLocationRequest) (*locationpb.Location, error) {
+	r := grpcRequestFrom(ctx)
+	id, err := parseGRPCID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	repo, err := g.s.writeRepository(r)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.GetEtag() == "" {
+		return nil, status.Error(codes.FailedPrecondition, "etag is required, send the etag returned by GetLocation")
+	}
+	fields := map[string]json.RawMessage{}
+	for _, path := range req.GetUpdateMask().GetPaths() {
+		key, ok := grpcUpdateFields[path]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "update_mask path %q cannot be updated", path)
+		}
+		fields[key] = nil
+	}
+	if len(fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask must name at least one of name, description, category, tags, address, location or expires_at")
+	}
+	patch, err := fromProtoLocation(req.GetLocation())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	existing, err := g.s.locations.GetByID(ctx, id)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	if !canModify(ctx, existing) {
+		return nil, status.Error(codes.PermissionDenied, "Only the owner of this location or an admin can change it")
+	}
+	if !etagMatches(req.GetEtag(), existing.ETag(), false) {
+		return nil, status.Errorf(codes.Aborted, "etag does not match the current revision %s, reload the location and retry", existing.ETag())
+	}
+
+	merged := existing
+	for key := range fields {
+		switch key {
+		case "name":
+			merged.Name = patch.Name
+		case "description":
+			merged.Description = patch.Description
+		case "category":
+			merged.Category = patch.Category
+		case "tags":
+			merged.Tags = normalizeTags(patch.Tags)
+		case "address":
+			merged.Address = patch.Address
+		case "location":
+			merged.Location = patch.Location
+		case "expires_at":
+			merged.ExpiresAt = patch.ExpiresAt
+		}
+	}
+	if errs := merged.validate(); len(errs) > 0 {
+		return nil, grpcValidationError(errs)
+	}
+	if err := validateBusinessRules(merged, fields); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	updated, err := repo.Update(ctx, id, revisionFilter(existing), locationUpdate(fields, merged, time.Now()))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, getErr := g.s.locations.GetByID(ctx, id); getErr == nil {
+			return nil, status.Error(codes.Aborted, "The location was changed concurrently, reload it and retry")
+		}
+		return nil, status.Error(codes.NotFound, "Location not found")
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	g.s.bumpCollectionVersion(ctx)
+	g.s.notifyLocationChange(&existing, &updated)
+	g.s.recordAudit(r, newAuditEvent(auditUpdate, &existing, &updated))
+	return toProtoLocation(updated), nil
+}
+
+// DeleteLocation memindahkan lokasi ke trash seperti DELETE /locations/{id}
+func (g *grpcLocationService) DeleteLocation(ctx context.Context, req *locationpb.DeleteLocationRequest) (*locationpb.Location, error) {
+	r := grpcRequestFrom(ctx)
+	id, err := parseGRPCID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	repo, err := g.s.writeRepository(r)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := g.s.checkLocationOwner(ctx, id); err != nil {
+		if errors.Is(err, errNotOwner) {
+			return nil, status.Error(codes.PermissionDenied, "Only the owner of this location or an admin can change it")
+		}
+		return nil, grpcError(ctx, err)
+	}
+	deleted, err := repo.Delete(ctx, id)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	g.s.bumpCollectionVersion(ctx)
+	g.s.recordAudit(r, newAuditEvent(auditDelete, &deleted, nil))
+	return toProtoLocation(deleted), nil
+}
+
+// NearLocations memakai default dan batas radius yang sama dengan GET /locations/near
+func (g *grpcLocationService) NearLocations(ctx context.Context, req *locationpb.NearLocationsRequest) (*locationpb.NearLocationsResponse, error) {
+	lng, lat := req.GetLng(), req.GetLat()
+	if math.IsNaN(lng) || lng < -180 || lng > 180 {
+		return nil, status.Error(codes.InvalidArgument, "lng must be between -180 and 180")
+	}
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return nil, status.Error(codes.InvalidArgument, "lat must be between -90 and 90")
+	}
+	maxMeters := req.GetMaxMeters()
+	switch {
+	case math.IsNaN(maxMeters) || maxMeters < 0:
+		return nil, status.Error(codes.InvalidArgument, "max_meters must be a positive number")
+	case maxMeters == 0:
+		maxMeters = defaultNearMeters
+	case maxMeters > maxNearMeters:
+		maxMeters = maxNearMeters
+	}
+	limit, err := grpcPageLimit(req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{}
+	tags, err := grpcTagsFilter(req.GetTags())
+	if err != nil {
+		return nil, err
+	}
+	if tags != nil {
+		query["tags"] = tags
+	}
+
+	locations, err := g.s.locations.Near(ctx, lng, lat, maxMeters, query, int64(limit))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	resp := &locationpb.NearLocationsResponse{}
+	for _, near := range locations {
+		resp.Locations = append(resp.Locations, &locationpb.NearLocation{
+			Location:       toProtoLocation(near.Location),
+			DistanceMeters: near.DistanceMeters,
+		})
+	}
+	return resp, nil
+}
+
+// WatchLocations padanan GET /locations/stream dengan batas jumlah stream yang sama. resume_token setiap
+// event bisa dikirim ulang untuk melanjutkan stream yang terputus tanpa kehilangan event.
+func (g *grpcLocationService) WatchLocations(req *locationpb.WatchLocationsRequest, srv locationpb.LocationService_WatchLocationsServer) error {
+	ctx := srv.Context()
+	if streamClients.Add(1) > maxStreamClients {
+		streamClients.Add(-1)
+		return status.Error(codes.ResourceExhausted, "Too many open streams, try again later")
+	}
+	defer streamClients.Add(-1)
+
+	stream, err := g.s.watchLocations(ctx, req.GetResumeToken())
+	if err != nil {
+		var se mongo.ServerError
+		switch {
+		case errors.As(err, &se) && se.HasErrorCode(errCodeChangeStreamNotSupported):
+			return status.Error(codes.Unavailable, "Change streams require MongoDB to run as a replica set")
+		case req.GetResumeToken() != "" && errors.As(err, &se):
+			return status.Error(codes.InvalidArgument, "resume_token cannot be resumed, reload the locations and watch without it")
+		}
+		return grpcError(ctx, err)
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	log := grpcLogger(grpcRequestFrom(ctx))
+	for stream.Next(ctx) {
+		var change changeEvent
+		if err := stream.Decode(&change); err != nil {
+			log.Warn("skipping change event that cannot be decoded", "error", err)
+			continue
+		}
+		evt := change.toLocationEvent()
+		token, _ := stream.ResumeToken().Lookup("_data").StringValueOK()
+		msg := &locationpb.LocationEvent{Type: evt.Type, Id: evt.ID, ResumeToken: token}
+		if evt.Location != nil {
+			msg.Location = toProtoLocation(*evt.Location)
+		}
+		if err := srv.Send(msg); err != nil {
+			return err
+		}
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		log.Error("change stream failed", "error", err)
+		return status.Error(codes.Unavailable, "Change stream failed, reconnect with the last resume_token")
+	}
+	return nil
+}
+
+// stopGRPC menunggu panggilan yang sedang berjalan selesai hingga ctx habis, lalu memutus sisanya. Stream
+// WatchLocations tidak pernah selesai sendiri, sehingga tanpa batas ini shutdown bisa menggantung.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Error("gRPC server shutdown did not complete, closing remaining calls")
+		srv.Stop()
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// readinessTimeout adalah batas waktu ping MongoDB dan pengecekan index pada /readyz, dibuat pendek
+// agar probe gagal cepat alih-alih menunggu timeout probe dari platform
+const readinessTimeout = 2 * time.Second
+
+// Informasi build diisi lewat ldflags, misalnya:
+//
+//	go build -ldflags "-X main.buildVersion=1.2.0 -X main.buildCommit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+var (
+	buildVersion = "dev"
+	buildCommit  = ""
+	buildTime    = ""
+)
+
+// BuildInfo adalah body response GET /version
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"buildTime,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// currentBuildInfo mengembalikan informasi build; jika commit tidak diisi lewat ldflags, revisi VCS
+// yang dicatat oleh go build dipakai sebagai gantinya
+func currentBuildInfo() BuildInfo {
+	info := BuildInfo{Version: buildVersion, Commit: buildCommit, BuildTime: buildTime, GoVersion: runtime.Version()}
+	if info.Commit == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
+
+// healthzHandler adalah liveness probe: selalu 200 selama proses masih bisa melayani request
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyzHandler adalah readiness probe: 200 hanya jika koneksi pertama ke MongoDB sudah berhasil, MongoDB
+// bisa di-ping, dan index 2dsphere ada,
+// selain itu 503 beserta pengecekan yang gagal sehingga traffic belum diarahkan ke instance ini
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	checks := map[string]string{"mongo": "ok", "geoIndex": "ok"}
+	ready := true
+	// Sebelum koneksi pertama berhasil, status percobaan koneksi lebih informatif daripada ping yang timeout
+	if err := s.mongoState.status(); err != nil {
+		checks["mongo"] = err.Error()
+		ready = false
+	} else if err := s.client.Ping(ctx, nil); err != nil {
+		checks["mongo"] = err.Error()
+		ready = false
+	}
+	if ready {
+		name, err := s.findGeoIndexName(ctx)
+		switch {
+		case err != nil:
+			checks["geoIndex"] = err.Error()
+			ready = false
+		case name == "":
+			checks["geoIndex"] = "2dsphere index on location is missing"
+			ready = false
+		}
+	} else {
+		checks["geoIndex"] = "skipped"
+	}
+
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"status": "unavailable", "checks": checks})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "checks": checks})
+}
+
+// versionHandler mengembalikan informasi build yang sedang berjalan
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusOK, currentBuildInfo())
+}
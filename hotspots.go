@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultHotspotMeters adalah radius default (meter) untuk menghitung tetangga sebuah lokasi
+	defaultHotspotMeters = 500
+	// maxHotspotMeters adalah radius maksimum; radius besar membuat hampir semua lokasi saling bertetangga
+	maxHotspotMeters = 5000
+	// defaultHotspotLimit adalah jumlah hotspot yang dikembalikan jika limit tidak diisi
+	defaultHotspotLimit = 20
+	// maxHotspotLimit adalah batas maksimum limit
+	maxHotspotLimit = 100
+	// maxHotspotCandidates membatasi jumlah lokasi yang dibandingkan, karena biayanya kuadratik pada kasus terburuk
+	maxHotspotCandidates = 5000
+)
+
+// Hotspot adalah lokasi beserta jumlah lokasi lain dalam radius yang diminta
+type Hotspot struct {
+	Location  `bson:",inline"`
+	Neighbors int `json:"neighbors"`
+}
+
+// parseHotspotParams membaca query param meters dan limit, memangkasnya ke batas server dengan peringatan
+func parseHotspotParams(r *http.Request, warns *[]string) (float64, int, error) {
+	q := r.URL.Query()
+	meters := float64(defaultHotspotMeters)
+	if raw := q.Get("meters"); raw != "" {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || f <= 0 {
+			return 0, 0, errors.New("meters must be a positive number")
+		}
+		if f > maxHotspotMeters {
+			addLimitWarning(warns, "meters was reduced from %g to the server maximum of %d", f, maxHotspotMeters)
+			f = maxHotspotMeters
+		}
+		meters = f
+	}
+	limit := defaultHotspotLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		if n > maxHotspotLimit {
+			addLimitWarning(warns, "limit was reduced from %d to the server maximum of %d", n, maxHotspotLimit)
+			n = maxHotspotLimit
+		}
+		limit = n
+	}
+	return meters, limit, nil
+}
+
+// countNeighbors menghitung, untuk setiap posisi [lng, lat], jumlah posisi lain dalam radius meters.
+// Lokasi diurutkan menurut lintang lalu hanya pasangan dengan selisih lintang <= radius yang dibandingkan,
+// sehingga biayanya mendekati O(n log n) untuk data yang tersebar dan O(n^2) jika semuanya berdekatan.
+func countNeighbors(positions [][]float64, meters float64) []int {
+	order := make([]int, len(positions))
+	for i := range order {
+		order[i] = i
+	}
+	lat := func(i int) float64 { return positions[i][1] }
+	sort.Slice(order, func(a, b int) bool { return lat(order[a]) < lat(order[b]) })
+
+	// Satu derajat lintang kurang lebih sama panjangnya di mana pun
+	maxDLat := meters / metersPerDegree
+	counts := make([]int, len(positions))
+	for a := range order {
+		i := order[a]
+		for b := a + 1; b < len(order); b++ {
+			j := order[b]
+			if lat(j)-lat(i) > maxDLat {
+				break
+			}
+			if haversineMeters(positions[i], positions[j]) <= meters {
+				counts[i]++
+				counts[j]++
+			}
+		}
+	}
+	return counts
+}
+
+// hotspotsHandler mengembalikan lokasi dengan jumlah tetangga terbanyak dalam radius meters.
+// Perhitungan dilakukan di memori atas paling banyak maxHotspotCandidates lokasi terbaru.
+func (s *Server) hotspotsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var warns []string
+	meters, limit, err := parseHotspotParams(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	total, err := s.countTenantDocuments(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if total > maxHotspotCandidates {
+		addLimitWarning(&warns, "only the %d most recent of %d locations were considered", maxHotspotCandidates, total)
+	}
+	setWarningHeaders(w, warns)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(maxHotspotCandidates)
+	cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, nil), opts)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	locations, skipped, err := decodeLocations(ctx, cursor)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	setWarningHeaders(w, skipped)
+	// Dokumen lama tanpa koordinat yang valid dilewati agar tidak membuat perhitungan panik;
+	// LineString dan Polygon diwakili rata-rata vertex-nya
+	valid := locations[:0]
+	var positions [][]float64
+	for _, loc := range locations {
+		if pos, ok := loc.Location.anchorPosition(); ok {
+			valid = append(valid, loc)
+			positions = append(positions, pos)
+		}
+	}
+
+	counts := countNeighbors(positions, meters)
+	hotspots := make([]Hotspot, len(valid))
+	for i, loc := range valid {
+		hotspots[i] = Hotspot{Location: loc, Neighbors: counts[i]}
+	}
+	sort.SliceStable(hotspots, func(a, b int) bool { return hotspots[a].Neighbors > hotspots[b].Neighbors })
+	if len(hotspots) > limit {
+		hotspots = hotspots[:limit]
+	}
+
+	writeResponse(w, r, http.StatusOK, hotspots)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// idempotencyTTL adalah lama hasil request dengan Idempotency-Key disimpan untuk di-replay
+	idempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength membatasi panjang header Idempotency-Key
+	maxIdempotencyKeyLength = 255
+	// idempotencyStaleAfter adalah umur record yang masih "pending" sebelum dianggap ditinggalkan (misalnya
+	// instance mati di tengah request), sehingga retry berikutnya boleh mengambil alih
+	idempotencyStaleAfter = time.Minute
+	// idempotencySaveTimeout adalah batas waktu menyimpan response ke record idempotency
+	idempotencySaveTimeout = 5 * time.Second
+)
+
+// idempotencyReplayedHeaders adalah header response yang disimpan dan dikirim ulang saat replay
+var idempotencyReplayedHeaders = []string{"Content-Type", "ETag", "Location", "Warning"}
+
+// idempotencyRecord adalah hasil satu request dengan Idempotency-Key. Record dibuat "pending" sebelum
+// handler berjalan, agar retry yang datang bersamaan tidak ikut menulis, lalu diisi response-nya.
+type idempotencyRecord struct {
+	ID          string              `bson:"_id"`
+	RequestHash string              `bson:"request_hash"`
+	Completed   bool                `bson:"completed"`
+	Status      int                 `bson:"status,omitempty"`
+	Header      map[string][]string `bson:"header,omitempty"`
+	Body        []byte              `bson:"body,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at"`
+}
+
+// ensureIdempotencyIndex membuat index TTL yang menghapus record idempotency setelah idempotencyTTL
+func (s *Server) ensureIdempotencyIndex(ctx context.Context) error {
+	_, err := s.idempotency.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(idempotencyTTL / time.Second)),
+	})
+	return err
+}
+
+// idempotencyRecorder meneruskan response ke client sambil menyimpan salinannya untuk record idempotency
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotencyRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// idempotent membungkus handler POST agar request yang diulang dengan Idempotency-Key yang sama mendapat
+// response aslinya alih-alih menulis ulang. Key berlaku per tenant, credential, dan path, sehingga client
+// berbeda tidak bisa saling membaca response lewat key yang kebetulan sama. Request tanpa header diteruskan
+// apa adanya; response 5xx tidak disimpan agar retry-nya benar-benar dicoba ulang.
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		ctx := r.Context()
+		w.Header().Set("Content-Type", "application/json")
+		if len(key) > maxIdempotencyKeyLength {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+
+		// Body dibaca dengan batas terbesar di antara endpoint write; batas milik handler tetap berlaku
+		// karena handler membaca ulang body yang sama
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBodyBytes))
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := sha256.Sum256([]byte(tenantFromContext(ctx) + "\x00" + auditActor(r) + "\x00" + r.URL.Path + "\x00" + key))
+		hash := sha256.Sum256(append([]byte(r.URL.RawQuery+"\x00"), body...))
+		record := idempotencyRecord{
+			ID:          hex.EncodeToString(scope[:]),
+			RequestHash: hex.EncodeToString(hash[:]),
+			CreatedAt:   time.Now(),
+		}
+		acquired, err := s.acquireIdempotencyKey(ctx, record)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if !acquired {
+			s.replayIdempotent(w, r, record)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// Record disimpan walau client sudah memutus koneksi, karena write-nya sudah terjadi
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencySaveTimeout)
+		defer cancel()
+		if rec.status >= 500 || rec.status == 0 {
+			if _, err := s.idempotency.DeleteOne(saveCtx, bson.M{"_id": record.ID}); err != nil {
+				requestLogger(r).Warn("idempotency key could not be released", "error", err)
+			}
+			return
+		}
+		header := map[string][]string{}
+		for _, name := range idempotencyReplayedHeaders {
+			if values := w.Header().Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+		_, err = s.idempotency.UpdateOne(saveCtx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{
+			"completed": true,
+			"status":    rec.status,
+			"header":    header,
+			"body":      rec.body.Bytes(),
+		}})
+		if err != nil {
+			requestLogger(r).Error("idempotent response could not be saved", "error", err)
+		}
+	}
+}
+
+// acquireIdempotencyKey menyimpan record pending untuk key tersebut. false berarti key sudah dipakai dan
+// masih berlaku; record pending yang sudah basi diambil alih.
+func (s *Server) acquireIdempotencyKey(ctx context.Context, record idempotencyRecord) (bool, error) {
+	_, err := s.idempotency.InsertOne(ctx, record)
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	result, err := s.idempotency.UpdateOne(ctx,
+		bson.M{"_id": record.ID, "completed": false, "created_at": bson.M{"$lt": record.CreatedAt.Add(-idempotencyStaleAfter)}},
+		bson.M{"$set": bson.M{"request_hash": record.RequestHash, "created_at": record.CreatedAt}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// replayIdempotent menjawab request yang key-nya sudah dipakai: response asli jika sudah selesai, 409 jika
+// request pertama masih berjalan, dan 422 jika key dipakai ulang untuk body yang berbeda
+func (s *Server) replayIdempotent(w http.ResponseWriter, r *http.Request, record idempotencyRecord) {
+	var stored idempotencyRecord
+	err := s.idempotency.FindOne(r.Context(), bson.M{"_id": record.ID}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Record baru saja dilepas (response 5xx) atau kedaluwarsa; client cukup mengulang
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusConflict, "idempotency_in_progress", "A request with this Idempotency-Key was just processed, retry it")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if stored.RequestHash != record.RequestHash {
+		writeError(w, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request body or query")
+		return
+	}
+	if !stored.Completed {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusConflict, "idempotency_in_progress", "A request with this Idempotency-Key is still being processed")
+		return
+	}
+
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxImportBodyBytes adalah ukuran maksimum file GeoJSON pada POST /locations/import. Lebih besar dari
+	// maxBodyBytes karena endpoint ini memang untuk memuat ribuan titik sekaligus.
+	maxImportBodyBytes = 32 << 20
+	// maxImportFeatures membatasi jumlah feature dalam satu import
+	maxImportFeatures = 50000
+	// importBatchSize adalah jumlah dokumen per InsertMany
+	importBatchSize = 500
+)
+
+// importFeature adalah satu Feature pada body import. Geometry disimpan mentah agar geometri yang rusak
+// cukup menggagalkan feature itu saja, bukan seluruh file.
+type importFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// importCollection adalah body POST /locations/import berupa GeoJSON FeatureCollection
+type importCollection struct {
+	Type     string          `json:"type"`
+	Features []importFeature `json:"features"`
+}
+
+// FeatureError menjelaskan kenapa satu feature tidak diimport
+type FeatureError struct {
+	Index  int          `json:"index"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// stringProperty mengambil properti string dari Feature; properti yang ada tetapi bukan string adalah error
+func stringProperty(props map[string]interface{}, key string) (string, error) {
+	v, ok := props[key]
+	if !ok || v == nil {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("properties.%s must be a string", key)
+	}
+	return s, nil
+}
+
+// featureToLocation memetakan properties (name, description, category) dan geometry Feature ke Location
+func featureToLocation(f importFeature, now time.Time) (Location, *FeatureError) {
+	var loc Location
+	if f.Type != "Feature" {
+		return loc, &FeatureError{Error: "type must be Feature"}
+	}
+	var err error
+	if loc.Name, err = stringProperty(f.Properties, "name"); err != nil {
+		return loc, &FeatureError{Error: err.Error()}
+	}
+	if loc.Description, err = stringProperty(f.Properties, "description"); err != nil {
+		return loc, &FeatureError{Error: err.Error()}
+	}
+	if loc.Category, err = stringProperty(f.Properties, "category"); err != nil {
+		return loc, &FeatureError{Error: err.Error()}
+	}
+	if len(f.Geometry) > 0 {
+		if err := json.Unmarshal(f.Geometry, &loc.Location); err != nil {
+			return loc, &FeatureError{Error: "geometry: " + err.Error()}
+		}
+	}
+
+	loc.ID = primitive.NewObjectID()
+	loc.NameNormalized = normalizeName(loc.Name)
+	loc.CreatedAt = now
+	loc.UpdatedAt = now
+	if errs := loc.validate(); len(errs) > 0 {
+		return loc, &FeatureError{Error: "validation failed", Fields: errs}
+	}
+	if err := validateBusinessRules(loc, nil); err != nil {
+		return loc, &FeatureError{Error: err.Error()}
+	}
+	return loc, nil
+}
+
+// importLocationsHandler mengimport GeoJSON FeatureCollection sebagai lokasi baru. Berbeda dengan
+// POST /locations/bulk, import tidak semua-atau-tidak-sama-sekali: feature yang valid tetap disimpan
+// dan feature yang gagal (validasi maupun nama duplikat) dilaporkan per index.
+func (s *Server) importLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	coll, err := s.writeCollection(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Decoder biasa (bukan decodeStrict), karena file GeoJSON dari QGIS dan sejenisnya sering membawa
+	// member tambahan seperti bbox atau crs
+	var req importCollection
+	if err := decodeSingle(json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportBodyBytes)), &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if req.Type != "FeatureCollection" {
+		writeJSONError(w, http.StatusBadRequest, "body must be a GeoJSON FeatureCollection")
+		return
+	}
+	if len(req.Features) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "features must not be empty")
+		return
+	}
+	if len(req.Features) > maxImportFeatures {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d features can be imported per request", maxImportFeatures))
+		return
+	}
+
+	locs, indexes, featureErrors, err := s.prepareImport(ctx, req.Features, time.Now())
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	inserted, insertErrors, err := insertImport(ctx, coll, locs, indexes)
+	featureErrors = append(featureErrors, insertErrors...)
+	if len(inserted) > 0 {
+		s.bumpCollectionVersion(ctx)
+		s.recordAudit(r, createAuditEvents(inserted)...)
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	sort.Slice(featureErrors, func(i, j int) bool { return featureErrors[i].Index < featureErrors[j].Index })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":    len(req.Features),
+		"inserted": len(inserted),
+		"failed":   len(featureErrors),
+		"errors":   featureErrors,
+	})
+}
+
+// prepareImport memvalidasi setiap feature dan mengembalikan lokasi yang siap disimpan beserta index feature
+// asalnya. Feature yang ditolak dicatat di FeatureError; error hanya dikembalikan jika database gagal.
+func (s *Server) prepareImport(ctx context.Context, features []importFeature, now time.Time) ([]Location, []int, []FeatureError, error) {
+	featureErrors := []FeatureError{}
+	var locs []Location
+	var indexes []int
+	for i, f := range features {
+		loc, ferr := featureToLocation(f, now)
+		if ferr != nil {
+			ferr.Index = i
+			featureErrors = append(featureErrors, *ferr)
+			continue
+		}
+		loc.TenantID = tenantFromContext(ctx)
+		if pos, ok := loc.Location.Position(); ok && rejectCoincidentPointsEnabled() {
+			existing, err := s.findNearestLocation(ctx, pos[0], pos[1], coincidentEpsilonMeters())
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if existing != nil {
+				featureErrors = append(featureErrors, FeatureError{Index: i, Error: "a location already exists at these coordinates (" + existing.ID.Hex() + ")"})
+				continue
+			}
+		}
+		locs = append(locs, loc)
+		indexes = append(indexes, i)
+	}
+	return locs, indexes, featureErrors, nil
+}
+
+// insertImport menyimpan locs per importBatchSize dan mengembalikan lokasi yang berhasil disimpan. Setiap batch
+// tidak berurutan, sehingga nama duplikat hanya menggagalkan dokumen itu sendiri dan dilaporkan sebagai
+// FeatureError dengan index dari indexes. Error lain menghentikan import; lokasi yang sudah tersimpan tetap
+// dikembalikan agar bisa dicatat.
+func insertImport(ctx context.Context, coll *mongo.Collection, locs []Location, indexes []int) ([]Location, []FeatureError, error) {
+	var inserted []Location
+	var featureErrors []FeatureError
+	for start := 0; start < len(locs); start += importBatchSize {
+		batch := locs[start:min(start+importBatchSize, len(locs))]
+		docs := make([]interface{}, len(batch))
+		for i := range batch {
+			docs[i] = batch[i]
+		}
+		_, err := coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		var bwe mongo.BulkWriteException
+		if err != nil && (!errors.As(err, &bwe) || bwe.WriteConcernError != nil) {
+			return inserted, featureErrors, err
+		}
+		failed := make(map[int]bool, len(bwe.WriteErrors))
+		for _, we := range bwe.WriteErrors {
+			failed[we.Index] = true
+			msg := we.Message
+			if isDuplicateExternalID(we.WriteError) {
+				msg = fmt.Sprintf("a location with external_id %q already exists", batch[we.Index].ExternalID)
+			} else if mongo.IsDuplicateKeyError(we.WriteError) {
+				msg = fmt.Sprintf("a location named %q already exists", batch[we.Index].Name)
+			}
+			featureErrors = append(featureErrors, FeatureError{Index: indexes[start+we.Index], Error: msg})
+		}
+		for i, loc := range batch {
+			if !failed[i] {
+				inserted = append(inserted, loc)
+			}
+		}
+	}
+	return inserted, featureErrors, nil
+}
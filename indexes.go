@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultBackgroundIndexThreshold adalah jumlah dokumen di atas mana index non-esensial dibuat di background
+const defaultBackgroundIndexThreshold = 100000
+
+// secondaryIndexes adalah index non-esensial: server tetap berfungsi benar tanpanya, hanya lebih lambat.
+// Setiap query lokasi menyebut tenant_id (null tanpa multi-tenancy), sehingga index diawali tenant_id.
+var secondaryIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "updated_at", Value: 1}}},
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "category", Value: 1}}},
+	// Index multikey untuk filter ?tags=
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "tags", Value: 1}}},
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "name_normalized", Value: 1}}},
+	// Index untuk GET /me/locations, yang mengurutkan lokasi milik satu pengguna dari yang terbaru
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	// Index text untuk GET /locations/search dan ?q= pada GET /locations; selama belum ada, keduanya mengembalikan 503.
+	// Tidak diawali tenant_id karena satu koleksi hanya boleh punya satu index text, sehingga menggantinya
+	// berarti search mati sampai index baru selesai dibangun.
+	{Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}}},
+	// Index TTL: dokumen dihapus MongoDB begitu expires_at terlewati. Index TTL harus satu field.
+	{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+}
+
+// legacyIndexNames adalah index lama tanpa tenant_id yang sudah digantikan secondaryIndexes; dihapus setelah
+// semua penggantinya berhasil dibuat agar tidak ada jeda tanpa index
+var legacyIndexNames = []string{"created_at_1", "updated_at_1", "category_1", "tags_1", "name_normalized_1"}
+
+// dropLegacyIndex menghapus index lama berdasarkan nama; index yang sudah tidak ada bukan error
+func (s *Server) dropLegacyIndex(name string) error {
+	_, err := s.collection.Indexes().DropOne(s.ctx, name)
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCode(errCodeIndexNotFound) {
+		return nil
+	}
+	return err
+}
+
+// backgroundIndexThreshold membaca INDEX_BACKGROUND_THRESHOLD dari environment
+func backgroundIndexThreshold() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("INDEX_BACKGROUND_THRESHOLD"), 10, 64); err == nil && n >= 0 {
+		return n
+	}
+	return defaultBackgroundIndexThreshold
+}
+
+// createSecondaryIndexes membuat semua index non-esensial satu per satu sambil mencatat progresnya,
+// lalu menghapus legacyIndexNames jika semuanya berhasil. Error hanya dipakai perintah reindex; saat
+// startup kegagalannya cukup tercatat di log.
+func (s *Server) createSecondaryIndexes() error {
+	failed := 0
+	for i, model := range secondaryIndexes {
+		start := time.Now()
+		name, err := s.collection.Indexes().CreateOne(s.ctx, model)
+		if err != nil {
+			failed++
+			slog.Error("secondary index creation failed", "index", i+1, "of", len(secondaryIndexes), "error", err)
+			continue
+		}
+		slog.Info("secondary index verified", "index", i+1, "of", len(secondaryIndexes), "name", name, "duration", time.Since(start).Round(time.Millisecond))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secondary indexes could not be created", failed, len(secondaryIndexes))
+	}
+	for _, name := range legacyIndexNames {
+		if err := s.dropLegacyIndex(name); err != nil {
+			slog.Warn("legacy index could not be dropped", "name", name, "error", err)
+		}
+	}
+	return nil
+}
+
+// ensureSecondaryIndexes membuat index non-esensial secara langsung pada koleksi kecil,
+// atau di goroutine terpisah pada koleksi besar agar startup tidak tertahan bermenit-menit
+func (s *Server) ensureSecondaryIndexes() {
+	count, err := s.collection.EstimatedDocumentCount(s.ctx)
+	if err != nil {
+		slog.Warn("could not estimate document count, creating secondary indexes inline", "error", err)
+		s.createSecondaryIndexes()
+		return
+	}
+
+	threshold := backgroundIndexThreshold()
+	if count <= threshold {
+		s.createSecondaryIndexes()
+		return
+	}
+
+	slog.Info("creating secondary indexes in the background", "estimated_documents", count, "threshold", threshold)
+	go s.createSecondaryIndexes()
+}
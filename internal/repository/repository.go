@@ -0,0 +1,209 @@
+// Package repository berisi akses penyimpanan lokasi yang dipakai handler CRUD dan near. Model lokasi dan aturan
+// aplikasi (tenant, trash, kedaluwarsa, projection ?fields=) tetap berada di package main, sehingga tipe
+// dokumen dan hasil near menjadi parameter tipe dan aturannya diberikan lewat Hooks.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Locations adalah operasi penyimpanan dasar untuk dokumen lokasi L dan hasil near N, sehingga handler bisa
+// diuji dengan implementasi tiruan tanpa MongoDB. Lokasi yang tidak ada dilaporkan sebagai
+// mongo.ErrNoDocuments, sama seperti driver. Lokasi di trash diperlakukan seperti tidak ada, kecuali filter
+// List/Count menyebut deleted_at sendiri.
+type Locations[L, N any] interface {
+	Create(ctx context.Context, loc L) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (L, error)
+	// List mengembalikan lokasi yang cocok beserta peringatan untuk dokumen yang tidak bisa di-decode
+	List(ctx context.Context, filter bson.M, sort bson.D, limit, skip int64) ([]L, []string, error)
+	Count(ctx context.Context, filter bson.M) (int64, error)
+	// Update menerapkan update ($set/$unset) dan mengembalikan dokumen setelah diperbarui. match berisi syarat
+	// tambahan (misalnya revisi yang diharapkan); jika tidak terpenuhi hasilnya mongo.ErrNoDocuments.
+	Update(ctx context.Context, id primitive.ObjectID, match bson.M, update bson.M) (L, error)
+	// Delete memindahkan lokasi aktif ke trash dan mengembalikan dokumen seperti sebelum dipindahkan
+	Delete(ctx context.Context, id primitive.ObjectID) (L, error)
+	// Restore mengeluarkan lokasi dari trash dan mengembalikan dokumen setelah dipulihkan
+	Restore(ctx context.Context, id primitive.ObjectID) (L, error)
+	// Purge menghapus permanen lokasi yang sudah di trash dan mengembalikan dokumen yang dihapus
+	Purge(ctx context.Context, id primitive.ObjectID) (L, error)
+	// Near mengembalikan lokasi yang cocok dengan filter dalam radius maxMeters terurut dari yang terdekat;
+	// limit 0 berarti tanpa batas
+	Near(ctx context.Context, lng, lat, maxMeters float64, filter bson.M, limit int64) ([]N, error)
+	// WithWriteConcern mengembalikan repository yang sama dengan write concern lain untuk operasi write
+	WithWriteConcern(wc *writeconcern.WriteConcern) (Locations[L, N], error)
+}
+
+// Hooks menghubungkan Mongo dengan aturan aplikasi. Semua field wajib diisi.
+type Hooks[L, N any] struct {
+	// Active membatasi filter ke lokasi aktif milik tenant request: di luar trash dan belum kedaluwarsa
+	Active func(ctx context.Context, filter bson.M) bson.M
+	// Tenant membatasi filter ke tenant request saja, untuk Restore dan Purge yang bekerja di trash
+	Tenant func(ctx context.Context, filter bson.M) bson.M
+	// Projection mengembalikan projection request, atau nil jika semua field diambil
+	Projection func(ctx context.Context) bson.M
+	// Decode membaca semua dokumen dari cursor; dokumen yang tidak cocok dilewati dan dilaporkan sebagai peringatan
+	Decode func(ctx context.Context, cursor *mongo.Cursor) ([]L, []string, error)
+	// Written men-decode dokumen yang sudah selesai dihapus atau dipindah ke trash. Write-nya sudah terjadi,
+	// sehingga dokumen yang bentuknya tidak cocok tidak boleh menjadi error.
+	Written func(raw bson.Raw, id primitive.ObjectID) L
+	// NearResult melengkapi satu hasil Near setelah di-decode
+	NearResult func(*N)
+}
+
+// DecodeError menandakan dokumen ada di database tetapi bentuknya tidak cocok dengan tipe lokasi
+type DecodeError struct {
+	ID  string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("location %s cannot be decoded: %v", e.ID, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Mongo adalah Locations di atas satu koleksi MongoDB
+type Mongo[L, N any] struct {
+	coll  *mongo.Collection
+	hooks Hooks[L, N]
+}
+
+// NewMongo membuat Locations untuk koleksi yang diberikan
+func NewMongo[L, N any](coll *mongo.Collection, hooks Hooks[L, N]) *Mongo[L, N] {
+	return &Mongo[L, N]{coll: coll, hooks: hooks}
+}
+
+func (m *Mongo[L, N]) Create(ctx context.Context, loc L) error {
+	_, err := m.coll.InsertOne(ctx, loc)
+	return err
+}
+
+// GetByID mengembalikan *DecodeError jika dokumen ada tetapi bentuknya tidak cocok dengan L,
+// agar handler bisa membedakannya dari kegagalan database
+func (m *Mongo[L, N]) GetByID(ctx context.Context, id primitive.ObjectID) (L, error) {
+	var loc L
+	opts := options.FindOne()
+	if projection := m.hooks.Projection(ctx); projection != nil {
+		opts.SetProjection(projection)
+	}
+	raw, err := m.coll.FindOne(ctx, m.hooks.Active(ctx, bson.M{"_id": id}), opts).Raw()
+	if err != nil {
+		return loc, err
+	}
+	if err := bson.Unmarshal(raw, &loc); err != nil {
+		return loc, &DecodeError{ID: id.Hex(), Err: err}
+	}
+	return loc, nil
+}
+
+func (m *Mongo[L, N]) List(ctx context.Context, filter bson.M, sort bson.D, limit, skip int64) ([]L, []string, error) {
+	opts := options.Find().SetSort(sort).SetLimit(limit).SetSkip(skip)
+	if projection := m.hooks.Projection(ctx); projection != nil {
+		opts.SetProjection(projection)
+	}
+	cursor, err := m.coll.Find(ctx, m.hooks.Active(ctx, filter), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+	return m.hooks.Decode(ctx, cursor)
+}
+
+func (m *Mongo[L, N]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	return m.coll.CountDocuments(ctx, m.hooks.Active(ctx, filter))
+}
+
+func (m *Mongo[L, N]) Update(ctx context.Context, id primitive.ObjectID, match bson.M, update bson.M) (L, error) {
+	filter := bson.M{"_id": id}
+	for k, v := range match {
+		filter[k] = v
+	}
+	var updated L
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := m.coll.FindOneAndUpdate(ctx, m.hooks.Active(ctx, filter), update, opts).Decode(&updated)
+	return updated, err
+}
+
+// Delete juga mengisi updated_at agar long-poll melihat perubahan dan client bisa membuang lokasinya
+func (m *Mongo[L, N]) Delete(ctx context.Context, id primitive.ObjectID) (L, error) {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	raw, err := m.coll.FindOneAndUpdate(ctx, m.hooks.Active(ctx, bson.M{"_id": id}),
+		bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}}, opts).Raw()
+	if err != nil {
+		var zero L
+		return zero, err
+	}
+	return m.hooks.Written(raw, id), nil
+}
+
+func (m *Mongo[L, N]) Restore(ctx context.Context, id primitive.ObjectID) (L, error) {
+	var restored L
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := m.coll.FindOneAndUpdate(ctx, m.hooks.Tenant(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}}),
+		bson.M{"$unset": bson.M{"deleted_at": ""}, "$set": bson.M{"updated_at": time.Now()}}, opts).Decode(&restored)
+	return restored, err
+}
+
+func (m *Mongo[L, N]) Purge(ctx context.Context, id primitive.ObjectID) (L, error) {
+	raw, err := m.coll.FindOneAndDelete(ctx, m.hooks.Tenant(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}})).Raw()
+	if err != nil {
+		var zero L
+		return zero, err
+	}
+	return m.hooks.Written(raw, id), nil
+}
+
+// Near memakai $geoNear agar jarak setiap hasil ikut dihitung ke distanceMeters. $geoNear harus menjadi stage
+// pertama pipeline, sehingga filter diterapkan lewat query-nya dan $limit baru dipasang sesudahnya.
+func (m *Mongo[L, N]) Near(ctx context.Context, lng, lat, maxMeters float64, filter bson.M, limit int64) ([]N, error) {
+	pipeline := bson.A{
+		bson.M{"$geoNear": bson.M{
+			"near":          bson.M{"type": "Point", "coordinates": []float64{lng, lat}},
+			"query":         m.hooks.Active(ctx, filter),
+			"distanceField": "distanceMeters",
+			"maxDistance":   maxMeters,
+			"spherical":     true,
+		}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+	if projection := m.hooks.Projection(ctx); projection != nil {
+		project := bson.M{"distanceMeters": 1}
+		for k, v := range projection {
+			project[k] = v
+		}
+		pipeline = append(pipeline, bson.M{"$project": project})
+	}
+	cursor, err := m.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []N{}
+	if err = cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+	for i := range locations {
+		m.hooks.NearResult(&locations[i])
+	}
+	return locations, nil
+}
+
+func (m *Mongo[L, N]) WithWriteConcern(wc *writeconcern.WriteConcern) (Locations[L, N], error) {
+	coll, err := m.coll.Clone(options.Collection().SetWriteConcern(wc))
+	if err != nil {
+		return nil, err
+	}
+	return NewMongo(coll, m.hooks), nil
+}
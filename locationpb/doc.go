@@ -0,0 +1,5 @@
+// Package locationpb berisi pesan protobuf dan stub gRPC LocationService yang dibuat dari location.proto.
+// Jangan mengedit file *.pb.go secara manual; jalankan go generate setelah mengubah location.proto.
+package locationpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative location.proto
@@ -0,0 +1,996 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: location.proto
+
+package locationpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Geometry adalah geometri GeoJSON; coordinates berbentuk array bersarang sesuai type
+type Geometry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Coordinates   *structpb.ListValue    `protobuf:"bytes,2,opt,name=coordinates,proto3" json:"coordinates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Geometry) Reset() {
+	*x = Geometry{}
+	mi := &file_location_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Geometry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Geometry) ProtoMessage() {}
+
+func (x *Geometry) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Geometry.ProtoReflect.Descriptor instead.
+func (*Geometry) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Geometry) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Geometry) GetCoordinates() *structpb.ListValue {
+	if x != nil {
+		return x.Coordinates
+	}
+	return nil
+}
+
+type Location struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Category    string                 `protobuf:"bytes,4,opt,name=category,proto3" json:"category,omitempty"`
+	Tags        []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	Address     string                 `protobuf:"bytes,6,opt,name=address,proto3" json:"address,omitempty"`
+	Location    *Geometry              `protobuf:"bytes,7,opt,name=location,proto3" json:"location,omitempty"`
+	Revision    int64                  `protobuf:"varint,8,opt,name=revision,proto3" json:"revision,omitempty"`
+	CreatedAt   *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt   *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	ExpiresAt   *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// etag sama dengan header ETag pada REST API, dipakai untuk UpdateLocation
+	Etag          string `protobuf:"bytes,12,opt,name=etag,proto3" json:"etag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Location) Reset() {
+	*x = Location{}
+	mi := &file_location_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Location) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Location) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Location) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Location) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Location) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Location) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Location) GetLocation() *Geometry {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *Location) GetRevision() int64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Location) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Location) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Location) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Location) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+type GetLocationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLocationRequest) Reset() {
+	*x = GetLocationRequest{}
+	mi := &file_location_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLocationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLocationRequest) ProtoMessage() {}
+
+func (x *GetLocationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLocationRequest.ProtoReflect.Descriptor instead.
+func (*GetLocationRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetLocationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListLocationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// limit default 20, maksimum 100
+	Limit int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	// page dimulai dari 1
+	Page int32 `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	// tags hanya mengembalikan lokasi yang memiliki semua tag ini
+	Tags          []string `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLocationsRequest) Reset() {
+	*x = ListLocationsRequest{}
+	mi := &file_location_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLocationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLocationsRequest) ProtoMessage() {}
+
+func (x *ListLocationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLocationsRequest.ProtoReflect.Descriptor instead.
+func (*ListLocationsRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListLocationsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLocationsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListLocationsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ListLocationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Locations     []*Location            `protobuf:"bytes,1,rep,name=locations,proto3" json:"locations,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Page          int32                  `protobuf:"varint,4,opt,name=page,proto3" json:"page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLocationsResponse) Reset() {
+	*x = ListLocationsResponse{}
+	mi := &file_location_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLocationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLocationsResponse) ProtoMessage() {}
+
+func (x *ListLocationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLocationsResponse.ProtoReflect.Descriptor instead.
+func (*ListLocationsResponse) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListLocationsResponse) GetLocations() []*Location {
+	if x != nil {
+		return x.Locations
+	}
+	return nil
+}
+
+func (x *ListLocationsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ListLocationsResponse) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLocationsResponse) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+type CreateLocationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id, revision, created_at, updated_at, dan etag diabaikan
+	Location      *Location `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateLocationRequest) Reset() {
+	*x = CreateLocationRequest{}
+	mi := &file_location_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateLocationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateLocationRequest) ProtoMessage() {}
+
+func (x *CreateLocationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateLocationRequest.ProtoReflect.Descriptor instead.
+func (*CreateLocationRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateLocationRequest) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+type UpdateLocationRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Location *Location              `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	// update_mask memilih field yang diubah: name, description, category, tags, address, location, expires_at
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	Etag          string                 `protobuf:"bytes,4,opt,name=etag,proto3" json:"etag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLocationRequest) Reset() {
+	*x = UpdateLocationRequest{}
+	mi := &file_location_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLocationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLocationRequest) ProtoMessage() {}
+
+func (x *UpdateLocationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLocationRequest.ProtoReflect.Descriptor instead.
+func (*UpdateLocationRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateLocationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateLocationRequest) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *UpdateLocationRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+func (x *UpdateLocationRequest) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+type DeleteLocationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteLocationRequest) Reset() {
+	*x = DeleteLocationRequest{}
+	mi := &file_location_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteLocationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteLocationRequest) ProtoMessage() {}
+
+func (x *DeleteLocationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteLocationRequest.ProtoReflect.Descriptor instead.
+func (*DeleteLocationRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteLocationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type NearLocationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Lng   float64                `protobuf:"fixed64,1,opt,name=lng,proto3" json:"lng,omitempty"`
+	Lat   float64                `protobuf:"fixed64,2,opt,name=lat,proto3" json:"lat,omitempty"`
+	// max_meters default 5000, maksimum sama dengan REST API
+	MaxMeters float64 `protobuf:"fixed64,3,opt,name=max_meters,json=maxMeters,proto3" json:"max_meters,omitempty"`
+	// limit default 20, maksimum 100
+	Limit         int32    `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Tags          []string `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NearLocationsRequest) Reset() {
+	*x = NearLocationsRequest{}
+	mi := &file_location_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NearLocationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearLocationsRequest) ProtoMessage() {}
+
+func (x *NearLocationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearLocationsRequest.ProtoReflect.Descriptor instead.
+func (*NearLocationsRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *NearLocationsRequest) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *NearLocationsRequest) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *NearLocationsRequest) GetMaxMeters() float64 {
+	if x != nil {
+		return x.MaxMeters
+	}
+	return 0
+}
+
+func (x *NearLocationsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *NearLocationsRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type NearLocation struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Location       *Location              `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	DistanceMeters float64                `protobuf:"fixed64,2,opt,name=distance_meters,json=distanceMeters,proto3" json:"distance_meters,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *NearLocation) Reset() {
+	*x = NearLocation{}
+	mi := &file_location_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NearLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearLocation) ProtoMessage() {}
+
+func (x *NearLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearLocation.ProtoReflect.Descriptor instead.
+func (*NearLocation) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NearLocation) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *NearLocation) GetDistanceMeters() float64 {
+	if x != nil {
+		return x.DistanceMeters
+	}
+	return 0
+}
+
+type NearLocationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Locations     []*NearLocation        `protobuf:"bytes,1,rep,name=locations,proto3" json:"locations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NearLocationsResponse) Reset() {
+	*x = NearLocationsResponse{}
+	mi := &file_location_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NearLocationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearLocationsResponse) ProtoMessage() {}
+
+func (x *NearLocationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearLocationsResponse.ProtoReflect.Descriptor instead.
+func (*NearLocationsResponse) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *NearLocationsResponse) GetLocations() []*NearLocation {
+	if x != nil {
+		return x.Locations
+	}
+	return nil
+}
+
+type WatchLocationsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// resume_token dari event terakhir yang diterima, untuk melanjutkan stream yang terputus
+	ResumeToken   string `protobuf:"bytes,1,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchLocationsRequest) Reset() {
+	*x = WatchLocationsRequest{}
+	mi := &file_location_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchLocationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchLocationsRequest) ProtoMessage() {}
+
+func (x *WatchLocationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchLocationsRequest.ProtoReflect.Descriptor instead.
+func (*WatchLocationsRequest) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchLocationsRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type LocationEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// type adalah insert, update, atau delete
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Id   string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// location kosong untuk delete
+	Location      *Location `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	ResumeToken   string    `protobuf:"bytes,4,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LocationEvent) Reset() {
+	*x = LocationEvent{}
+	mi := &file_location_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LocationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LocationEvent) ProtoMessage() {}
+
+func (x *LocationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_location_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LocationEvent.ProtoReflect.Descriptor instead.
+func (*LocationEvent) Descriptor() ([]byte, []int) {
+	return file_location_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *LocationEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *LocationEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LocationEvent) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *LocationEvent) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+var File_location_proto protoreflect.FileDescriptor
+
+const file_location_proto_rawDesc = "" +
+	"\n" +
+	"\x0elocation.proto\x12\vlocation.v1\x1a google/protobuf/field_mask.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\\\n" +
+	"\bGeometry\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12<\n" +
+	"\vcoordinates\x18\x02 \x01(\v2\x1a.google.protobuf.ListValueR\vcoordinates\"\xae\x03\n" +
+	"\bLocation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bcategory\x18\x04 \x01(\tR\bcategory\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12\x18\n" +
+	"\aaddress\x18\x06 \x01(\tR\aaddress\x121\n" +
+	"\blocation\x18\a \x01(\v2\x15.location.v1.GeometryR\blocation\x12\x1a\n" +
+	"\brevision\x18\b \x01(\x03R\brevision\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x12\n" +
+	"\x04etag\x18\f \x01(\tR\x04etag\"$\n" +
+	"\x12GetLocationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"T\n" +
+	"\x14ListLocationsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\"\x8c\x01\n" +
+	"\x15ListLocationsResponse\x123\n" +
+	"\tlocations\x18\x01 \x03(\v2\x15.location.v1.LocationR\tlocations\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04page\x18\x04 \x01(\x05R\x04page\"J\n" +
+	"\x15CreateLocationRequest\x121\n" +
+	"\blocation\x18\x01 \x01(\v2\x15.location.v1.LocationR\blocation\"\xab\x01\n" +
+	"\x15UpdateLocationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x121\n" +
+	"\blocation\x18\x02 \x01(\v2\x15.location.v1.LocationR\blocation\x12;\n" +
+	"\vupdate_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\x12\x12\n" +
+	"\x04etag\x18\x04 \x01(\tR\x04etag\"'\n" +
+	"\x15DeleteLocationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x83\x01\n" +
+	"\x14NearLocationsRequest\x12\x10\n" +
+	"\x03lng\x18\x01 \x01(\x01R\x03lng\x12\x10\n" +
+	"\x03lat\x18\x02 \x01(\x01R\x03lat\x12\x1d\n" +
+	"\n" +
+	"max_meters\x18\x03 \x01(\x01R\tmaxMeters\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\"j\n" +
+	"\fNearLocation\x121\n" +
+	"\blocation\x18\x01 \x01(\v2\x15.location.v1.LocationR\blocation\x12'\n" +
+	"\x0fdistance_meters\x18\x02 \x01(\x01R\x0edistanceMeters\"P\n" +
+	"\x15NearLocationsResponse\x127\n" +
+	"\tlocations\x18\x01 \x03(\v2\x19.location.v1.NearLocationR\tlocations\":\n" +
+	"\x15WatchLocationsRequest\x12!\n" +
+	"\fresume_token\x18\x01 \x01(\tR\vresumeToken\"\x89\x01\n" +
+	"\rLocationEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x121\n" +
+	"\blocation\x18\x03 \x01(\v2\x15.location.v1.LocationR\blocation\x12!\n" +
+	"\fresume_token\x18\x04 \x01(\tR\vresumeToken2\xc3\x04\n" +
+	"\x0fLocationService\x12E\n" +
+	"\vGetLocation\x12\x1f.location.v1.GetLocationRequest\x1a\x15.location.v1.Location\x12V\n" +
+	"\rListLocations\x12!.location.v1.ListLocationsRequest\x1a\".location.v1.ListLocationsResponse\x12K\n" +
+	"\x0eCreateLocation\x12\".location.v1.CreateLocationRequest\x1a\x15.location.v1.Location\x12K\n" +
+	"\x0eUpdateLocation\x12\".location.v1.UpdateLocationRequest\x1a\x15.location.v1.Location\x12K\n" +
+	"\x0eDeleteLocation\x12\".location.v1.DeleteLocationRequest\x1a\x15.location.v1.Location\x12V\n" +
+	"\rNearLocations\x12!.location.v1.NearLocationsRequest\x1a\".location.v1.NearLocationsResponse\x12R\n" +
+	"\x0eWatchLocations\x12\".location.v1.WatchLocationsRequest\x1a\x1a.location.v1.LocationEvent0\x01B(Z&go-mongo-railway/locationpb;locationpbb\x06proto3"
+
+var (
+	file_location_proto_rawDescOnce sync.Once
+	file_location_proto_rawDescData []byte
+)
+
+func file_location_proto_rawDescGZIP() []byte {
+	file_location_proto_rawDescOnce.Do(func() {
+		file_location_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_location_proto_rawDesc), len(file_location_proto_rawDesc)))
+	})
+	return file_location_proto_rawDescData
+}
+
+var file_location_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_location_proto_goTypes = []any{
+	(*Geometry)(nil),              // 0: location.v1.Geometry
+	(*Location)(nil),              // 1: location.v1.Location
+	(*GetLocationRequest)(nil),    // 2: location.v1.GetLocationRequest
+	(*ListLocationsRequest)(nil),  // 3: location.v1.ListLocationsRequest
+	(*ListLocationsResponse)(nil), // 4: location.v1.ListLocationsResponse
+	(*CreateLocationRequest)(nil), // 5: location.v1.CreateLocationRequest
+	(*UpdateLocationRequest)(nil), // 6: location.v1.UpdateLocationRequest
+	(*DeleteLocationRequest)(nil), // 7: location.v1.DeleteLocationRequest
+	(*NearLocationsRequest)(nil),  // 8: location.v1.NearLocationsRequest
+	(*NearLocation)(nil),          // 9: location.v1.NearLocation
+	(*NearLocationsResponse)(nil), // 10: location.v1.NearLocationsResponse
+	(*WatchLocationsRequest)(nil), // 11: location.v1.WatchLocationsRequest
+	(*LocationEvent)(nil),         // 12: location.v1.LocationEvent
+	(*structpb.ListValue)(nil),    // 13: google.protobuf.ListValue
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
+	(*fieldmaskpb.FieldMask)(nil), // 15: google.protobuf.FieldMask
+}
+var file_location_proto_depIdxs = []int32{
+	13, // 0: location.v1.Geometry.coordinates:type_name -> google.protobuf.ListValue
+	0,  // 1: location.v1.Location.location:type_name -> location.v1.Geometry
+	14, // 2: location.v1.Location.created_at:type_name -> google.protobuf.Timestamp
+	14, // 3: location.v1.Location.updated_at:type_name -> google.protobuf.Timestamp
+	14, // 4: location.v1.Location.expires_at:type_name -> google.protobuf.Timestamp
+	1,  // 5: location.v1.ListLocationsResponse.locations:type_name -> location.v1.Location
+	1,  // 6: location.v1.CreateLocationRequest.location:type_name -> location.v1.Location
+	1,  // 7: location.v1.UpdateLocationRequest.location:type_name -> location.v1.Location
+	15, // 8: location.v1.UpdateLocationRequest.update_mask:type_name -> google.protobuf.FieldMask
+	1,  // 9: location.v1.NearLocation.location:type_name -> location.v1.Location
+	9,  // 10: location.v1.NearLocationsResponse.locations:type_name -> location.v1.NearLocation
+	1,  // 11: location.v1.LocationEvent.location:type_name -> location.v1.Location
+	2,  // 12: location.v1.LocationService.GetLocation:input_type -> location.v1.GetLocationRequest
+	3,  // 13: location.v1.LocationService.ListLocations:input_type -> location.v1.ListLocationsRequest
+	5,  // 14: location.v1.LocationService.CreateLocation:input_type -> location.v1.CreateLocationRequest
+	6,  // 15: location.v1.LocationService.UpdateLocation:input_type -> location.v1.UpdateLocationRequest
+	7,  // 16: location.v1.LocationService.DeleteLocation:input_type -> location.v1.DeleteLocationRequest
+	8,  // 17: location.v1.LocationService.NearLocations:input_type -> location.v1.NearLocationsRequest
+	11, // 18: location.v1.LocationService.WatchLocations:input_type -> location.v1.WatchLocationsRequest
+	1,  // 19: location.v1.LocationService.GetLocation:output_type -> location.v1.Location
+	4,  // 20: location.v1.LocationService.ListLocations:output_type -> location.v1.ListLocationsResponse
+	1,  // 21: location.v1.LocationService.CreateLocation:output_type -> location.v1.Location
+	1,  // 22: location.v1.LocationService.UpdateLocation:output_type -> location.v1.Location
+	1,  // 23: location.v1.LocationService.DeleteLocation:output_type -> location.v1.Location
+	10, // 24: location.v1.LocationService.NearLocations:output_type -> location.v1.NearLocationsResponse
+	12, // 25: location.v1.LocationService.WatchLocations:output_type -> location.v1.LocationEvent
+	19, // [19:26] is the sub-list for method output_type
+	12, // [12:19] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_location_proto_init() }
+func file_location_proto_init() {
+	if File_location_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_location_proto_rawDesc), len(file_location_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_location_proto_goTypes,
+		DependencyIndexes: file_location_proto_depIdxs,
+		MessageInfos:      file_location_proto_msgTypes,
+	}.Build()
+	File_location_proto = out.File
+	file_location_proto_goTypes = nil
+	file_location_proto_depIdxs = nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength membatasi panjang X-Request-ID dari client agar log tidak bisa dibanjiri
+const maxRequestIDLength = 128
+
+// requestIDKey adalah key context untuk request ID
+type requestIDKey struct{}
+
+// initLogger memasang logger slog default. LOG_LEVEL (debug, info, warn, error; default info) mengatur level
+// dan LOG_FORMAT=text memilih format teks untuk pengembangan lokal; defaultnya JSON satu baris per event
+// agar log Railway bisa difilter per field. Package log bawaan ikut diarahkan ke handler yang sama.
+// Perintah CLI menulis log ke stderr agar stdout tetap bersih untuk output seperti export.
+func initLogger(cfg Config, out io.Writer) {
+	opts := &slog.HandlerOptions{Level: cfg.LogLevel}
+	var handler slog.Handler = slog.NewJSONHandler(out, opts)
+	if cfg.LogFormat == "text" {
+		handler = slog.NewTextHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// fatal mencatat error lalu menghentikan proses, pengganti log.Fatal untuk logger terstruktur
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// validRequestID menerima ID dari client hanya jika pendek dan berisi karakter yang aman ditulis ke log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c))
+	}) < 0
+}
+
+// newRequestID membuat ID acak 16 byte dalam hex
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", b)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// requestIDMiddleware memakai X-Request-ID dari client (atau proxy) jika valid, selain itu membuat yang baru.
+// ID dikirim balik di header response dan disimpan di context, agar log server bisa dicocokkan dengan
+// error yang dilaporkan client.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestIDFrom mengembalikan request ID dari context, string kosong di luar request
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger mengembalikan logger yang menyertakan method, path, dan request ID dari request
+func requestLogger(r *http.Request) *slog.Logger {
+	return withTrace(r.Context(), slog.With("request_id", requestIDFrom(r.Context()), "method", r.Method, "path", r.URL.RequestURI()))
+}
+
+// withTrace menambahkan trace_id dan span_id dari span aktif ke logger, agar baris log sebuah request bisa
+// dicari dari trace-nya; tanpa tracing logger dikembalikan apa adanya
+func withTrace(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return logger
+	}
+	return logger.With("trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultLongPollTimeout adalah lama default koneksi long-poll ditahan sebelum mengembalikan hasil kosong
+	defaultLongPollTimeout = 30 * time.Second
+	// maxLongPollTimeout adalah batas maksimum timeout long-poll yang boleh diminta client
+	maxLongPollTimeout = 60 * time.Second
+	// longPollInterval adalah jeda antar pengecekan perubahan ke database
+	longPollInterval = time.Second
+)
+
+// longPollLocationsHandler menahan request GET /locations?waitFor=changes sampai ada lokasi yang dibuat atau
+// diperbarui setelah since, atau mengembalikan array kosong ketika timeout tercapai. Hanya lokasi aktif yang
+// dikirim: lokasi yang dipindah ke trash atau sudah kedaluwarsa tidak muncul, sehingga client yang butuh
+// event penghapusan harus memakai GET /locations/stream. Paling banyak limit lokasi dikirim per response, terurut
+// updated_at; jika terpotong, Warning menyebut since untuk request berikutnya.
+func (s *Server) longPollLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+
+	since, err := time.Parse(time.RFC3339Nano, q.Get("since"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+		return
+	}
+
+	timeout := defaultLongPollTimeout
+	if raw := q.Get("timeout"); raw != "" {
+		timeout, err = time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "timeout must be a positive duration such as 30s")
+			return
+		}
+	}
+	var warns []string
+	limit, _, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if timeout > maxLongPollTimeout {
+		addLimitWarning(&warns, "timeout was reduced from %s to the server maximum of %s", timeout, maxLongPollTimeout)
+		timeout = maxLongPollTimeout
+	}
+
+	// Dokumen lama belum memiliki updated_at, sehingga created_at tetap ikut dicek
+	changed := bson.M{"$or": bson.A{
+		bson.M{"updated_at": bson.M{"$gt": since}},
+		bson.M{"created_at": bson.M{"$gt": since}},
+	}}
+	// _id sebagai tiebreaker agar dokumen dengan updated_at yang sama selalu keluar dalam urutan yang sama.
+	// Satu dokumen lebih diambil untuk mengetahui apakah hasilnya terpotong.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit) + 1)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(longPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Filter disusun ulang setiap putaran agar lokasi yang kedaluwarsa selama menunggu ikut tersaring
+		cursor, err := s.collection.Find(r.Context(), withoutDeleted(r.Context(), changed), findOptions)
+		if err != nil {
+			// Client sudah memutus koneksi, tidak perlu menulis response
+			if r.Context().Err() != nil {
+				return
+			}
+			writeDBError(w, r, err)
+			return
+		}
+
+		locations := []Location{}
+		err = cursor.All(r.Context(), &locations)
+		cursor.Close(r.Context())
+		if err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			writeDBError(w, r, err)
+			return
+		}
+
+		if len(locations) > 0 {
+			if len(locations) > limit {
+				locations = locations[:limit]
+				last := locations[limit-1]
+				next := last.UpdatedAt
+				if next.IsZero() {
+					next = last.CreatedAt
+				}
+				addLimitWarning(&warns, "results were truncated to %d locations, poll again with since=%s for the rest", limit, next.UTC().Format(time.RFC3339Nano))
+			}
+			setWarningHeaders(w, warns)
+			writeResponse(w, r, http.StatusOK, locations)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			setWarningHeaders(w, warns)
+			writeResponse(w, r, http.StatusOK, locations)
+			return
+		case <-ticker.C:
+		}
+	}
+}
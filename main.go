@@ -2,26 +2,109 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"google.golang.org/grpc"
 )
 
-// collection adalah variabel global untuk menyimpan koneksi ke koleksi MongoDB
-var collection *mongo.Collection
+const (
+	// connectTimeout adalah batas waktu satu ping ke MongoDB saat menunggu koneksi pertama
+	connectTimeout = 10 * time.Second
+	// connectRetryDelay adalah jeda sebelum percobaan kedua; jeda berikutnya berlipat ganda
+	connectRetryDelay = time.Second
+	// maxConnectRetryDelay membatasi jeda antar percobaan agar koneksi cepat pulih setelah MongoDB siap
+	maxConnectRetryDelay = 30 * time.Second
+	// shutdownTimeout adalah waktu yang diberikan untuk request yang sedang berjalan saat server dihentikan
+	shutdownTimeout = 15 * time.Second
+	// maxRequestTimeout adalah batas maksimum yang boleh diminta client lewat X-Request-Timeout
+	maxRequestTimeout = 30 * time.Second
+)
+
+// maxTTLSeconds adalah batas maksimum X-TTL-Seconds (satu tahun)
+const maxTTLSeconds = 365 * 24 * 60 * 60
+
+// Server menyimpan dependency yang dipakai semua handler, sehingga handler bisa dijalankan terhadap koleksi
+// lain (misalnya koleksi sementara saat pengujian) tanpa menjalankan main
+type Server struct {
+	// client adalah koneksi MongoDB, disimpan agar bisa diputus saat shutdown
+	client *mongo.Client
+	// collection adalah koleksi lokasi
+	collection *mongo.Collection
+	// locations adalah akses ke koleksi lokasi untuk handler CRUD dan near; bisa diganti implementasi tiruan
+	locations LocationRepository
+	// meta menyimpan dokumen metadata koleksi lokasi (saat ini hanya versi)
+	meta *mongo.Collection
+	// ctx adalah context dasar untuk operasi database di luar request, seperti pembuatan index
+	ctx context.Context
+	// centroids adalah cache hasil GET /locations/category-centroids
+	centroids categoryCentroidsCache
+	// mongoState mencatat apakah koneksi pertama ke MongoDB sudah berhasil, untuk /readyz
+	mongoState connectionState
+	// geofences adalah koleksi geofence, di database yang sama dengan nama <koleksi>_geofences
+	geofences *mongo.Collection
+	// auditEvents adalah audit log semua write lokasi, di database yang sama dengan nama <koleksi>_audit_events
+	auditEvents *mongo.Collection
+	// idempotency menyimpan response request dengan Idempotency-Key, dengan nama <koleksi>_idempotency
+	idempotency *mongo.Collection
+	// favorites menyimpan lokasi favorit setiap pengguna, dengan nama <koleksi>_favorites
+	favorites *mongo.Collection
+	// tracks menyimpan riwayat posisi perangkat bergerak, koleksi time-series <koleksi>_tracks
+	tracks *mongo.Collection
+	// transactions bernilai true jika MongoDB mendukung transaksi multi-dokumen (replica set atau mongos)
+	transactions atomic.Bool
+	// webhooks mengirim event geofence; nil jika GEOFENCE_WEBHOOK_URLS tidak diset
+	webhooks *webhookNotifier
+	// geocoder adalah provider geocoding untuk /geocode dan /reverse; nil jika GEOCODER tidak diset
+	geocoder geocoder
+	// photos menyimpan file foto lokasi: GridFS <koleksi>_photos, atau bucket S3 jika PHOTO_STORAGE=s3
+	photos photoStore
+	// rateLimiter membatasi request REST dan panggilan gRPC per client; nil jika RATE_LIMIT_RPS=0
+	rateLimiter *requestRateLimiter
+}
 
-// ctx adalah variabel global untuk context, digunakan di semua operasi database
-var ctx = context.Background()
+// NewServer membuat Server untuk koleksi lokasi yang diberikan; koleksi pendukung (metadata, geofence, audit log,
+// idempotency, favorit, dan track) diletakkan di database yang sama dengan nama <koleksi>_meta,
+// <koleksi>_geofences, <koleksi>_audit_events, <koleksi>_idempotency, <koleksi>_favorites, dan <koleksi>_tracks
+func NewServer(collection *mongo.Collection) *Server {
+	return &Server{
+		client:      collection.Database().Client(),
+		collection:  collection,
+		locations:   newMongoLocationRepository(collection),
+		meta:        collection.Database().Collection(collection.Name() + "_meta"),
+		geofences:   collection.Database().Collection(collection.Name() + "_geofences"),
+		auditEvents: collection.Database().Collection(collection.Name() + "_audit_events"),
+		idempotency: collection.Database().Collection(collection.Name() + "_idempotency"),
+		favorites:   collection.Database().Collection(collection.Name() + "_favorites"),
+		tracks:      collection.Database().Collection(collection.Name() + "_tracks"),
+		photos:      newGridFSPhotoStore(collection),
+		ctx:         context.Background(),
+	}
+}
 
 // Point mendefinisikan struktur GeoJSON Point sesuai standar MongoDB
 type Point struct {
@@ -29,187 +112,1064 @@ type Point struct {
 	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
 }
 
-// Location adalah model data (struct) untuk setiap lokasi yang disimpan
+// Location adalah model data (struct) untuk setiap lokasi yang disimpan.
+// NameNormalized hanya disimpan di database (untuk autocomplete) dan tidak ikut dikirim ke client.
+// DeletedAt hanya terisi untuk lokasi yang sedang di trash. Revision naik setiap kali lokasi diubah lewat
+// update dan menjadi bagian dari ETag-nya. TenantID diisi dari tenant request saat dibuat dan tidak pernah
+// dikirim ke client maupun bisa diubah lewat body. Photos hanya diubah lewat endpoint /photos. OwnerID adalah
+// klaim sub JWT pembuatnya; hanya pemilik atau admin yang boleh mengubah lokasi yang punya pemilik. ExternalID
+// adalah ID lokasi di sistem sumber, unik per tenant, yang dipakai PUT /locations/by-external-id/{externalId}.
 type Location struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name        string             `bson:"name" json:"name"`
-	Description string             `bson:"description,omitempty" json:"description,omitempty"`
-	Location    Point              `bson:"location" json:"location"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name           string             `bson:"name" json:"name"`
+	NameNormalized string             `bson:"name_normalized,omitempty" json:"-"`
+	TenantID       string             `bson:"tenant_id,omitempty" json:"-"`
+	OwnerID        string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	ExternalID     string             `bson:"external_id,omitempty" json:"external_id,omitempty"`
+	Description    string             `bson:"description,omitempty" json:"description,omitempty"`
+	Category       string             `bson:"category,omitempty" json:"category,omitempty"`
+	Tags           []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	Address        string             `bson:"address,omitempty" json:"address,omitempty"`
+	Revision       int64              `bson:"revision,omitempty" json:"revision"`
+	Location       Geometry           `bson:"location" json:"location"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	DeletedAt      *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Photos         []Photo            `bson:"photos,omitempty" json:"photos,omitempty"`
 }
 
-// initDB berfungsi untuk menginisialisasi koneksi ke database MongoDB
-func initDB() {
-	if err := godotenv.Load(); err != nil {
-		fmt.Println("No .env file found, reading environment variables from system")
+// initDB membuat client MongoDB untuk koleksi lokasi tanpa menunggu server bisa dihubungi; koneksi dibuka
+// di background oleh driver dan ditunggu oleh waitForMongo. Hanya URI yang tidak valid yang menghentikan proses.
+func initDB(cfg Config) *mongo.Collection {
+	clientOptions := options.Client().
+		ApplyURI(cfg.MongoURI).
+		SetRetryWrites(true).
+		SetRetryReads(true).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout).
+		SetMaxPoolSize(cfg.MaxPoolSize)
+	// Setiap perintah MongoDB menjadi span anak dari span request jika tracing aktif
+	var tracingMonitor *event.CommandMonitor
+	if tracingEnabled() {
+		tracingMonitor = otelmongo.NewMonitor()
 	}
+	clientOptions.SetMonitor(mongoCommandMonitor(tracingMonitor))
+	clientOptions.SetPoolMonitor(mongoPoolMonitor())
 
-	mongoURL := os.Getenv("MONGO_PUBLIC_URL")
-	if mongoURL == "" {
-		log.Fatal("MONGO_PUBLIC_URL environment variable is not set")
+	client, err := mongo.Connect(context.Background(), clientOptions)
+	if err != nil {
+		fatal("invalid MongoDB client configuration", "error", err)
 	}
+	return client.Database(cfg.Database).Collection(cfg.Collection)
+}
 
-	clientOptions := options.Client().ApplyURI(mongoURL)
-	client, err := mongo.Connect(ctx, clientOptions)
-	if err != nil {
-		log.Fatal(err)
+// connectionState adalah status koneksi pertama ke MongoDB yang dilaporkan oleh /readyz
+type connectionState struct {
+	mu        sync.Mutex
+	connected bool
+	attempts  int
+	lastErr   error
+}
+
+// record mencatat hasil satu percobaan koneksi
+func (c *connectionState) record(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts++
+	c.lastErr = err
+	c.connected = err == nil
+}
+
+// status mengembalikan nil jika sudah terhubung, atau error yang menjelaskan percobaan terakhir
+func (c *connectionState) status() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.connected:
+		return nil
+	case c.lastErr == nil:
+		return errors.New("connecting")
+	default:
+		return fmt.Errorf("not connected after %d attempts: %w", c.attempts, c.lastErr)
+	}
+}
+
+// waitForMongo melakukan ping sampai MongoDB bisa dihubungi, dengan jeda yang berlipat ganda hingga
+// maxConnectRetryDelay, lalu membuat index. Saat deploy, MongoDB sering baru siap beberapa detik setelah
+// aplikasi; selama itu server tetap jalan dan /readyz mengembalikan 503 alih-alih proses crash-loop.
+func (s *Server) waitForMongo(ctx context.Context) {
+	delay := connectRetryDelay
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+		err := s.client.Ping(pingCtx, nil)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		s.mongoState.record(err)
+		if err == nil {
+			break
+		}
+		slog.Warn("MongoDB connection attempt failed, retrying", "attempt", attempt, "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxConnectRetryDelay)
 	}
 
-	err = client.Ping(ctx, nil)
+	slog.Info("connected to MongoDB")
+	s.detectTransactionSupport(ctx)
+	s.ensureIndexes()
+}
+
+// ensureIndexes membuat semua index yang dibutuhkan koleksi lokasi
+func (s *Server) ensureIndexes() {
+	// Index 2dsphere wajib ada sebelum /readyz melapor siap; index sekunder boleh menyusul
+	s.ensurePrimaryIndexes()
+	s.ensureSecondaryIndexes()
+}
+
+// ensurePrimaryIndexes membuat index 2dsphere, unique name, dan index koleksi pendamping. Setiap kegagalan
+// dicatat di log lalu dikembalikan bersama-sama, agar satu index yang gagal tidak menghentikan yang lain.
+func (s *Server) ensurePrimaryIndexes() error {
+	var errs []error
+	err := s.ensureGeoIndex(s.ctx)
 	if err != nil {
-		log.Fatal(err)
+		slog.Warn("2dsphere index creation might have failed (or already exists)", "error", err)
+		errs = append(errs, err)
+	} else {
+		slog.Info("2dsphere index verified", "field", "location")
 	}
 
-	fmt.Println("Successfully connected to MongoDB!")
+	// Gagal jika koleksi sudah berisi nama ganda; duplikat tersebut harus dibereskan manual dulu
+	if err := s.ensureUniqueNameIndex(s.ctx); err != nil {
+		slog.Warn("unique index could not be created (existing duplicates?)", "field", "name", "error", err)
+		errs = append(errs, err)
+	} else {
+		slog.Info("unique index verified", "field", "name")
+	}
 
-	collection = client.Database("test").Collection("locations")
+	if err := s.ensureGeofenceIndex(s.ctx); err != nil {
+		slog.Warn("geofence 2dsphere index creation failed", "error", err)
+		errs = append(errs, err)
+	}
+	if err := s.ensureAuditIndexes(s.ctx); err != nil {
+		slog.Warn("audit index creation failed", "error", err)
+		errs = append(errs, err)
+	}
+	if err := s.ensureIdempotencyIndex(s.ctx); err != nil {
+		slog.Warn("idempotency TTL index creation failed", "error", err)
+		errs = append(errs, err)
+	}
+	if err := s.ensureExternalIDIndex(s.ctx); err != nil {
+		slog.Warn("unique index could not be created (existing duplicates?)", "field", "external_id", "error", err)
+		errs = append(errs, err)
+	} else {
+		slog.Info("unique index verified", "field", "external_id")
+	}
+	if err := s.ensureFavoriteIndexes(s.ctx); err != nil {
+		slog.Warn("favorite index creation failed", "error", err)
+		errs = append(errs, err)
+	}
+	if err := s.ensureTrackCollection(s.ctx); err != nil {
+		slog.Warn("track index creation failed", "error", err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
 
+// ensureGeoIndex membuat index 2dsphere pada field location (no-op jika sudah ada)
+func (s *Server) ensureGeoIndex(ctx context.Context) error {
 	indexModel := mongo.IndexModel{
 		Keys: bson.M{"location": "2dsphere"},
 	}
-	_, err = collection.Indexes().CreateOne(ctx, indexModel)
-	if err != nil {
-		fmt.Printf("Index creation might have failed (or already exists): %v\n", err)
-	} else {
-		fmt.Println("2dsphere index on 'location' field verified.")
+	_, err := s.collection.Indexes().CreateOne(ctx, indexModel)
+	return err
+}
+
+// ensureUniqueNameIndex membuat index unique pada tenant_id dan name agar lokasi yang sama tidak tersimpan dua
+// kali dalam satu tenant, lalu menghapus index unique lama yang hanya pada name (yang melarang dua tenant
+// memakai nama yang sama)
+func (s *Server) ensureUniqueNameIndex(ctx context.Context) error {
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
 	}
+	if _, err := s.collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return err
+	}
+	return s.dropLegacyIndex("name_1")
+}
+
+// parseWriteConcern membaca header X-Write-Concern (1 atau majority); nil berarti header tidak diisi
+func parseWriteConcern(r *http.Request) (*writeconcern.WriteConcern, error) {
+	switch r.Header.Get("X-Write-Concern") {
+	case "":
+		return nil, nil
+	case "1":
+		return writeconcern.W1(), nil
+	case "majority":
+		return writeconcern.Majority(), nil
+	default:
+		return nil, errors.New("X-Write-Concern must be either 1 or majority")
+	}
+}
+
+// writeCollection mengembalikan koleksi dengan write concern dari header X-Write-Concern.
+// Tanpa header, koleksi default dipakai apa adanya.
+func (s *Server) writeCollection(r *http.Request) (*mongo.Collection, error) {
+	wc, err := parseWriteConcern(r)
+	if err != nil || wc == nil {
+		return s.collection, err
+	}
+	return s.collection.Clone(options.Collection().SetWriteConcern(wc))
+}
+
+// prepareNewLocation mengisi field yang ditentukan server untuk lokasi baru dan membuang nilai dari client
+// untuk field tersebut. Dipakai semua jalur create agar hasilnya sama.
+func prepareNewLocation(ctx context.Context, loc *Location, now time.Time) {
+	loc.ID = primitive.NewObjectID()
+	loc.NameNormalized = normalizeName(loc.Name)
+	loc.Tags = normalizeTags(loc.Tags)
+	loc.DeletedAt = nil
+	loc.Photos = nil
+	loc.Revision = 0
+	loc.TenantID = tenantFromContext(ctx)
+	loc.OwnerID = userFromContext(ctx).ID
+	loc.CreatedAt = now
+	loc.UpdatedAt = now
 }
 
 // createLocationHandler: Saat sukses, mengembalikan data yang baru dibuat. Ini sudah pesan sukses yang sangat baik.
-func createLocationHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) createLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var loc Location
+	if err := decodeBody(w, r, &loc); err != nil {
+		writeBodyError(w, err)
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	prepareNewLocation(ctx, &loc, time.Now())
+
+	// X-TTL-Seconds membuat dokumen kedaluwarsa otomatis. Reaper TTL MongoDB berjalan kira-kira
+	// sekali per menit, jadi dokumen bisa masih terlihat hingga ~60 detik setelah expires_at.
+	if raw := r.Header.Get("X-TTL-Seconds"); raw != "" {
+		seconds, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || seconds <= 0 || seconds > maxTTLSeconds {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("X-TTL-Seconds must be an integer between 1 and %d", maxTTLSeconds))
+			return
+		}
+		expiresAt := loc.CreatedAt.Add(time.Duration(seconds) * time.Second)
+		loc.ExpiresAt = &expiresAt
+	}
+
+	if errs := loc.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if err := validateBusinessRules(loc, nil); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
-	loc.ID = primitive.NewObjectID()
-	loc.CreatedAt = time.Now()
+	// Cegah pin berimpit sejak awal, alih-alih membersihkannya belakangan lewat merge-exact-duplicates
+	if pos, ok := loc.Location.Position(); ok && rejectCoincidentPointsEnabled() {
+		existing, err := s.findNearestLocation(ctx, pos[0], pos[1], coincidentEpsilonMeters())
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if existing != nil {
+			writeErrorDetails(w, http.StatusConflict, "coincident_location", "A location already exists at these coordinates",
+				map[string]interface{}{"existingId": existing.ID.Hex()})
+			return
+		}
+	}
 
-	_, err := collection.InsertOne(ctx, loc)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Alamat diisi setelah validasi agar provider tidak dipanggil untuk lokasi yang akan ditolak
+	s.fillAddress(ctx, &loc)
+
+	if err := repo.Create(ctx, loc); err != nil {
+		s.writeWriteError(w, r, err)
 		return
 	}
+	s.bumpCollectionVersion(ctx)
+	s.notifyLocationChange(nil, &loc)
+	s.recordAudit(r, newAuditEvent(auditCreate, nil, &loc))
+
+	w.Header().Set("ETag", loc.ETag())
+	writeJSON(w, http.StatusCreated, loc)
+}
+
+const (
+	// defaultPageLimit adalah jumlah lokasi per halaman jika limit tidak diisi
+	defaultPageLimit = 20
+	// maxPageLimit adalah batas maksimum limit; nilai yang lebih besar dipangkas dengan peringatan
+	maxPageLimit = 100
+)
+
+// parsePagination membaca query param limit dan page (dimulai dari 1), memangkas limit ke maxPageLimit
+func parsePagination(r *http.Request, warns *[]string) (int, int, error) {
+	q := r.URL.Query()
+	limit, page := defaultPageLimit, 1
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		if n > maxPageLimit {
+			addLimitWarning(warns, "limit was reduced from %d to the server maximum of %d", n, maxPageLimit)
+			n = maxPageLimit
+		}
+		limit = n
+	}
+	if raw := q.Get("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.New("page must be a positive integer")
+		}
+		page = n
+	}
+	return limit, page, nil
+}
+
+// parseCursor membaca query param after (ID terakhir dari halaman sebelumnya) dan sort (id atau -id).
+// after tidak bisa digabung dengan page karena keduanya menentukan posisi halaman.
+func parseCursor(r *http.Request) (primitive.ObjectID, int, error) {
+	q := r.URL.Query()
+	direction := 1
+	switch q.Get("sort") {
+	case "", "id":
+	case "-id":
+		direction = -1
+	default:
+		return primitive.NilObjectID, 0, errors.New("sort must be either id or -id")
+	}
+	raw := q.Get("after")
+	if raw == "" {
+		return primitive.NilObjectID, direction, nil
+	}
+	if q.Get("page") != "" {
+		return primitive.NilObjectID, 0, errors.New("page and after cannot be combined")
+	}
+	after, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return primitive.NilObjectID, 0, errors.New("after must be a location ID")
+	}
+	return after, direction, nil
+}
+
+// listPageQuery menyusun sort dan skip satu halaman GET /locations. Urut berdasarkan _id agar isi setiap halaman
+// stabil antar request, juga untuk lokasi dengan created_at yang sama. Dengan after, filter dibatasi ke ID
+// setelahnya (memakai index _id) sehingga tidak perlu skip yang makin lambat di halaman belakang.
+func listPageQuery(filter bson.M, after primitive.ObjectID, direction, page, limit int) (bson.D, int64) {
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if after == primitive.NilObjectID {
+		return sort, int64((page - 1) * limit)
+	}
+	op := "$gt"
+	if direction < 0 {
+		op = "$lt"
+	}
+	filter["_id"] = bson.M{op: after}
+	return sort, 0
+}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(loc)
+// nextListCursor mengembalikan cursor halaman berikutnya, atau "" jika ini halaman terakhir. Halaman penuh
+// berarti mungkin masih ada data berikutnya; dokumen yang dilewati ikut dihitung agar cursor tidak berhenti
+// lebih awal.
+func nextListCursor(locations []Location, skipped []string, limit int) string {
+	if len(locations) > 0 && len(locations)+len(skipped) == limit {
+		return locations[len(locations)-1].ID.Hex()
+	}
+	return ""
 }
 
-// getLocationsHandler: Saat sukses, mengembalikan array data. Ini juga sudah merupakan pesan sukses.
-func getLocationsHandler(w http.ResponseWriter, r *http.Request) {
+// getLocationsHandler mengembalikan satu halaman lokasi (limit & page, atau limit & after), beserta total dokumen
+// jika withTotal=true. Filter dari parseListFilter bisa digabung dengan kedua cara paginasi.
+func (s *Server) getLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.URL.Query().Get("waitFor") == "changes" {
+		s.longPollLocationsHandler(w, r)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	var locations []Location
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format, err := parseFormat(r, formatJSON)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	after, direction, err := parseCursor(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Format peta butuh geometri dan atribut tetap, sehingga pemilihan field hanya untuk JSON biasa
+	if fields != nil && format != formatJSON {
+		writeJSONError(w, http.StatusBadRequest, "fields can only be used with format=json")
 		return
 	}
-	defer cursor.Close(ctx)
 
-	if err = cursor.All(ctx, &locations); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// ETag mengikuti versi koleksi, sehingga client yang menyimpan halaman ini cukup memvalidasi ulang
+	// lewat If-None-Match: setiap write mengubah versi dan membatalkan semua halaman sekaligus
+	if !s.checkCollectionETag(w, r) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(locations)
+	// total mengikuti filter, sehingga client tahu berapa halaman hasil filter yang tersedia. CountDocuments
+	// mahal pada koleksi besar, jadi hanya dihitung dengan withTotal=true; tanpanya client membaca halaman
+	// sampai halaman kosong atau sampai next_cursor tidak ada lagi.
+	withTotal := r.URL.Query().Get("withTotal") == "true"
+	var total int64
+	if withTotal {
+		if total, err = s.locations.Count(ctx, filter); err != nil {
+			writeListError(w, r, err)
+			return
+		}
+	}
+
+	sort, skip := listPageQuery(filter, after, direction, page, limit)
+	if !s.guardQueryCost(w, r, withoutDeleted(ctx, filter), sort, int64(limit), skip) {
+		return
+	}
+	locations, skipped, err := s.locations.List(withProjection(ctx, fields), filter, sort, int64(limit), skip)
+	if err != nil {
+		writeListError(w, r, err)
+		return
+	}
+	warns = append(warns, skipped...)
+	setWarningHeaders(w, warns)
+
+	nextCursor := nextListCursor(locations, skipped, limit)
+
+	// X-Total-Count juga dikirim untuk JSON, agar komponen pagination di UI bisa membacanya tanpa mengurai body
+	if withTotal {
+		w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+	// Format khusus library peta hanya berisi data halaman ini; total dan cursor dikirim lewat header
+	if format != formatJSON {
+		payload, contentType := formatLocations(format, locations)
+		w.Header().Add("Vary", "Accept")
+		if nextCursor != "" {
+			w.Header().Set("X-Next-Cursor", nextCursor)
+		}
+		w.Header().Set("Content-Type", contentType)
+		writeJSON(w, http.StatusOK, payload)
+		return
+	}
+
+	var data interface{} = locations
+	if fields != nil {
+		if data, err = fields.sparseList(locations); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Locations could not be encoded")
+			return
+		}
+	}
+	response := map[string]interface{}{
+		"data":  data,
+		"limit": limit,
+	}
+	if withTotal {
+		response["total"] = total
+	}
+	if after == primitive.NilObjectID {
+		response["page"] = page
+	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
 }
 
-// updateLocationHandler menangani request PUT untuk memperbarui data lokasi
-func updateLocationHandler(w http.ResponseWriter, r *http.Request) {
+// writeListError menulis error query daftar lokasi; pencarian teks sebelum text index selesai dibuat dijawab 503
+func writeListError(w http.ResponseWriter, r *http.Request, err error) {
+	if isTextIndexMissing(err) {
+		writeJSONError(w, http.StatusServiceUnavailable, "Text index is not available yet, try again later")
+		return
+	}
+	writeDBError(w, r, err)
+}
+
+// getLocationHandler menangani request GET untuk mengambil satu lokasi berdasarkan ID
+func (s *Server) getLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	id, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid location ID format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	var loc Location
-	if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	loc, err := s.locations.GetByID(withProjection(ctx, fields), id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Location with ID %s was not found", vars["id"]))
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
 		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"name":        loc.Name,
-			"description": loc.Description,
-			"location":    loc.Location,
-		},
+	etag := loc.ETag()
+	w.Header().Set("ETag", etag)
+	modified := loc.UpdatedAt
+	if modified.IsZero() {
+		modified = loc.CreatedAt
+	}
+	setLastModified(w, modified)
+	if etagMatches(r.Header.Get("If-None-Match"), etag, true) || notModifiedSince(r, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if fields != nil {
+		sparse, err := fields.sparse(loc)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Location could not be encoded")
+			return
+		}
+		writeResponse(w, r, http.StatusOK, sparse)
+		return
 	}
+	writeResponse(w, r, http.StatusOK, loc)
+}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+// updateLocationHandler menangani request PUT untuk memperbarui sebagian data lokasi (hanya field yang dikirim)
+// dan mengembalikan dokumen setelah diperbarui. If-Match wajib berisi ETag dari GET, agar dua client yang
+// mengedit lokasi yang sama tidak saling menimpa perubahan tanpa sadar.
+func (s *Server) updateLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, http.StatusPreconditionRequired, "precondition_required", "If-Match header is required, send the ETag returned by GET /locations/{id}")
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		http.Error(w, "Location not found", http.StatusNotFound)
+	body, err := readBody(w, r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	// Body di-decode ke map dulu untuk mengetahui field mana yang benar-benar dikirim
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// --- PERUBAHAN DI SINI ---
-	// Mengirimkan pesan sukses dalam format JSON yang terstruktur
-	w.WriteHeader(http.StatusOK)
-	response := map[string]string{
-		"status":  "success",
-		"message": fmt.Sprintf("Location with ID %s was successfully updated", vars["id"]),
+	existing, err := s.locations.GetByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !canModify(ctx, existing) {
+		writeNotOwner(w)
+		return
+	}
+	if !etagMatches(ifMatch, existing.ETag(), false) {
+		writePreconditionFailed(w, existing)
+		return
+	}
+
+	// Field yang dikirim ditimpakan ke dokumen lama, sehingga validasi berlaku pada hasil akhirnya
+	merged := existing
+	if err := decodeStrict(body, &merged); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	merged.Tags = normalizeTags(merged.Tags)
+	if errs := merged.validate(); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if err := validateBusinessRules(merged, fields); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+
+	update := locationUpdate(fields, merged, time.Now())
+	if update == nil {
+		writeJSONError(w, http.StatusBadRequest, "Request body must set at least one of name, description, category, tags, address, location or expires_at")
+		return
+	}
+
+	// Update hanya berlaku jika dokumen belum berubah sejak dibaca di atas; jika berubah di antaranya,
+	// lokasi yang masih ada berarti kalah balapan dengan write lain
+	updated, err := repo.Update(ctx, id, revisionFilter(existing), update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		current, getErr := s.locations.GetByID(ctx, id)
+		if getErr == nil {
+			writePreconditionFailed(w, current)
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+	s.notifyLocationChange(&existing, &updated)
+	s.recordAudit(r, newAuditEvent(auditUpdate, &existing, &updated))
+
+	w.Header().Set("ETag", updated.ETag())
+	writeResponse(w, r, http.StatusOK, updated)
 }
 
-// deleteLocationHandler menangani request DELETE untuk menghapus data lokasi
-func deleteLocationHandler(w http.ResponseWriter, r *http.Request) {
+// locationUpdate menyusun dokumen update $set/$unset hanya untuk field yang dikirim client (fields),
+// dengan nilai dari merged yang sudah divalidasi. Mengembalikan nil jika tidak ada field yang bisa diubah.
+func locationUpdate(fields map[string]json.RawMessage, merged Location, now time.Time) bson.M {
+	set, unset := bson.M{}, bson.M{}
+	if _, ok := fields["name"]; ok {
+		set["name"] = merged.Name
+		set["name_normalized"] = normalizeName(merged.Name)
+	}
+	if _, ok := fields["description"]; ok {
+		set["description"] = merged.Description
+	}
+	if _, ok := fields["category"]; ok {
+		set["category"] = merged.Category
+	}
+	if _, ok := fields["address"]; ok {
+		set["address"] = merged.Address
+	}
+	if _, ok := fields["location"]; ok {
+		set["location"] = merged.Location
+	}
+	// tags menggantikan seluruh daftar tag; array kosong atau null menghapus semuanya
+	if _, ok := fields["tags"]; ok {
+		if len(merged.Tags) == 0 {
+			unset["tags"] = ""
+		} else {
+			set["tags"] = merged.Tags
+		}
+	}
+	// expires_at: null menghapus TTL, field yang tidak dikirim membiarkan TTL lama
+	if _, ok := fields["expires_at"]; ok {
+		if merged.ExpiresAt == nil {
+			unset["expires_at"] = ""
+		} else {
+			set["expires_at"] = merged.ExpiresAt
+		}
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return nil
+	}
+	set["updated_at"] = now
+	update := bson.M{"$set": set, "$inc": bson.M{"revision": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// deleteLocationHandler menangani request DELETE dengan memindahkan lokasi ke trash (soft delete);
+// lokasi bisa dikembalikan lewat /restore atau dihapus permanen lewat /purge
+func (s *Server) deleteLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	id, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid location ID format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
 		return
 	}
-
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	repo, err := s.writeRepository(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.requireLocationOwner(w, r, id) {
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		http.Error(w, "Location not found", http.StatusNotFound)
+	deleted, err := repo.Delete(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
 		return
 	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+	s.recordAudit(r, newAuditEvent(auditDelete, &deleted, nil))
 
 	// --- PERUBAHAN DI SINI ---
 	// Mengganti 204 No Content menjadi 200 OK agar bisa mengirim pesan
-	w.WriteHeader(http.StatusOK)
 	response := map[string]string{
 		"status":  "success",
-		"message": fmt.Sprintf("Location with ID %s was successfully deleted", vars["id"]),
+		"message": fmt.Sprintf("Location with ID %s was moved to the trash", vars["id"]),
 	}
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
-// main adalah fungsi utama tempat aplikasi dimulai
+// newTLSConfig mengembalikan konfigurasi TLS minimal 1.2 dengan cipher suite yang aman (forward secrecy + AEAD)
+func newTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+}
+
+// registerRoutes mendaftarkan semua route API ke router yang diberikan, dipakai bersama oleh /v1 dan path lama
+func (s *Server) registerRoutes(r *mux.Router) {
+	if protectReadsEnabled() {
+		r.Use(readAuthMiddleware)
+	}
+	r.Use(tenantMiddleware)
+	r.Use(userMiddleware)
+	r.Use(includeExpiredMiddleware)
+	r.Use(cacheControlMiddleware)
+	r.HandleFunc("/admin/index-stats", requireAdmin(s.indexStatsHandler)).Methods("GET")
+	r.HandleFunc("/admin/sample-schema", requireAdmin(s.sampleSchemaHandler)).Methods("GET")
+	r.HandleFunc("/admin/geo-check", requireAdmin(s.geoCheckHandler)).Methods("GET")
+	r.HandleFunc("/admin/stats", requireAdmin(s.collectionStatsHandler)).Methods("GET")
+	r.HandleFunc("/admin/report", requireAdmin(s.adminReportHandler)).Methods("GET")
+	r.HandleFunc("/admin/merge-exact-duplicates", requireAdmin(s.mergeExactDuplicatesHandler)).Methods("POST")
+	r.HandleFunc("/admin/audit", requireAdmin(s.auditEventsHandler)).Methods("GET")
+
+	// Endpoint yang mengubah data butuh X-API-Key atau JWT jika API_KEY/JWT_SECRET diset; endpoint baca
+	// (termasuk POST yang hanya menghitung, seperti distance-matrix) tetap terbuka kecuali PROTECT_READS=true.
+	// POST yang menulis menerima Idempotency-Key agar retry dari client tidak membuat data ganda.
+	r.HandleFunc("/locations", requireAuth(s.idempotent(s.createLocationHandler))).Methods("POST")
+	r.HandleFunc("/locations", s.getLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/along-route", s.alongRouteHandler).Methods("POST")
+	r.HandleFunc("/locations/assign-category", requireAdmin(s.assignCategoryHandler)).Methods("POST")
+	r.HandleFunc("/locations/bearing", s.bearingHandler).Methods("GET")
+	r.HandleFunc("/locations/batch", requireAuth(s.idempotent(s.batchLocationsHandler))).Methods("POST")
+	r.HandleFunc("/locations/bulk", requireAuth(s.idempotent(s.bulkCreateHandler))).Methods("POST")
+	r.HandleFunc("/locations/bulk-coordinates", requireAdmin(s.bulkCoordinatesHandler)).Methods("POST")
+	r.HandleFunc("/locations/autocomplete", s.autocompleteHandler).Methods("GET")
+	r.HandleFunc("/locations/circle", s.circleLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/category-centroids", s.categoryCentroidsHandler).Methods("GET")
+	r.HandleFunc("/locations/check-name", s.checkNameHandler).Methods("GET")
+	r.HandleFunc("/locations/containing", s.containingLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/count", s.countLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/distance-matrix", s.distanceMatrixHandler).Methods("POST")
+	r.HandleFunc("/distance-matrix", s.distanceMatrixHandler).Methods("POST")
+	r.HandleFunc("/locations/export", s.exportLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/exact-duplicates", s.exactDuplicatesHandler).Methods("GET")
+	r.HandleFunc("/locations/duplicates", s.nearDuplicatesHandler).Methods("GET")
+	r.HandleFunc("/locations/region-counts", s.regionCountsHandler).Methods("POST")
+	r.HandleFunc("/locations/resolve", s.resolveLocationsHandler).Methods("POST")
+	r.HandleFunc("/locations/version", s.collectionVersionHandler).Methods("GET")
+	r.HandleFunc("/locations/search", s.searchLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/snap", s.snapLocationHandler).Methods("GET")
+	r.HandleFunc("/locations/most-central", s.mostCentralLocationHandler).Methods("GET")
+	r.HandleFunc("/locations/near", s.nearLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/near/categories", s.nearCategoriesHandler).Methods("GET")
+	r.HandleFunc("/locations/near/ranked", s.rankedNearHandler).Methods("GET")
+	r.HandleFunc("/locations/grid", s.nearestGridHandler).Methods("GET")
+	r.HandleFunc("/locations/import", requireAuth(s.idempotent(s.importLocationsHandler))).Methods("POST")
+	r.HandleFunc("/locations/intersects", s.intersectsLocationsHandler).Methods("POST")
+	r.HandleFunc("/locations/clusters", s.clustersHandler).Methods("GET")
+	r.HandleFunc("/locations/heatmap", s.heatmapHandler).Methods("GET")
+	r.HandleFunc("/locations/hotspots", s.hotspotsHandler).Methods("GET")
+	r.HandleFunc("/locations/latest-by-category", s.latestByCategoryHandler).Methods("GET")
+	r.HandleFunc("/locations/within", s.withinLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/within", s.withinPolygonHandler).Methods("POST")
+	r.HandleFunc("/locations/stats", s.locationStatsHandler).Methods("GET")
+	r.HandleFunc("/locations/stats/daily", s.dailyStatsHandler).Methods("GET")
+	r.HandleFunc("/locations/stream", s.streamLocationsHandler).Methods("GET")
+	r.HandleFunc("/locations/tiles/{z}/{x}/{y}/count", s.tileCountHandler).Methods("GET")
+	r.HandleFunc("/locations/tiles/{z}/{x}/{y:[0-9]+}.geojson", s.tileGeoJSONHandler).Methods("GET")
+	r.HandleFunc("/locations/trash", requireAuth(s.trashLocationsHandler)).Methods("GET")
+	r.HandleFunc("/locations/by-external-id/{externalId}", requireAuth(s.upsertByExternalIDHandler)).Methods("PUT")
+	r.HandleFunc("/locations/{id}", s.getLocationHandler).Methods("GET")
+	// PUT sudah bersifat partial update; PATCH disediakan untuk client yang mengikuti semantik HTTP tersebut
+	r.HandleFunc("/locations/{id}", requireAuth(s.updateLocationHandler)).Methods("PUT", "PATCH")
+	r.HandleFunc("/locations/{id}/with-neighbors", s.locationWithNeighborsHandler).Methods("GET")
+	r.HandleFunc("/locations/{id}/distance", s.locationDistanceHandler).Methods("GET")
+	// Riwayat memuat isi lokasi yang sudah dihapus, sehingga dijaga seperti trash
+	r.HandleFunc("/locations/{id}/history", requireAuth(s.locationHistoryHandler)).Methods("GET")
+	r.HandleFunc("/locations/{id}", requireAuth(s.deleteLocationHandler)).Methods("DELETE")
+	r.HandleFunc("/locations/{id}/restore", requireAuth(s.idempotent(s.restoreLocationHandler))).Methods("POST")
+	r.HandleFunc("/locations/{id}/purge", requireAuth(s.purgeLocationHandler)).Methods("DELETE")
+	r.HandleFunc("/locations/{id}/photos", requireAuth(s.uploadPhotoHandler)).Methods("POST")
+	r.HandleFunc("/locations/{id}/photos/{photoId}", s.getPhotoHandler).Methods("GET")
+	r.HandleFunc("/locations/{id}/photos/{photoId}", requireAuth(s.deletePhotoHandler)).Methods("DELETE")
+	r.HandleFunc("/locations/{id}/merge/{otherId}", requireAuth(s.mergeLocationsHandler)).Methods("POST")
+	// Favorit dan /me/* per pengguna, sehingga butuh bearer token JWT dengan klaim sub
+	r.HandleFunc("/locations/{id}/favorite", requireAuth(s.favoriteLocationHandler)).Methods("POST")
+	r.HandleFunc("/locations/{id}/favorite", requireAuth(s.unfavoriteLocationHandler)).Methods("DELETE")
+	r.HandleFunc("/me/locations", requireAuth(s.myLocationsHandler)).Methods("GET")
+	r.HandleFunc("/me/favorites", requireAuth(s.myFavoritesHandler)).Methods("GET")
+
+	r.HandleFunc("/geocode", s.geocodeHandler).Methods("GET")
+	r.HandleFunc("/reverse", s.reverseGeocodeHandler).Methods("GET")
+
+	// Track perangkat menunjukkan posisi kendaraan dari waktu ke waktu, sehingga pembacaannya juga dijaga
+	r.HandleFunc("/tracks/{deviceId}/points", requireAuth(s.idempotent(s.recordTrackPointsHandler))).Methods("POST")
+	r.HandleFunc("/tracks/{deviceId}", requireAuth(s.trackHandler)).Methods("GET")
+
+	r.HandleFunc("/geofences", s.listGeofencesHandler).Methods("GET")
+	r.HandleFunc("/geofences", requireAuth(s.idempotent(s.createGeofenceHandler))).Methods("POST")
+	r.HandleFunc("/geofences/{id}", s.getGeofenceHandler).Methods("GET")
+	r.HandleFunc("/geofences/{id}", requireAuth(s.updateGeofenceHandler)).Methods("PUT")
+	r.HandleFunc("/geofences/{id}", requireAuth(s.deleteGeofenceHandler)).Methods("DELETE")
+}
+
+// main adalah fungsi utama tempat aplikasi dimulai. Argumen pertama memilih subcommand (lihat cliCommands);
+// tanpa argumen server dijalankan seperti biasa, sehingga CMD di Dockerfile tidak perlu diubah.
 func main() {
-	initDB()
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := cliCommands[name]
+	switch {
+	case name == "help" || (name == "serve" && len(args) > 0 && isHelpFlag(args[0])):
+		printUsage(os.Stdout)
+		return
+	case name == "serve" && len(args) > 0:
+		fmt.Fprintf(os.Stderr, "serve does not take arguments, got %q\n", args)
+		os.Exit(2)
+	case name != "serve" && !ok:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
+	// .env dibaca paling awal agar konfigurasi logger dan tracing di dalamnya ikut berlaku
+	dotEnvErr := godotenv.Load()
+	cfg, problems := loadConfig()
+	logOut := os.Stdout
+	if name != "serve" {
+		logOut = os.Stderr
+	}
+	initLogger(cfg, logOut)
+	if dotEnvErr != nil {
+		slog.Info("no .env file found, reading environment variables from system")
+	}
+	if len(problems) > 0 {
+		fatal("invalid configuration", "problems", problems)
+	}
+	config = cfg
+	if name != "serve" {
+		os.Exit(runCLI(name, cmd, args))
+	}
+	serve()
+}
+
+// serve menjalankan server HTTP (dan gRPC bila GRPC_PORT diset) hingga menerima SIGTERM
+func serve() {
+	config.logConfig()
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracingEnabled() {
+		var err error
+		shutdownTracing, err = initTracing(context.Background())
+		if err != nil {
+			fatal("failed to initialise tracing", "error", err)
+		}
+	}
+
+	s := NewServer(initDB(config))
+	if s.geocoder = newGeocoder(config); s.geocoder != nil {
+		slog.Info("geocoding enabled", "provider", config.Geocoder, "auto_address", config.AutoGeocodeAddress)
+	}
+	if config.PhotoStorage == "s3" {
+		slog.Info("photos stored in S3", "endpoint", config.S3Endpoint, "bucket", config.S3Bucket)
+		s.photos = newS3PhotoStore(config)
+	}
+	// Koneksi ditunggu di background agar /healthz langsung hidup walaupun MongoDB belum siap
+	bgCtx, stopBackground := context.WithCancel(s.ctx)
+	defer stopBackground()
+	go s.waitForMongo(bgCtx)
+	if config.ReadCacheTTL > 0 {
+		// Cache baru dipakai setelah change stream pembatalnya terhubung
+		cache := newReadCache(config.ReadCacheTTL, config.ReadCacheMaxEntries)
+		s.locations = newCachingLocationRepository(s.locations, cache)
+		go s.runReadCacheInvalidator(bgCtx, cache)
+	}
+	if len(config.WebhookURLs) > 0 {
+		slog.Info("geofence webhooks enabled", "urls", len(config.WebhookURLs), "signed", config.WebhookSecret != "")
+		s.webhooks = newWebhookNotifier(config.WebhookURLs, config.WebhookSecret)
+		go s.runGeofenceWorker(bgCtx)
+	}
+	loadCoordPrecision()
+	loadCoordOutOfRange()
+	loadLargeResponseBytes()
+	loadCompression()
+	loadCacheMaxAge()
+	loadMaxDocsExamined()
+	loadMaxBodyBytes()
+	loadMaxPhotoBytes()
 
 	r := mux.NewRouter()
+	if tracingEnabled() {
+		// Span per request, melanjutkan trace context dari header traceparent jika ada
+		r.Use(otelmux.Middleware(tracingServiceName))
+	}
+	r.Use(requestIDMiddleware)
+	r.Use(requestLoggingMiddleware)
+	r.Use(metricsMiddleware)
+	r.Use(corsMiddleware(config.CORS))
+	if limit, burst := rateLimitConfig(); limit > 0 {
+		authLimit, authBurst := authRateLimitConfig(limit, burst)
+		slog.Info("rate limiting clients", "requests_per_second", float64(limit), "burst", burst,
+			"auth_requests_per_second", float64(authLimit), "auth_burst", authBurst)
+		s.rateLimiter = newRequestRateLimiter(limit, burst, authLimit, authBurst, trustProxyHeaders())
+		r.Use(rateLimitMiddleware(s.rateLimiter))
+	}
+	r.Use(requestTimeoutMiddleware(config.RequestTimeout, maxRequestTimeout))
+	// Kompresi dipasang setelah logging dan sebelum debug body, sehingga body yang dicatat tetap terbaca
+	r.Use(compressionMiddleware)
+	r.Use(bodyLimitMiddleware)
 
-	r.HandleFunc("/locations", createLocationHandler).Methods("POST")
-	r.HandleFunc("/locations", getLocationsHandler).Methods("GET")
-	r.HandleFunc("/locations/{id}", updateLocationHandler).Methods("PUT")
-	r.HandleFunc("/locations/{id}", deleteLocationHandler).Methods("DELETE")
+	if debugBodiesEnabled() {
+		slog.Warn("DEBUG_LOG_BODIES is enabled, request and response bodies will be written to the logs. Do not use this in production!")
+		r.Use(debugBodyMiddleware(debugBodyMax()))
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	if demoEnabled() {
+		slog.Info("serving the map demo page at /")
+		r.Handle("/", demoHandler()).Methods("GET")
 	}
 
-	fmt.Printf("Server starting on port %s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	// Probe platform, info build, dan dokumentasi tidak diberi versi karena bukan bagian dari API lokasi
+	r.HandleFunc("/healthz", healthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", s.readyzHandler).Methods("GET")
+	r.HandleFunc("/version", versionHandler).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	r.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+	r.HandleFunc("/docs", docsHandler).Methods("GET")
+
+	// Semua route tersedia di bawah /v1, sehingga /v2 nantinya bisa ditambahkan tanpa merusak client lama
+	s.registerRoutes(r.PathPrefix("/v1").Subrouter())
+
+	// Path tanpa versi tetap dilayani selama masa transisi, dengan header Deprecation
+	legacy := r.NewRoute().Subrouter()
+	legacy.Use(deprecationMiddleware)
+	s.registerRoutes(legacy)
+
+	// Middleware mux hanya berjalan untuk route yang cocok, sehingga preflight OPTIONS butuh route sendiri
+	// (dijawab oleh corsMiddleware) agar tidak berakhir sebagai 405
+	r.Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := &http.Server{
+		Addr:      ":" + config.Port,
+		Handler:   r,
+		TLSConfig: newTLSConfig(),
+	}
+
+	go func() {
+		// TLS langsung hanya dipakai jika sertifikat diset; di belakang proxy Railway cukup HTTP biasa
+		var err error
+		if config.TLSCertFile != "" {
+			slog.Info("server starting", "port", config.Port, "tls", true)
+			err = srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			slog.Info("server starting", "port", config.Port, "tls", false)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("server failed", "error", err)
+		}
+	}()
+
+	// Server gRPC berjalan di port kedua dengan Server yang sama, sehingga keduanya berbagi koneksi dan aturan
+	var grpcServer *grpc.Server
+	if config.GRPCPort != "" {
+		var err error
+		if grpcServer, err = s.newGRPCServer(); err != nil {
+			fatal("failed to create gRPC server", "error", err)
+		}
+		lis, err := net.Listen("tcp", ":"+config.GRPCPort)
+		if err != nil {
+			fatal("failed to listen for gRPC", "port", config.GRPCPort, "error", err)
+		}
+		go func() {
+			slog.Info("grpc server starting", "port", config.GRPCPort, "tls", config.TLSCertFile != "")
+			if err := grpcServer.Serve(lis); err != nil {
+				fatal("grpc server failed", "error", err)
+			}
+		}()
+	}
+
+	// Railway mengirim SIGTERM saat redeploy; request yang sedang berjalan diberi waktu untuk selesai
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	sig := <-stop
+	slog.Info("shutting down", "signal", sig.String())
+
+	stopBackground()
+	shutdownCtx, cancel := context.WithTimeout(s.ctx, shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server shutdown did not complete", "error", err)
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	if err := s.client.Disconnect(shutdownCtx); err != nil {
+		slog.Error("MongoDB disconnect failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("tracing shutdown failed", "error", err)
+	}
+	slog.Info("server stopped")
 }
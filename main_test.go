@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLocationUpdate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expires := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	// Dokumen lama dibuat ulang per kasus karena decode ke salinannya ikut menulis pointer ExpiresAt
+	existing := func() Location {
+		expiresAt := now.Add(time.Hour)
+		return Location{Name: "Monas", Category: "landmark", Tags: []string{"jakarta"}, ExpiresAt: &expiresAt}
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want bson.M
+	}{
+		{name: "empty object", body: `{}`, want: nil},
+		{
+			name: "omitted fields are left unchanged",
+			body: `{"name":"Monumen Nasional"}`,
+			want: bson.M{
+				"$set": bson.M{"name": "Monumen Nasional", "name_normalized": "monumen nasional", "updated_at": now},
+				"$inc": bson.M{"revision": 1},
+			},
+		},
+		{
+			name: "explicit null removes tags and expiry",
+			body: `{"tags":null,"expires_at":null}`,
+			want: bson.M{
+				"$set":   bson.M{"updated_at": now},
+				"$unset": bson.M{"tags": "", "expires_at": ""},
+				"$inc":   bson.M{"revision": 1},
+			},
+		},
+		{
+			name: "empty tags removes tags",
+			body: `{"tags":[]}`,
+			want: bson.M{
+				"$set":   bson.M{"updated_at": now},
+				"$unset": bson.M{"tags": ""},
+				"$inc":   bson.M{"revision": 1},
+			},
+		},
+		{
+			name: "null on a string field keeps the old value",
+			body: `{"category":null,"expires_at":"2024-06-01T00:00:00Z"}`,
+			want: bson.M{
+				"$set": bson.M{"category": "landmark", "expires_at": &expires, "updated_at": now},
+				"$inc": bson.M{"revision": 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sama seperti updateLocationHandler: field yang dikirim dari map, nilainya dari dokumen gabungan
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.body), &fields); err != nil {
+				t.Fatal(err)
+			}
+			merged := existing()
+			if err := decodeStrict([]byte(tt.body), &merged); err != nil {
+				t.Fatal(err)
+			}
+			merged.Tags = normalizeTags(merged.Tags)
+
+			if got := locationUpdate(fields, merged, now); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("locationUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocationUpdateEmptyBody(t *testing.T) {
+	if got := locationUpdate(nil, Location{Name: "Monas"}, time.Now()); got != nil {
+		t.Fatalf("locationUpdate(nil) = %v, want nil", got)
+	}
+}
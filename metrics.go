@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route template and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongodb_command_duration_seconds",
+		Help:    "MongoDB command latency by command name and outcome.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"command", "outcome"})
+	mongoPoolOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections_open",
+		Help: "Open connections in the MongoDB connection pool.",
+	})
+	mongoPoolInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections_in_use",
+		Help: "MongoDB connections currently checked out by operations.",
+	})
+	mongoPoolCheckoutFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mongodb_pool_checkout_failures_total",
+		Help: "Failed attempts to check a connection out of the MongoDB pool.",
+	})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "geofence_webhook_deliveries_total",
+		Help: "Geofence webhook deliveries by outcome (delivered or failed after all retries).",
+	}, []string{"outcome"})
+
+	readCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "read_cache_lookups_total",
+		Help: "Read cache lookups by result (hit, miss, or bypass while the invalidating change stream is down).",
+	}, []string{"result"})
+
+	auditFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "audit_events_failed_total",
+		Help: "Audit events that could not be saved after a successful write.",
+	})
+)
+
+// routeLabel mengembalikan template path route yang cocok (misalnya /v1/locations/{id}) agar label
+// metrik tidak meledak oleh ID di path; request yang tidak cocok dengan route apa pun digabung jadi satu label
+func routeLabel(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// metricsMiddleware mencatat jumlah dan durasi request per route, method, dan status code
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		route := routeLabel(r)
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// mongoCommandMonitor mencatat latensi setiap command MongoDB lalu meneruskan event ke next (boleh nil),
+// sehingga bisa dipasang bersama monitor tracing
+func mongoCommandMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			if next != nil && next.Started != nil {
+				next.Started(ctx, evt)
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			mongoCommandDuration.WithLabelValues(evt.CommandName, "success").Observe(evt.Duration.Seconds())
+			if next != nil && next.Succeeded != nil {
+				next.Succeeded(ctx, evt)
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			mongoCommandDuration.WithLabelValues(evt.CommandName, "failure").Observe(evt.Duration.Seconds())
+			if next != nil && next.Failed != nil {
+				next.Failed(ctx, evt)
+			}
+		},
+	}
+}
+
+// mongoPoolMonitor menurunkan statistik connection pool dari event pool, karena driver tidak menyediakannya langsung
+func mongoPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			switch evt.Type {
+			case event.ConnectionCreated:
+				mongoPoolOpen.Inc()
+			case event.ConnectionClosed:
+				mongoPoolOpen.Dec()
+			case event.GetSucceeded:
+				mongoPoolInUse.Inc()
+			case event.ConnectionReturned:
+				mongoPoolInUse.Dec()
+			case event.GetFailed:
+				mongoPoolCheckoutFailures.Inc()
+			}
+		},
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// legacySunset adalah tanggal (HTTP-date) saat path tanpa versi akan dihapus
+const legacySunset = "Thu, 31 Dec 2026 23:59:59 GMT"
+
+// defaultDebugBodyMax adalah panjang maksimum body (byte) yang dicatat oleh debug logging
+const defaultDebugBodyMax = 2048
+
+// secretFieldPattern mencocokkan field JSON yang nilainya tidak boleh masuk ke log
+var secretFieldPattern = regexp.MustCompile(`(?i)("(?:password|secret|token|api_?key|authorization)"\s*:\s*)"[^"]*"`)
+
+// deprecationMiddleware menandai response dari path tanpa prefix versi sebagai deprecated
+func deprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", legacySunset)
+		w.Header().Set("Link", "</v1"+r.URL.Path+">; rel=\"successor-version\"")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsAllowedMethods adalah method default yang boleh dipakai browser dari origin lain
+const corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE"
+
+// corsAllowedHeaders adalah header request default yang boleh dikirim browser dari origin lain
+const corsAllowedHeaders = "Accept, Authorization, Content-Type, Idempotency-Key, If-Match, If-Modified-Since, If-None-Match, Last-Event-ID, X-Admin-Key, X-API-Key, X-Request-ID, X-Request-Timeout, X-Tenant-ID, X-Write-Concern"
+
+// corsExposedHeaders adalah header response yang boleh dibaca JavaScript di origin lain
+const corsExposedHeaders = "Warning, Deprecation, Sunset, Link, ETag, Retry-After, X-Total-Count, X-Next-Cursor, X-Response-Bytes, X-Cache, X-Docs-Examined, X-Request-ID, Idempotent-Replayed, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset"
+
+// corsConfig adalah pengaturan CORS; Methods dan Headers sudah dalam bentuk nilai header
+type corsConfig struct {
+	Origins []string
+	Methods string
+	Headers string
+}
+
+// envList membaca variabel environment berisi daftar dipisah koma, membuang spasi dan item kosong
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadCORSConfig membaca ALLOWED_ORIGINS (kosong berarti semua origin, "*"), CORS_ALLOWED_METHODS, dan
+// CORS_ALLOWED_HEADERS dari environment; method dan header yang tidak diset memakai default di atas
+func loadCORSConfig() corsConfig {
+	cfg := corsConfig{Origins: envList("ALLOWED_ORIGINS"), Methods: corsAllowedMethods, Headers: corsAllowedHeaders}
+	if len(cfg.Origins) == 0 {
+		cfg.Origins = []string{"*"}
+	}
+	if methods := envList("CORS_ALLOWED_METHODS"); len(methods) > 0 {
+		for i, m := range methods {
+			methods[i] = strings.ToUpper(m)
+		}
+		cfg.Methods = strings.Join(methods, ", ")
+	}
+	if headers := envList("CORS_ALLOWED_HEADERS"); len(headers) > 0 {
+		cfg.Headers = strings.Join(headers, ", ")
+	}
+	return cfg
+}
+
+// corsMiddleware menambahkan header CORS agar API bisa dipanggil dari frontend di origin lain, dan menjawab
+// preflight OPTIONS dengan 204. Jika origins berisi daftar tertentu, hanya origin yang cocok yang dipantulkan.
+func corsMiddleware(cfg corsConfig) mux.MiddlewareFunc {
+	allowAll := len(cfg.Origins) == 1 && cfg.Origins[0] == "*"
+	allowed := make(map[string]bool, len(cfg.Origins))
+	for _, o := range cfg.Origins {
+		allowed[o] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if allowAll {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Add("Vary", "Origin")
+				if allowed[origin] {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+			}
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", cfg.Methods)
+				w.Header().Set("Access-Control-Allow-Headers", cfg.Headers)
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestTimeoutMiddleware memberi setiap request context dengan batas waktu, sehingga operasi database
+// yang menggantung tidak menahan goroutine selamanya. Client boleh meminta batas sendiri lewat header
+// X-Request-Timeout (misalnya "3s"), dipangkas ke maxTimeout. Long-poll dan stream SSE dikecualikan karena memang
+// dibiarkan terbuka lama, begitu juga export dan download foto yang durasinya sebanding dengan ukuran data.
+func requestTimeoutMiddleware(timeout, maxTimeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("waitFor") == "changes" || strings.HasSuffix(r.URL.Path, "/locations/export") ||
+				strings.HasSuffix(r.URL.Path, "/locations/stream") || isPhotoDownload(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timeout := timeout
+			if raw := r.Header.Get("X-Request-Timeout"); raw != "" {
+				d, err := time.ParseDuration(raw)
+				if err != nil || d <= 0 {
+					writeJSONError(w, http.StatusBadRequest, "X-Request-Timeout must be a positive duration such as 3s")
+					return
+				}
+				if d > maxTimeout {
+					setWarningHeaders(w, []string{fmt.Sprintf("X-Request-Timeout was reduced from %s to the server maximum of %s", d, maxTimeout)})
+					d = maxTimeout
+				}
+				timeout = d
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// statusRecorder membungkus ResponseWriter untuk mencatat status code yang dikirim handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap membuat http.ResponseController bisa mencapai ResponseWriter asli, misalnya untuk Flush
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// requestLoggingMiddleware mencatat method, path, status code, durasi, dan alamat client untuk setiap request
+func requestLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		// Default 200: handler yang langsung memanggil Write tanpa WriteHeader berarti 200
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		requestLogger(r).Info("request", "status", rec.status, "latency_ms", time.Since(start).Milliseconds(), "remote_addr", r.RemoteAddr)
+	})
+}
+
+// bodyLogWriter membungkus ResponseWriter untuk menyalin body response hingga batas tertentu
+type bodyLogWriter struct {
+	http.ResponseWriter
+	status int
+	limit  int
+	body   bytes.Buffer
+}
+
+func (w *bodyLogWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	if remaining := w.limit - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			w.body.Write(b[:remaining])
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// debugBodiesEnabled mengecek apakah DEBUG_LOG_BODIES diaktifkan
+func debugBodiesEnabled() bool {
+	return os.Getenv("DEBUG_LOG_BODIES") == "true"
+}
+
+// debugBodyMax membaca DEBUG_LOG_BODIES_MAX, memakai nilai default jika kosong atau tidak valid
+func debugBodyMax() int {
+	if n, err := strconv.Atoi(os.Getenv("DEBUG_LOG_BODIES_MAX")); err == nil && n > 0 {
+		return n
+	}
+	return defaultDebugBodyMax
+}
+
+// redactBody memotong body ke panjang maksimum dan menyamarkan field rahasia
+func redactBody(body []byte, limit int) string {
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+	s := secretFieldPattern.ReplaceAllString(string(body), `$1"[REDACTED]"`)
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+// debugBodyMiddleware mencatat body request dan response untuk debugging (hanya jika DEBUG_LOG_BODIES=true)
+func debugBodyMiddleware(limit int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Body dibaca ke buffer lalu dipasang kembali agar handler tetap bisa membacanya
+			reqBody, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeBodyError(w, err)
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+			bw := &bodyLogWriter{ResponseWriter: w, status: http.StatusOK, limit: limit + 1}
+			next.ServeHTTP(bw, r)
+
+			requestLogger(r).Info("request body", "body", redactBody(reqBody, limit))
+			requestLogger(r).Info("response body", "status", bw.status, "body", redactBody(bw.body.Bytes(), limit))
+		})
+	}
+}
+
+// requireAdmin membatasi handler hanya untuk request dengan header X-Admin-Key yang cocok dengan ADMIN_API_KEY
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminKey := config.AdminAPIKey
+		// Tanpa ADMIN_API_KEY, endpoint admin ditutup sepenuhnya
+		if adminKey == "" {
+			writeJSONError(w, http.StatusForbidden, "Admin endpoints are disabled (ADMIN_API_KEY is not set)")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(adminKey)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid or missing X-Admin-Key header")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// jwtSigningMethods adalah algoritma JWT yang diterima; hanya HMAC agar token tidak bisa lolos dengan "none"
+// atau dengan kunci publik yang dipakai sebagai secret
+var jwtSigningMethods = []string{"HS256", "HS384", "HS512"}
+
+// bearerToken mengambil token dari header Authorization: Bearer <token>
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// validateJWT memeriksa tanda tangan HMAC dan klaim waktu (exp, nbf, iat) token terhadap secret,
+// lalu mengembalikan klaim sub (boleh kosong)
+func validateJWT(token, secret string) (string, error) {
+	claims, err := parseJWTClaims(token, secret)
+	if err != nil {
+		return "", err
+	}
+	sub, _ := claims.GetSubject()
+	return sub, nil
+}
+
+// parseJWTClaims memvalidasi token seperti validateJWT dan mengembalikan seluruh klaimnya
+func parseJWTClaims(token, secret string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods(jwtSigningMethods))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validAdminKey mengecek apakah header X-Admin-Key cocok dengan ADMIN_API_KEY
+func validAdminKey(r *http.Request) bool {
+	key := r.Header.Get("X-Admin-Key")
+	return key != "" && config.AdminAPIKey != "" &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(config.AdminAPIKey)) == 1
+}
+
+// requireAuth membatasi handler hanya untuk request dengan header X-API-Key yang cocok dengan API_KEY atau
+// salah satu key di TENANT_API_KEYS, atau bearer token JWT yang ditandatangani dengan JWT_SECRET. Semuanya
+// boleh diset sekaligus. Tanpa satu pun semua request diteruskan, agar pengembangan lokal tidak perlu key.
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := authenticate(r); err != nil {
+			if err.missing {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="locations"`)
+			}
+			writeJSONError(w, http.StatusUnauthorized, err.msg)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authError adalah alasan authenticate menolak request; missing berarti request tidak membawa credential
+type authError struct {
+	msg     string
+	missing bool
+}
+
+// authenticate memeriksa credential request dengan aturan requireAuth tanpa menulis response, sehingga
+// bisa dipakai juga oleh server gRPC. nil berarti request boleh diteruskan.
+func authenticate(r *http.Request) *authError {
+	apiKey, jwtSecret := config.APIKey, config.JWTSecret
+	if apiKey == "" && jwtSecret == "" && !multiTenantEnabled() {
+		return nil
+	}
+	if _, ok := tenantForKey(r.Header.Get("X-API-Key")); ok {
+		return nil
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" && apiKey != "" {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			return &authError{msg: "Invalid X-API-Key header"}
+		}
+		return nil
+	}
+	if token, ok := bearerToken(r); ok && jwtSecret != "" {
+		if _, err := validateJWT(token, jwtSecret); err != nil {
+			return &authError{msg: fmt.Sprintf("Invalid bearer token: %v", err)}
+		}
+		return nil
+	}
+
+	switch {
+	case apiKey != "" && jwtSecret != "":
+		return &authError{msg: "Missing X-API-Key header or bearer token", missing: true}
+	case apiKey != "" || jwtSecret == "":
+		return &authError{msg: "Invalid or missing X-API-Key header", missing: true}
+	default:
+		return &authError{msg: "Missing bearer token", missing: true}
+	}
+}
+
+// protectReadsEnabled aktif jika PROTECT_READS=true, sehingga endpoint baca juga membutuhkan API key atau JWT
+func protectReadsEnabled() bool {
+	return os.Getenv("PROTECT_READS") == "true"
+}
+
+// readAuthMiddleware menerapkan requireAuth pada semua route lokasi, termasuk endpoint baca. Endpoint admin
+// dilewati karena sudah memakai X-Admin-Key; endpoint write tetap dibungkus requireAuth sendiri sehingga
+// pengecekannya sama saja.
+func readAuthMiddleware(next http.Handler) http.Handler {
+	protected := requireAuth(next.ServeHTTP)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		protected(w, r)
+	})
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultDuplicateRadius adalah radius default (meter) di mana dua lokasi dengan nama mirip dianggap duplikat
+	defaultDuplicateRadius = 25
+	// maxDuplicateRadius membatasi ?radius= agar setiap $geoNear per kandidat tetap murah
+	maxDuplicateRadius = 1000
+	// defaultDuplicateSimilarity adalah kemiripan nama minimum (0..1) agar dua lokasi dianggap duplikat
+	defaultDuplicateSimilarity = 0.8
+	// maxDuplicateCandidates adalah jumlah lokasi terbaru yang diperiksa per request, masing-masing satu $geoNear
+	maxDuplicateCandidates = 200
+	// duplicateNeighborLimit adalah jumlah tetangga terdekat yang dibandingkan untuk setiap kandidat
+	duplicateNeighborLimit = 10
+	// defaultDuplicatePairs dan maxDuplicatePairs mengatur jumlah pasangan yang dikembalikan lewat ?limit=
+	defaultDuplicatePairs = 20
+	maxDuplicatePairs     = 100
+)
+
+// NearDuplicate adalah dua lokasi berdekatan yang namanya hampir sama. A adalah yang lebih dulu dibuat,
+// sehingga biasanya A yang dipertahankan saat merge.
+type NearDuplicate struct {
+	A              Location `json:"a"`
+	B              Location `json:"b"`
+	DistanceMeters float64  `json:"distanceMeters"`
+	Similarity     float64  `json:"similarity"`
+}
+
+// nameSimilarity mengembalikan kemiripan dua nama dari 0 sampai 1: 1 dikurangi jarak Levenshtein dibagi
+// panjang nama terpanjang, dihitung per rune setelah normalizeName
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeName(a)), []rune(normalizeName(b))
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein menghitung jumlah minimum sisip, hapus, atau ganti satu rune untuk mengubah a menjadi b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// parseDuplicateParams membaca ?radius= (meter), ?similarity= (0..1), dan ?limit= (jumlah pasangan)
+func parseDuplicateParams(r *http.Request, warns *[]string) (radius, similarity float64, limit int, err error) {
+	q := r.URL.Query()
+	radius, similarity, limit = defaultDuplicateRadius, defaultDuplicateSimilarity, defaultDuplicatePairs
+	if raw := q.Get("radius"); raw != "" {
+		radius, err = strconv.ParseFloat(raw, 64)
+		if err != nil || radius <= 0 {
+			return 0, 0, 0, errors.New("radius must be a positive number")
+		}
+		if radius > maxDuplicateRadius {
+			addLimitWarning(warns, "radius was reduced from %g to the server maximum of %d", radius, maxDuplicateRadius)
+			radius = maxDuplicateRadius
+		}
+	}
+	if raw := q.Get("similarity"); raw != "" {
+		similarity, err = strconv.ParseFloat(raw, 64)
+		if err != nil || similarity < 0 || similarity > 1 {
+			return 0, 0, 0, errors.New("similarity must be a number between 0 and 1")
+		}
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return 0, 0, 0, errors.New("limit must be a positive integer")
+		}
+		if limit > maxDuplicatePairs {
+			addLimitWarning(warns, "limit was reduced from %d to the server maximum of %d", limit, maxDuplicatePairs)
+			limit = maxDuplicatePairs
+		}
+	}
+	return radius, similarity, limit, nil
+}
+
+// nearDuplicatesHandler mencari pasangan lokasi dalam ?radius= meter yang namanya hampir sama. Yang diperiksa
+// hanya maxDuplicateCandidates lokasi terbaru (tempat duplikat biasanya baru masuk), masing-masing dibandingkan
+// dengan tetangga terdekatnya lewat $geoNear. Pasangan diurutkan dari yang paling mirip, lalu yang paling dekat.
+func (s *Server) nearDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var warns []string
+	radius, similarity, limit, err := parseDuplicateParams(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(maxDuplicateCandidates + 1)
+	cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, nil), opts)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+	candidates, skipped, err := decodeLocations(ctx, cursor)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+		addLimitWarning(&warns, "only the %d most recently created locations were checked for duplicates", maxDuplicateCandidates)
+	}
+
+	seen := map[[2]primitive.ObjectID]bool{}
+	pairs := []NearDuplicate{}
+	for _, loc := range candidates {
+		pos, ok := loc.Location.anchorPosition()
+		if !ok {
+			continue
+		}
+		neighbors, err := s.findNearLocations(ctx, pos[0], pos[1], radius, duplicateNeighborLimit, bson.M{"_id": bson.M{"$ne": loc.ID}})
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		for _, n := range neighbors {
+			score := nameSimilarity(loc.Name, n.Name)
+			if score < similarity {
+				continue
+			}
+			a, b := loc, n.Location
+			if b.CreatedAt.Before(a.CreatedAt) || (b.CreatedAt.Equal(a.CreatedAt) && b.ID.Hex() < a.ID.Hex()) {
+				a, b = b, a
+			}
+			key := [2]primitive.ObjectID{a.ID, b.ID}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			pairs = append(pairs, NearDuplicate{A: a, B: b, DistanceMeters: math.Round(n.Distance*100) / 100, Similarity: math.Round(score*1000) / 1000})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		return pairs[i].DistanceMeters < pairs[j].DistanceMeters
+	})
+	total := len(pairs)
+	if len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	setWarningHeaders(w, append(skipped, warns...))
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"data":       pairs,
+		"radius":     radius,
+		"similarity": similarity,
+		"total":      total,
+	})
+}
+
+// mergeInto melengkapi target dengan data duplikat: description, category, dan address yang masih kosong
+// diambil dari duplikat, dan tag keduanya digabung. Mengembalikan field yang berubah dalam format locationUpdate.
+func mergeInto(target *Location, dup Location) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if target.Description == "" && dup.Description != "" {
+		target.Description = dup.Description
+		fields["description"] = nil
+	}
+	if target.Category == "" && dup.Category != "" {
+		target.Category = dup.Category
+		fields["category"] = nil
+	}
+	if target.Address == "" && dup.Address != "" {
+		target.Address = dup.Address
+		fields["address"] = nil
+	}
+	if tags := normalizeTags(append(append([]string{}, target.Tags...), dup.Tags...)); len(tags) != len(target.Tags) {
+		target.Tags = tags
+		fields["tags"] = nil
+	}
+	return fields
+}
+
+// errMergeTargetChanged menandai target merge yang berubah atau terhapus sejak dibaca
+var errMergeTargetChanged = errors.New("merge target changed since it was read")
+
+// mergeLocationsHandler menggabungkan lokasi {otherId} ke lokasi {id}: field kosong di {id} dilengkapi dari
+// {otherId}, tag digabung, lalu {otherId} dipindahkan ke trash sehingga masih bisa di-restore bila salah merge
+func (s *Server) mergeLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	ids, err := parseObjectIDs([]string{vars["id"], vars["otherId"]})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	id, otherID := ids[0], ids[1]
+	if id == otherID {
+		writeJSONError(w, http.StatusBadRequest, "A location cannot be merged into itself")
+		return
+	}
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	byID, err := s.findLocationsByIDs(ctx, ids)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	existing, ok := byID[id]
+	dup, dupOK := byID[otherID]
+	if !ok || !dupOK {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	// Merge mengubah target dan menghapus duplikatnya, sehingga keduanya harus boleh diubah pengguna ini
+	if !canModify(ctx, existing) || !canModify(ctx, dup) {
+		writeNotOwner(w)
+		return
+	}
+
+	merged := existing
+	fields := mergeInto(&merged, dup)
+	if err := validateBusinessRules(merged, fields); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	// Target diperbarui lebih dulu agar data duplikat tidak hilang jika update kalah balapan dengan write lain.
+	// Keduanya dijalankan dalam satu transaksi bila tersedia; tanpa transaksi, update target tetap tersimpan
+	// walaupun penghapusan duplikatnya gagal.
+	update := locationUpdate(fields, merged, time.Now())
+	var updated, deleted Location
+	targetUpdated := false
+	inTxn, err := s.runInTransaction(r, func(ctx context.Context) error {
+		updated, targetUpdated = existing, false
+		var audits []AuditEvent
+		var err error
+		if update != nil {
+			updated, err = repo.Update(ctx, id, revisionFilter(existing), update)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return errMergeTargetChanged
+			}
+			if err != nil {
+				return err
+			}
+			targetUpdated = true
+			audits = append(audits, newAuditEvent(auditUpdate, &existing, &updated))
+		}
+		deleted, err = repo.Delete(ctx, otherID)
+		if err != nil {
+			return err
+		}
+		return s.auditInTransaction(ctx, r, append(audits, newAuditEvent(auditDelete, &deleted, nil))...)
+	})
+	if targetUpdated && (err == nil || !inTxn) {
+		s.bumpCollectionVersion(ctx)
+		s.notifyLocationChange(&existing, &updated)
+		if !inTxn {
+			s.recordAudit(r, newAuditEvent(auditUpdate, &existing, &updated))
+		}
+	}
+	if errors.Is(err, errMergeTargetChanged) {
+		current, getErr := s.locations.GetByID(ctx, id)
+		if getErr == nil {
+			writePreconditionFailed(w, current)
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+	if !inTxn {
+		s.recordAudit(r, newAuditEvent(auditDelete, &deleted, nil))
+	}
+
+	w.Header().Set("ETag", updated.ETag())
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"kept":   updated,
+		"merged": otherID,
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// adminRole adalah nilai klaim JWT role (atau salah satu isi roles) yang boleh mengubah lokasi milik siapa pun
+const adminRole = "admin"
+
+// userKey adalah key context untuk pengguna request
+type userKey struct{}
+
+// requestUser adalah pengguna di balik request. ID berasal dari klaim sub bearer token JWT; request dengan
+// API key tidak mewakili pengguna tertentu sehingga ID-nya kosong. Admin berarti X-Admin-Key yang valid
+// atau token dengan role admin.
+type requestUser struct {
+	ID    string
+	Admin bool
+}
+
+// identifyUser menentukan pengguna request dari header-nya. Token yang tidak valid diabaikan di sini karena
+// penolakannya sudah menjadi tugas requireAuth.
+func identifyUser(r *http.Request) requestUser {
+	user := requestUser{Admin: validAdminKey(r)}
+	token, ok := bearerToken(r)
+	if !ok || config.JWTSecret == "" {
+		return user
+	}
+	claims, err := parseJWTClaims(token, config.JWTSecret)
+	if err != nil {
+		return user
+	}
+	user.ID, _ = claims.GetSubject()
+	if role, _ := claims["role"].(string); role == adminRole {
+		user.Admin = true
+	}
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if role == adminRole {
+				user.Admin = true
+			}
+		}
+	}
+	return user
+}
+
+// userMiddleware menyimpan pengguna request di context, agar lokasi baru diberi pemilik dan pengecekan
+// kepemilikan tidak perlu mem-parse token ulang
+func userMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, identifyUser(r))))
+	})
+}
+
+// userFromContext mengembalikan pengguna request; kosong untuk request tanpa JWT dan perintah CLI
+func userFromContext(ctx context.Context) requestUser {
+	user, _ := ctx.Value(userKey{}).(requestUser)
+	return user
+}
+
+// canModify mengecek apakah pengguna request boleh mengubah atau menghapus loc. Lokasi tanpa owner_id
+// (dibuat sebelum ada kepemilikan, lewat API key, atau lewat CLI) tetap boleh diubah siapa pun yang lolos
+// requireAuth seperti sebelumnya.
+func canModify(ctx context.Context, loc Location) bool {
+	user := userFromContext(ctx)
+	return loc.OwnerID == "" || user.Admin || (user.ID != "" && user.ID == loc.OwnerID)
+}
+
+// errNotOwner dikembalikan jika lokasi dimiliki pengguna lain
+var errNotOwner = errors.New("only the owner of this location or an admin can change it")
+
+// writeNotOwner menulis response 403 untuk errNotOwner
+func writeNotOwner(w http.ResponseWriter) {
+	writeError(w, http.StatusForbidden, "not_owner", "Only the owner of this location or an admin can change it")
+}
+
+// checkLocationOwner mengecek kepemilikan lokasi id, termasuk yang sedang di trash, untuk write yang tidak
+// membaca dokumennya lebih dulu. Lokasi yang tidak ada bukan error di sini agar write-nya sendiri yang
+// menjawab 404 dengan pesan yang biasa.
+func (s *Server) checkLocationOwner(ctx context.Context, id primitive.ObjectID) error {
+	var owner struct {
+		OwnerID string `bson:"owner_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"owner_id": 1})
+	err := s.collection.FindOne(ctx, forTenant(ctx, bson.M{"_id": id}), opts).Decode(&owner)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !canModify(ctx, Location{OwnerID: owner.OwnerID}) {
+		return errNotOwner
+	}
+	return nil
+}
+
+// requireLocationOwner menjalankan checkLocationOwner dan menulis response error-nya; false berarti
+// handler harus berhenti
+func (s *Server) requireLocationOwner(w http.ResponseWriter, r *http.Request, id primitive.ObjectID) bool {
+	err := s.checkLocationOwner(r.Context(), id)
+	if errors.Is(err, errNotOwner) {
+		writeNotOwner(w)
+		return false
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// s3Timeout adalah batas waktu satu request ke bucket S3, di luar batas waktu request client
+	s3Timeout = 30 * time.Second
+	// defaultS3Region dipakai jika S3_REGION tidak diset; kebanyakan layanan S3-compatible menerimanya
+	defaultS3Region = "us-east-1"
+)
+
+// errPhotoFileNotFound dikembalikan photoStore jika file foto tidak ada di storage
+var errPhotoFileNotFound = errors.New("photo file not found")
+
+// photoStore menyimpan isi file foto; metadata-nya disimpan di dokumen lokasi. Key adalah ID foto (hex),
+// sehingga setiap file tidak pernah ditimpa dan boleh di-cache selamanya.
+type photoStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Open mengembalikan isi file, atau errPhotoFileNotFound jika tidak ada
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete tidak menganggap file yang sudah tidak ada sebagai error
+	Delete(ctx context.Context, key string) error
+}
+
+// gridFSPhotoStore menyimpan foto di bucket GridFS <koleksi>_photos pada database yang sama
+type gridFSPhotoStore struct {
+	bucket *gridfs.Bucket
+}
+
+// newGridFSPhotoStore membuat photoStore GridFS untuk koleksi lokasi. NewBucket hanya gagal untuk opsi yang
+// tidak valid, sehingga error-nya tidak mungkin terjadi di sini.
+func newGridFSPhotoStore(coll *mongo.Collection) *gridFSPhotoStore {
+	bucket, err := gridfs.NewBucket(coll.Database(), options.GridFSBucket().SetName(coll.Name()+"_photos"))
+	if err != nil {
+		panic(err)
+	}
+	return &gridFSPhotoStore{bucket: bucket}
+}
+
+// Put memakai deadline per stream karena deadline milik bucket dipakai bersama oleh semua request
+func (g *gridFSPhotoStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	stream, err := g.bucket.OpenUploadStreamWithID(key, key,
+		options.GridFSUpload().SetMetadata(bson.M{"content_type": contentType}))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetWriteDeadline(deadline)
+	}
+	if _, err := stream.Write(data); err != nil {
+		stream.Abort()
+		return err
+	}
+	return stream.Close()
+}
+
+func (g *gridFSPhotoStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	stream, err := g.bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, errPhotoFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetReadDeadline(deadline)
+	}
+	return stream, nil
+}
+
+func (g *gridFSPhotoStore) Delete(ctx context.Context, key string) error {
+	err := g.bucket.DeleteContext(ctx, key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
+
+// s3PhotoStore menyimpan foto di bucket S3 atau layanan S3-compatible (MinIO, R2, dan sejenisnya) lewat
+// REST API dengan Signature V4. URL memakai gaya path (endpoint/bucket/key) yang didukung semua layanan tersebut.
+type s3PhotoStore struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	prefix    string
+	client    *http.Client
+}
+
+// newS3PhotoStore membuat photoStore S3 dari konfigurasi yang sudah divalidasi loadConfig
+func newS3PhotoStore(cfg Config) *s3PhotoStore {
+	endpoint, _ := url.Parse(cfg.S3Endpoint)
+	return &s3PhotoStore{
+		endpoint:  endpoint,
+		bucket:    cfg.S3Bucket,
+		region:    cfg.S3Region,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		prefix:    cfg.Collection + "/photos/",
+		client:    &http.Client{Timeout: s3Timeout, Transport: tracedTransport()},
+	}
+}
+
+func (s *s3PhotoStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, contentType, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3 put returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *s3PhotoStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, errPhotoFileNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 get returned status %d", resp.StatusCode)
+	}
+}
+
+func (s *s3PhotoStore) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("s3 delete returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// do mengirim satu request bertanda tangan ke objek key
+func (s *s3PhotoStore) do(ctx context.Context, method, key, contentType string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + s.prefix + key
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now())
+	return s.client.Do(req)
+}
+
+// sign menambahkan header Authorization AWS Signature V4 untuk layanan s3. Yang ditandatangani hanya host,
+// x-amz-content-sha256, dan x-amz-date, ditambah hash body sehingga isi file tidak bisa diganti di tengah jalan.
+func (s *s3PhotoStore) sign(req *http.Request, body []byte, now time.Time) {
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 menghitung HMAC-SHA256 dari data dengan key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// parseS3Endpoint memvalidasi S3_ENDPOINT; kosong berarti endpoint AWS untuk region tersebut
+func parseS3Endpoint(raw, region string) (string, error) {
+	if raw == "" {
+		return "https://s3." + region + ".amazonaws.com", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+		return "", fmt.Errorf("S3_ENDPOINT must be an absolute http(s) URL without a query, got %q", raw)
+	}
+	return strings.TrimSuffix(raw, "/"), nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// defaultMaxPhotoBytes adalah ukuran maksimum satu foto jika PHOTO_MAX_BYTES tidak diset
+	defaultMaxPhotoBytes = 10 << 20
+	// photoMultipartOverhead adalah sisa batas body upload foto untuk boundary dan header part multipart
+	photoMultipartOverhead = 64 << 10
+	// maxPhotosPerLocation membatasi jumlah foto per lokasi agar dokumen lokasi tetap kecil
+	maxPhotosPerLocation = 20
+	// photoFormField adalah nama field multipart yang berisi file foto
+	photoFormField = "photo"
+	// maxPhotoFilenameLength membatasi nama file asli yang disimpan di metadata
+	maxPhotoFilenameLength = 255
+	// photoCleanupTimeout adalah batas waktu menghapus file foto setelah metadata-nya dihapus
+	photoCleanupTimeout = 10 * time.Second
+)
+
+// maxPhotoBytes adalah ukuran maksimum satu foto, bisa diatur lewat PHOTO_MAX_BYTES
+var maxPhotoBytes int64 = defaultMaxPhotoBytes
+
+// photoContentTypes adalah jenis gambar yang diterima. Jenisnya dideteksi dari isi file, bukan dari header
+// client, agar file lain tidak bisa disajikan sebagai gambar.
+var photoContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Photo adalah metadata satu foto lokasi; isi file-nya ada di photoStore dengan key ID foto
+type Photo struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	Filename    string             `bson:"filename,omitempty" json:"filename,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ETag foto diturunkan dari ID-nya, karena isi file untuk satu ID tidak pernah berubah
+func (p Photo) ETag() string {
+	return `"` + p.ID.Hex() + `"`
+}
+
+// loadMaxPhotoBytes membaca PHOTO_MAX_BYTES dari environment (kosong berarti memakai default)
+func loadMaxPhotoBytes() {
+	raw := os.Getenv("PHOTO_MAX_BYTES")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		fatal("PHOTO_MAX_BYTES must be a positive integer", "value", raw)
+	}
+	maxPhotoBytes = n
+	slog.Info("photo size limit configured", "max_photo_bytes", n)
+}
+
+// findPhoto mengembalikan metadata foto dengan ID tersebut pada lokasi
+func findPhoto(loc Location, id primitive.ObjectID) (Photo, bool) {
+	for _, p := range loc.Photos {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Photo{}, false
+}
+
+// readPhotoPart mencari field photoFormField di body multipart dan membaca isinya, paling banyak maxPhotoBytes
+func readPhotoPart(w http.ResponseWriter, r *http.Request) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoBytes+photoMultipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", fmt.Errorf("multipart body must contain a %q file field", photoFormField)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() != photoFormField {
+			part.Close()
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, maxPhotoBytes+1))
+		if err != nil {
+			return nil, "", err
+		}
+		if int64(len(data)) > maxPhotoBytes {
+			return nil, "", &http.MaxBytesError{Limit: maxPhotoBytes}
+		}
+		return data, filepath.Base(part.FileName()), nil
+	}
+}
+
+// uploadPhotoHandler menyimpan satu foto dari field multipart "photo" untuk lokasi {id}. File disimpan ke
+// photoStore lebih dulu, lalu metadata-nya ditambahkan ke lokasi; jika langkah kedua gagal, file dihapus lagi.
+func (s *Server) uploadPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, err := s.locations.GetByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !canModify(ctx, existing) {
+		writeNotOwner(w)
+		return
+	}
+	if len(existing.Photos) >= maxPhotosPerLocation {
+		writeError(w, http.StatusConflict, "photo_limit_reached", fmt.Sprintf("A location can have at most %d photos", maxPhotosPerLocation))
+		return
+	}
+
+	data, filename, err := readPhotoPart(w, r)
+	if errors.Is(err, http.ErrNotMultipart) {
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Request body must be multipart/form-data")
+		return
+	}
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !photoContentTypes[contentType] {
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", "Photo must be a JPEG, PNG, GIF or WebP image")
+		return
+	}
+	if len(filename) > maxPhotoFilenameLength || filename == "." || filename == string(filepath.Separator) {
+		filename = ""
+	}
+
+	now := time.Now()
+	photo := Photo{ID: primitive.NewObjectID(), ContentType: contentType, Size: int64(len(data)), Filename: filename, CreatedAt: now}
+	if err := s.photos.Put(ctx, photo.ID.Hex(), contentType, data); err != nil {
+		requestLogger(r).Error("photo could not be stored", "photo_id", photo.ID.Hex(), "error", err)
+		writeError(w, http.StatusBadGateway, "photo_storage_failed", "Photo could not be stored, try again later")
+		return
+	}
+
+	// Batas jumlah foto diperiksa ulang di filter, karena upload lain bisa selesai lebih dulu
+	match := bson.M{fmt.Sprintf("photos.%d", maxPhotosPerLocation-1): bson.M{"$exists": false}}
+	updated, err := repo.Update(ctx, id, match, bson.M{
+		"$push": bson.M{"photos": photo},
+		"$set":  bson.M{"updated_at": now},
+		"$inc":  bson.M{"revision": 1},
+	})
+	if err != nil {
+		s.deletePhotoFiles(r, photo)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, getErr := s.locations.GetByID(ctx, id); getErr == nil {
+			writeError(w, http.StatusConflict, "photo_limit_reached", fmt.Sprintf("A location can have at most %d photos", maxPhotosPerLocation))
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+	s.recordAudit(r, newAuditEvent(auditUpdate, &existing, &updated))
+
+	w.Header().Set("Location", r.URL.Path+"/"+photo.ID.Hex())
+	writeResponse(w, r, http.StatusCreated, photo)
+}
+
+// getPhotoHandler menyajikan isi foto dengan Content-Type aslinya. Isi satu ID foto tidak pernah berubah,
+// sehingga response boleh di-cache selamanya; cache bersama hanya diizinkan jika data memang publik.
+func (s *Server) getPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	ids, err := parseObjectIDs([]string{vars["id"], vars["photoId"]})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location or photo ID format")
+		return
+	}
+
+	loc, err := s.locations.GetByID(ctx, ids[0])
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	photo, ok := findPhoto(loc, ids[1])
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Photo not found")
+		return
+	}
+
+	cacheControl := "public, max-age=31536000, immutable"
+	if protectReadsEnabled() || multiTenantEnabled() {
+		cacheControl = "private, max-age=31536000, immutable"
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", photo.ETag())
+	w.Header().Set("Last-Modified", photo.CreatedAt.UTC().Format(http.TimeFormat))
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, photo.ETag(), true) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	file, err := s.photos.Open(ctx, photo.ID.Hex())
+	if errors.Is(err, errPhotoFileNotFound) {
+		w.Header().Del("Cache-Control")
+		writeJSONError(w, http.StatusNotFound, "Photo file not found")
+		return
+	}
+	if err != nil {
+		w.Header().Del("Cache-Control")
+		requestLogger(r).Error("photo could not be read", "photo_id", photo.ID.Hex(), "error", err)
+		writeError(w, http.StatusBadGateway, "photo_storage_failed", "Photo could not be read, try again later")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", photo.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(photo.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if photo.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": photo.Filename}))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		// Header sudah terkirim, sehingga kegagalan di tengah hanya bisa dicatat
+		requestLogger(r).Warn("photo download interrupted", "photo_id", photo.ID.Hex(), "error", err)
+	}
+}
+
+// deletePhotoHandler menghapus satu foto dari lokasi beserta file-nya
+func (s *Server) deletePhotoHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	ids, err := parseObjectIDs([]string{vars["id"], vars["photoId"]})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location or photo ID format")
+		return
+	}
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, err := s.locations.GetByID(ctx, ids[0])
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Location not found")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !canModify(ctx, existing) {
+		writeNotOwner(w)
+		return
+	}
+	photo, ok := findPhoto(existing, ids[1])
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Photo not found")
+		return
+	}
+
+	updated, err := repo.Update(ctx, ids[0], bson.M{"photos._id": photo.ID}, bson.M{
+		"$pull": bson.M{"photos": bson.M{"_id": photo.ID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$inc":  bson.M{"revision": 1},
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, "Photo not found")
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.deletePhotoFiles(r, photo)
+	s.bumpCollectionVersion(ctx)
+	s.recordAudit(r, newAuditEvent(auditUpdate, &existing, &updated))
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Photo with ID %s was deleted", vars["photoId"]),
+	})
+}
+
+// isPhotoDownload mengenali GET /locations/{id}/photos/{photoId}, yang durasinya bergantung pada ukuran
+// foto dan kecepatan client
+func isPhotoDownload(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/photos/")
+}
+
+// deletePhotoFiles menghapus file foto yang metadata-nya sudah tidak dipakai lagi. Kegagalan hanya dicatat:
+// write lokasinya sudah terjadi, dan file yang tertinggal tidak bisa diakses lewat API.
+func (s *Server) deletePhotoFiles(r *http.Request, photos ...Photo) {
+	if len(photos) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), photoCleanupTimeout)
+	defer cancel()
+	for _, p := range photos {
+		if err := s.photos.Delete(ctx, p.ID.Hex()); err != nil {
+			requestLogger(r).Warn("photo file could not be deleted", "photo_id", p.ID.Hex(), "error", err)
+		}
+	}
+}
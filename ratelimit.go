@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultRateLimit adalah jumlah request per detik per IP jika RATE_LIMIT_RPS tidak diset
+	defaultRateLimit = 10
+	// defaultRateBurst adalah jumlah request beruntun yang diizinkan jika RATE_LIMIT_BURST tidak diset
+	defaultRateBurst = 20
+	// rateLimiterIdleTTL adalah lama limiter sebuah IP disimpan setelah request terakhirnya
+	rateLimiterIdleTTL = 3 * time.Minute
+	// rateLimiterSweepInterval adalah jeda antar pembersihan limiter yang sudah tidak dipakai
+	rateLimiterSweepInterval = time.Minute
+)
+
+// rateLimitConfig membaca RATE_LIMIT_RPS dan RATE_LIMIT_BURST dari environment; RATE_LIMIT_RPS=0 mematikan limit
+func rateLimitConfig() (rate.Limit, int) {
+	return parseRateLimitEnv("RATE_LIMIT_RPS", "RATE_LIMIT_BURST", defaultRateLimit, defaultRateBurst)
+}
+
+// authRateLimitConfig membaca RATE_LIMIT_AUTH_RPS dan RATE_LIMIT_AUTH_BURST untuk client yang mengirim
+// API key atau JWT yang valid; tanpa keduanya nilainya sama dengan limit per IP. RATE_LIMIT_AUTH_RPS=0
+// membebaskan client tersebut dari limit.
+func authRateLimitConfig(limit rate.Limit, burst int) (rate.Limit, int) {
+	authLimit, authBurst := parseRateLimitEnv("RATE_LIMIT_AUTH_RPS", "RATE_LIMIT_AUTH_BURST", float64(limit), burst)
+	if authLimit == 0 {
+		return rate.Inf, authBurst
+	}
+	return authLimit, authBurst
+}
+
+// parseRateLimitEnv membaca pasangan env rps dan burst, memakai default untuk yang kosong
+func parseRateLimitEnv(rpsKey, burstKey string, rps float64, burst int) (rate.Limit, int) {
+	if raw := os.Getenv(rpsKey); raw != "" {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || f < 0 {
+			fatal(rpsKey+" must be a non-negative number", "value", raw)
+		}
+		rps = f
+	}
+	if raw := os.Getenv(burstKey); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			fatal(burstKey+" must be a positive integer", "value", raw)
+		}
+		burst = n
+	}
+	return rate.Limit(rps), burst
+}
+
+// trustProxyHeaders aktif jika TRUST_PROXY_HEADERS=true. Di belakang proxy (seperti Railway) RemoteAddr adalah
+// alamat proxy, sehingga IP client diambil dari X-Forwarded-For; jangan diaktifkan tanpa proxy karena header bisa dipalsukan.
+func trustProxyHeaders() bool {
+	return os.Getenv("TRUST_PROXY_HEADERS") == "true"
+}
+
+// clientIP mengembalikan alamat IP client untuk keperluan rate limiting
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// visitor adalah token bucket satu client beserta waktu request terakhirnya
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// clientRateLimiter menyimpan token bucket per client (IP atau credential)
+type clientRateLimiter struct {
+	sync.Mutex
+	visitors map[string]*visitor
+	limit    rate.Limit
+	burst    int
+}
+
+// newClientRateLimiter membuat clientRateLimiter dan menjalankan pembersihan berkala di background,
+// agar map tidak terus membesar oleh client yang hanya sekali lewat
+func newClientRateLimiter(limit rate.Limit, burst int) *clientRateLimiter {
+	l := &clientRateLimiter{visitors: map[string]*visitor{}, limit: limit, burst: burst}
+	go func() {
+		for range time.Tick(rateLimiterSweepInterval) {
+			l.sweep(time.Now().Add(-rateLimiterIdleTTL))
+		}
+	}()
+	return l
+}
+
+// limiter mengembalikan token bucket untuk key, membuatnya jika belum ada
+func (l *clientRateLimiter) limiter(key string) *rate.Limiter {
+	l.Lock()
+	defer l.Unlock()
+	v, ok := l.visitors[key]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.visitors[key] = v
+	}
+	v.lastSeen = time.Now()
+	return v.limiter
+}
+
+// sweep menghapus limiter yang request terakhirnya sebelum cutoff
+func (l *clientRateLimiter) sweep(cutoff time.Time) {
+	l.Lock()
+	defer l.Unlock()
+	for key, v := range l.visitors {
+		if v.lastSeen.Before(cutoff) {
+			delete(l.visitors, key)
+		}
+	}
+}
+
+// rateLimitCredential mengembalikan key bucket untuk request yang membawa API key atau JWT yang valid.
+// Credential yang tidak valid diabaikan (jatuh ke limit per IP), agar client tidak bisa lolos dari limit
+// dengan mengganti-ganti key palsu.
+func rateLimitCredential(r *http.Request) (string, bool) {
+	if tenant, ok := tenantForKey(r.Header.Get("X-API-Key")); ok {
+		return "tenant:" + tenant, true
+	}
+	if apiKey := config.APIKey; apiKey != "" {
+		if key := r.Header.Get("X-API-Key"); key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+			return "api-key", true
+		}
+	}
+	if secret := config.JWTSecret; secret != "" {
+		if token, ok := bearerToken(r); ok {
+			// Token tanpa sub tetap dibedakan per token, bukan digabung dalam satu bucket
+			if sub, err := validateJWT(token, secret); err == nil {
+				if sub == "" {
+					sum := sha256.Sum256([]byte(token))
+					sub = hex.EncodeToString(sum[:8])
+				}
+				return "jwt:" + sub, true
+			}
+		}
+	}
+	return "", false
+}
+
+// setRateLimitHeaders menulis X-RateLimit-Limit (ukuran bucket), X-RateLimit-Remaining (token tersisa), dan
+// X-RateLimit-Reset (detik sampai bucket penuh kembali)
+func setRateLimitHeaders(w http.ResponseWriter, lim *rate.Limiter, now time.Time) {
+	tokens := math.Max(0, lim.TokensAt(now))
+	reset := 0
+	if missing := float64(lim.Burst()) - tokens; missing > 0 && lim.Limit() > 0 {
+		reset = int(math.Ceil(missing / float64(lim.Limit())))
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(lim.Burst()))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Floor(tokens))))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(reset))
+}
+
+// requestRateLimiter adalah token bucket per IP dan per credential yang dipakai bersama oleh REST API dan gRPC,
+// sehingga client tidak mendapat kuota dua kali dengan memakai kedua port
+type requestRateLimiter struct {
+	byIP         *clientRateLimiter
+	byCredential *clientRateLimiter
+	trustProxy   bool
+}
+
+// newRequestRateLimiter membuat requestRateLimiter. Client dengan API key atau JWT yang valid dihitung per
+// credential dengan limit authLimit/authBurst, client lain per IP.
+func newRequestRateLimiter(limit rate.Limit, burst int, authLimit rate.Limit, authBurst int, trustProxy bool) *requestRateLimiter {
+	return &requestRateLimiter{
+		byIP:         newClientRateLimiter(limit, burst),
+		byCredential: newClientRateLimiter(authLimit, authBurst),
+		trustProxy:   trustProxy,
+	}
+}
+
+// reserve mengambil satu token dari bucket client r dan mengembalikan bucket tersebut beserta waktu tunggunya.
+// Jika waktu tunggu lebih dari nol, token tidak dipakai dan request harus ditolak.
+func (l *requestRateLimiter) reserve(r *http.Request, now time.Time) (*rate.Limiter, time.Duration) {
+	var lim *rate.Limiter
+	if key, ok := rateLimitCredential(r); ok {
+		lim = l.byCredential.limiter(key)
+	} else {
+		lim = l.byIP.limiter(clientIP(r, l.trustProxy))
+	}
+	res := lim.ReserveN(now, 1)
+	delay := res.DelayFrom(now)
+	if delay > 0 {
+		res.CancelAt(now)
+	}
+	return lim, delay
+}
+
+// rateLimitMiddleware menolak request dengan 429 dan header Retry-After jika client melebihi token bucket-nya
+func rateLimitMiddleware(l *requestRateLimiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			lim, delay := l.reserve(r, now)
+			setRateLimitHeaders(w, lim, now)
+			if delay > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				writeJSONError(w, http.StatusTooManyRequests, fmt.Sprintf("Rate limit of %g requests per second exceeded", float64(lim.Limit())))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
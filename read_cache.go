@@ -0,0 +1,348 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// defaultReadCacheMaxEntries adalah jumlah entri read cache jika READ_CACHE_MAX_ENTRIES tidak diset. Satu entri
+// bisa berisi satu halaman list penuh, sehingga batasnya sengaja rendah.
+const defaultReadCacheMaxEntries = 1000
+
+// readCacheEntry adalah satu hasil repository yang di-cache. id hanya terisi untuk hasil GetByID; entri lain
+// (list, count, near) bisa dipengaruhi perubahan lokasi mana pun.
+type readCacheEntry struct {
+	key     string
+	id      primitive.ObjectID
+	value   interface{}
+	expires time.Time
+}
+
+// readCache adalah cache LRU dengan TTL untuk hasil baca repository. Cache hanya dipakai selama active, yaitu
+// selama change stream yang membatalkan entrinya sedang berjalan; tanpa change stream, perubahan dari instance
+// lain tidak terlihat sampai TTL habis. gen naik setiap kali entri dibatalkan, agar hasil query yang dimulai
+// sebelum pembatalan tidak disimpan setelahnya.
+type readCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	active  bool
+	gen     uint64
+	order   *list.List // depan = paling baru dipakai
+	entries map[string]*list.Element
+}
+
+func newReadCache(ttl time.Duration, maxEntries int) *readCache {
+	return &readCache{ttl: ttl, maxEntries: maxEntries, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get mengembalikan nilai yang belum kedaluwarsa, beserta generasi cache saat ini untuk put berikutnya
+func (c *readCache) get(key string) (interface{}, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		readCacheLookups.WithLabelValues("bypass").Inc()
+		return nil, c.gen, false
+	}
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*readCacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.order.MoveToFront(el)
+			readCacheLookups.WithLabelValues("hit").Inc()
+			return entry.value, c.gen, true
+		}
+		c.remove(el)
+	}
+	readCacheLookups.WithLabelValues("miss").Inc()
+	return nil, c.gen, false
+}
+
+// put menyimpan nilai jika cache aktif dan tidak ada pembatalan sejak get yang mengembalikan gen
+func (c *readCache) put(key string, id primitive.ObjectID, value interface{}, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active || gen != c.gen {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&readCacheEntry{key: key, id: id, value: value, expires: time.Now().Add(c.ttl)})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *readCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*readCacheEntry).key)
+}
+
+// invalidate membuang entri GetByID untuk id beserta semua entri query. ID kosong membuang seluruh cache.
+func (c *readCache) invalidate(id primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if entryID := el.Value.(*readCacheEntry).id; id.IsZero() || entryID.IsZero() || entryID == id {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// setActive menyalakan atau mematikan cache; keduanya membuang seluruh isi cache, karena perubahan selama
+// change stream tidak berjalan tidak diketahui
+func (c *readCache) setActive(active bool) {
+	c.mu.Lock()
+	c.active = active
+	c.mu.Unlock()
+	c.invalidate(primitive.NilObjectID)
+}
+
+// cachingLocationRepository membungkus LocationRepository dengan readCache untuk GetByID, List, Count, dan Near.
+// Write lewat repository ini langsung membatalkan cache, sehingga instance yang menulis tidak menunggu change
+// stream untuk melihat perubahannya sendiri. Hasil selalu disalin sebelum dikembalikan, karena handler boleh
+// mengubah lokasi yang diterimanya.
+type cachingLocationRepository struct {
+	LocationRepository
+	cache *readCache
+}
+
+func newCachingLocationRepository(repo LocationRepository, cache *readCache) *cachingLocationRepository {
+	return &cachingLocationRepository{LocationRepository: repo, cache: cache}
+}
+
+// readCacheKey membentuk key cache dari tenant request, include_expired, projection ?fields=, dan parameter
+// query. fmt mencetak isi
+// map terurut berdasarkan key, sehingga filter bson.M yang sama selalu menghasilkan key yang sama.
+func readCacheKey(ctx context.Context, op string, params ...interface{}) string {
+	return fmt.Sprintf("%s|%q|%t|%v|%v", op, tenantFromContext(ctx), includeExpired(ctx), projectionFromContext(ctx), params)
+}
+
+func (c *cachingLocationRepository) GetByID(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	key := readCacheKey(ctx, "get", id)
+	value, gen, ok := c.cache.get(key)
+	if ok {
+		return value.(Location).clone(), nil
+	}
+	loc, err := c.LocationRepository.GetByID(ctx, id)
+	if err != nil {
+		return loc, err
+	}
+	c.cache.put(key, id, loc.clone(), gen)
+	return loc, nil
+}
+
+// listResult adalah hasil List yang di-cache, termasuk peringatan dokumen yang tidak bisa di-decode
+type listResult struct {
+	locations []Location
+	skipped   []string
+}
+
+func (c *cachingLocationRepository) List(ctx context.Context, filter bson.M, sort bson.D, limit, skip int64) ([]Location, []string, error) {
+	key := readCacheKey(ctx, "list", filter, sort, limit, skip)
+	value, gen, ok := c.cache.get(key)
+	if ok {
+		res := value.(listResult)
+		return cloneLocations(res.locations), slices.Clone(res.skipped), nil
+	}
+	locations, skipped, err := c.LocationRepository.List(ctx, filter, sort, limit, skip)
+	if err != nil {
+		return locations, skipped, err
+	}
+	c.cache.put(key, primitive.NilObjectID, listResult{locations: cloneLocations(locations), skipped: slices.Clone(skipped)}, gen)
+	return locations, skipped, nil
+}
+
+func (c *cachingLocationRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
+	key := readCacheKey(ctx, "count", filter)
+	value, gen, ok := c.cache.get(key)
+	if ok {
+		return value.(int64), nil
+	}
+	count, err := c.LocationRepository.Count(ctx, filter)
+	if err != nil {
+		return count, err
+	}
+	c.cache.put(key, primitive.NilObjectID, count, gen)
+	return count, nil
+}
+
+func (c *cachingLocationRepository) Near(ctx context.Context, lng, lat, maxMeters float64, filter bson.M, limit int64) ([]NearLocation, error) {
+	key := readCacheKey(ctx, "near", lng, lat, maxMeters, filter, limit)
+	value, gen, ok := c.cache.get(key)
+	if ok {
+		return cloneNearLocations(value.([]NearLocation)), nil
+	}
+	locations, err := c.LocationRepository.Near(ctx, lng, lat, maxMeters, filter, limit)
+	if err != nil {
+		return locations, err
+	}
+	c.cache.put(key, primitive.NilObjectID, cloneNearLocations(locations), gen)
+	return locations, nil
+}
+
+// Create dan write lainnya membatalkan cache walaupun gagal, karena write yang timeout mungkin tetap
+// diterapkan MongoDB
+func (c *cachingLocationRepository) Create(ctx context.Context, loc Location) error {
+	defer c.cache.invalidate(loc.ID)
+	return c.LocationRepository.Create(ctx, loc)
+}
+
+func (c *cachingLocationRepository) Update(ctx context.Context, id primitive.ObjectID, match bson.M, update bson.M) (Location, error) {
+	defer c.cache.invalidate(id)
+	return c.LocationRepository.Update(ctx, id, match, update)
+}
+
+func (c *cachingLocationRepository) Delete(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	defer c.cache.invalidate(id)
+	return c.LocationRepository.Delete(ctx, id)
+}
+
+func (c *cachingLocationRepository) Restore(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	defer c.cache.invalidate(id)
+	return c.LocationRepository.Restore(ctx, id)
+}
+
+func (c *cachingLocationRepository) Purge(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	defer c.cache.invalidate(id)
+	return c.LocationRepository.Purge(ctx, id)
+}
+
+// WithWriteConcern tetap memakai cache yang sama, agar write dengan write concern lain ikut membatalkannya
+func (c *cachingLocationRepository) WithWriteConcern(wc *writeconcern.WriteConcern) (LocationRepository, error) {
+	repo, err := c.LocationRepository.WithWriteConcern(wc)
+	if err != nil {
+		return nil, err
+	}
+	return newCachingLocationRepository(repo, c.cache), nil
+}
+
+// clone menyalin lokasi beserta slice dan pointer di dalamnya
+func (l Location) clone() Location {
+	out := l
+	out.Tags = slices.Clone(l.Tags)
+	out.Photos = slices.Clone(l.Photos)
+	out.Location.Coordinates = cloneCoordinates(l.Location.Coordinates)
+	if l.ExpiresAt != nil {
+		expiresAt := *l.ExpiresAt
+		out.ExpiresAt = &expiresAt
+	}
+	if l.DeletedAt != nil {
+		deletedAt := *l.DeletedAt
+		out.DeletedAt = &deletedAt
+	}
+	return out
+}
+
+// cloneCoordinates menyalin koordinat Point, LineString, atau Polygon
+func cloneCoordinates(coords interface{}) interface{} {
+	switch c := coords.(type) {
+	case []float64:
+		return slices.Clone(c)
+	case [][]float64:
+		out := make([][]float64, len(c))
+		for i, pos := range c {
+			out[i] = slices.Clone(pos)
+		}
+		return out
+	case [][][]float64:
+		out := make([][][]float64, len(c))
+		for i, ring := range c {
+			out[i] = cloneCoordinates(ring).([][]float64)
+		}
+		return out
+	}
+	return coords
+}
+
+func cloneLocations(locations []Location) []Location {
+	if locations == nil {
+		return nil
+	}
+	out := make([]Location, len(locations))
+	for i, loc := range locations {
+		out[i] = loc.clone()
+	}
+	return out
+}
+
+func cloneNearLocations(locations []NearLocation) []NearLocation {
+	out := make([]NearLocation, len(locations))
+	for i, loc := range locations {
+		out[i] = NearLocation{Location: loc.Location.clone(), DistanceMeters: loc.DistanceMeters, DistanceM: loc.DistanceM}
+	}
+	return out
+}
+
+// runReadCacheInvalidator menjalankan change stream yang membatalkan entri read cache untuk setiap perubahan
+// lokasi, dari instance mana pun (termasuk penghapusan oleh index TTL). Selama change stream terputus cache
+// dimatikan, lalu dicoba lagi dengan jeda berlipat ganda hingga maxConnectRetryDelay.
+func (s *Server) runReadCacheInvalidator(ctx context.Context, cache *readCache) {
+	delay := connectRetryDelay
+	for {
+		opened, err := s.watchReadCache(ctx, cache)
+		cache.setActive(false)
+		if ctx.Err() != nil {
+			return
+		}
+		var se mongo.ServerError
+		if errors.As(err, &se) && se.HasErrorCode(errCodeChangeStreamNotSupported) {
+			slog.Error("read cache disabled, change streams require MongoDB to run as a replica set")
+			return
+		}
+		if opened {
+			delay = connectRetryDelay
+		}
+		slog.Warn("read cache change stream stopped, cache disabled until it reconnects", "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxConnectRetryDelay)
+	}
+}
+
+// watchReadCache membuka change stream semua tenant dan membatalkan cache per event sampai stream berhenti.
+// Hanya documentKey yang dibutuhkan, sehingga isi dokumen dan updateDescription tidak ikut dikirim.
+func (s *Server) watchReadCache(ctx context.Context, cache *readCache) (bool, error) {
+	pipeline := bson.A{bson.M{"$project": bson.M{"operationType": 1, "documentKey": 1}}}
+	cs, err := s.collection.Watch(ctx, pipeline, options.ChangeStream().SetMaxAwaitTime(streamHeartbeatInterval))
+	if err != nil {
+		return false, err
+	}
+	defer cs.Close(context.Background())
+	cache.setActive(true)
+	slog.Info("read cache enabled", "ttl", cache.ttl, "max_entries", cache.maxEntries)
+
+	for cs.Next(ctx) {
+		var evt changeEvent
+		if err := cs.Decode(&evt); err != nil {
+			// Event yang tidak bisa dibaca tetap berarti ada perubahan, hanya lokasinya yang tidak diketahui
+			cache.invalidate(primitive.NilObjectID)
+			continue
+		}
+		// Event tanpa documentKey (drop, rename, invalidate) membuang seluruh cache lewat ID kosong
+		cache.invalidate(evt.DocumentKey.ID)
+	}
+	if err := cs.Err(); err != nil {
+		return true, err
+	}
+	return true, errors.New("change stream closed")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxRegions membatasi jumlah region dalam satu request, karena setiap region menjadi satu query count
+const maxRegions = 50
+
+// regionFeature adalah satu Feature berisi Polygon pada body POST /locations/region-counts
+type regionFeature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   Polygon                `json:"geometry"`
+}
+
+// regionCollection adalah body request berupa GeoJSON FeatureCollection
+type regionCollection struct {
+	Type     string          `json:"type"`
+	Features []regionFeature `json:"features"`
+}
+
+// RegionCount adalah jumlah lokasi di dalam satu region; ID dan properties dikembalikan apa adanya
+// agar client bisa mencocokkan hasil dengan feature yang dikirim
+type RegionCount struct {
+	Index      int                    `json:"index"`
+	ID         interface{}            `json:"id,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Count      int64                  `json:"count"`
+}
+
+// regionCountsHandler menghitung jumlah lokasi di dalam setiap polygon pada FeatureCollection.
+// Satu CountDocuments per region dipakai (alih-alih satu $facet) agar setiap hitungan bisa memakai index 2dsphere.
+func (s *Server) regionCountsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var req regionCollection
+	// Seperti import: bukan decodeStrict karena FeatureCollection hasil ekspor GIS sering membawa member
+	// tambahan seperti crs, dan batasnya sama karena polygon batas wilayah bisa berukuran besar
+	if err := decodeSingle(json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportBodyBytes)), &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if req.Type != "FeatureCollection" {
+		writeJSONError(w, http.StatusBadRequest, "body must be a GeoJSON FeatureCollection")
+		return
+	}
+	if len(req.Features) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "features must not be empty")
+		return
+	}
+	if len(req.Features) > maxRegions {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d regions can be counted per request", maxRegions))
+		return
+	}
+	for i, f := range req.Features {
+		if err := validatePolygon(f.Geometry); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("feature %d: %v", i, err))
+			return
+		}
+	}
+
+	counts := make([]RegionCount, len(req.Features))
+	for i, f := range req.Features {
+		filter := bson.M{"location": bson.M{"$geoWithin": bson.M{"$geometry": f.Geometry}}}
+		n, err := s.collection.CountDocuments(ctx, withoutDeleted(ctx, filter))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		counts[i] = RegionCount{Index: i, ID: f.ID, Properties: f.Properties, Count: n}
+	}
+
+	writeResponse(w, r, http.StatusOK, counts)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+
+	"go-mongo-railway/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LocationRepository adalah repository.Locations untuk Location, dipakai handler CRUD dan near lewat
+// Server.locations sehingga handler tersebut bisa diuji dengan implementasi tiruan tanpa MongoDB
+type LocationRepository = repository.Locations[Location, NearLocation]
+
+// newMongoLocationRepository membuat LocationRepository untuk koleksi yang diberikan, dengan aturan tenant, trash,
+// kedaluwarsa, dan projection ?fields= dari package ini
+func newMongoLocationRepository(coll *mongo.Collection) *repository.Mongo[Location, NearLocation] {
+	return repository.NewMongo(coll, repository.Hooks[Location, NearLocation]{
+		Active:     withoutDeleted,
+		Tenant:     forTenant,
+		Projection: projectionFromContext,
+		Decode:     decodeLocations,
+		Written:    decodeWrittenLocation,
+		NearResult: func(loc *NearLocation) { loc.DistanceM = loc.DistanceMeters },
+	})
+}
+
+// decodeWrittenLocation men-decode dokumen yang sudah selesai ditulis (dihapus atau dipindah ke trash).
+// Write-nya sudah terjadi, sehingga dokumen yang bentuknya tidak cocok dengan Location tidak dilaporkan
+// sebagai error; hasilnya cukup berisi ID saja.
+func decodeWrittenLocation(raw bson.Raw, id primitive.ObjectID) Location {
+	var loc Location
+	if err := bson.Unmarshal(raw, &loc); err != nil {
+		return Location{ID: id}
+	}
+	return loc
+}
+
+// writeRepository seperti writeCollection, tetapi mengembalikan LocationRepository
+func (s *Server) writeRepository(r *http.Request) (LocationRepository, error) {
+	wc, err := parseWriteConcern(r)
+	if err != nil || wc == nil {
+		return s.locations, err
+	}
+	return s.locations.WithWriteConcern(wc)
+}
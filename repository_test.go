@@ -0,0 +1,236 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// errFakeUnsupported dikembalikan fakeLocationRepository untuk operasi yang tidak dipakai test
+var errFakeUnsupported = errors.New("not supported by the fake repository")
+
+// fakeLocationRepository adalah LocationRepository di memori untuk test tanpa MongoDB. Filter List dan Count
+// hanya mendukung _id ($gt/$lt) dan location ($geoWithin/$geoIntersects dengan $geometry Polygon terhadap
+// lokasi Point), dan seperti MongoDB urutan dokumen dengan sort key yang sama tidak dijamin: dokumen diacak
+// sebelum diurutkan.
+type fakeLocationRepository struct {
+	locations []Location
+}
+
+func (f *fakeLocationRepository) Create(ctx context.Context, loc Location) error {
+	if loc.ID.IsZero() {
+		loc.ID = primitive.NewObjectID()
+	}
+	f.locations = append(f.locations, loc)
+	return nil
+}
+
+func (f *fakeLocationRepository) GetByID(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	for _, loc := range f.locations {
+		if loc.ID == id && loc.DeletedAt == nil {
+			return loc, nil
+		}
+	}
+	return Location{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeLocationRepository) List(ctx context.Context, filter bson.M, sortSpec bson.D, limit, skip int64) ([]Location, []string, error) {
+	matched := f.match(filter)
+	rand.Shuffle(len(matched), func(i, j int) { matched[i], matched[j] = matched[j], matched[i] })
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, key := range sortSpec {
+			if c := compareField(matched[i], matched[j], key.Key) * key.Value.(int); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	matched = matched[min(int(skip), len(matched)):]
+	if limit > 0 {
+		matched = matched[:min(int(limit), len(matched))]
+	}
+	return matched, nil, nil
+}
+
+func (f *fakeLocationRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
+	return int64(len(f.match(filter))), nil
+}
+
+func (f *fakeLocationRepository) Update(ctx context.Context, id primitive.ObjectID, match bson.M, update bson.M) (Location, error) {
+	return Location{}, errFakeUnsupported
+}
+
+func (f *fakeLocationRepository) Delete(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	return Location{}, errFakeUnsupported
+}
+
+func (f *fakeLocationRepository) Restore(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	return Location{}, errFakeUnsupported
+}
+
+func (f *fakeLocationRepository) Purge(ctx context.Context, id primitive.ObjectID) (Location, error) {
+	return Location{}, errFakeUnsupported
+}
+
+func (f *fakeLocationRepository) Near(ctx context.Context, lng, lat, maxMeters float64, filter bson.M, limit int64) ([]NearLocation, error) {
+	return nil, errFakeUnsupported
+}
+
+func (f *fakeLocationRepository) WithWriteConcern(wc *writeconcern.WriteConcern) (LocationRepository, error) {
+	return f, nil
+}
+
+// match mengembalikan salinan lokasi aktif yang cocok dengan filter _id dan location
+func (f *fakeLocationRepository) match(filter bson.M) []Location {
+	var out []Location
+	for _, loc := range f.locations {
+		if loc.DeletedAt != nil {
+			continue
+		}
+		if cond, ok := filter["_id"].(bson.M); ok {
+			if after, ok := cond["$gt"].(primitive.ObjectID); ok && loc.ID.Hex() <= after.Hex() {
+				continue
+			}
+			if before, ok := cond["$lt"].(primitive.ObjectID); ok && loc.ID.Hex() >= before.Hex() {
+				continue
+			}
+		}
+		if cond, ok := filter["location"].(bson.M); ok && !matchGeoFilter(loc, cond) {
+			continue
+		}
+		out = append(out, loc)
+	}
+	return out
+}
+
+// matchGeoFilter mengevaluasi $geoWithin atau $geoIntersects dengan $geometry Polygon untuk lokasi Point.
+// Untuk titik keduanya sama: titik harus di dalam ring luar dan tidak di dalam lubang mana pun.
+func matchGeoFilter(loc Location, cond bson.M) bool {
+	for _, operator := range []string{"$geoWithin", "$geoIntersects"} {
+		geo, ok := cond[operator].(bson.M)
+		if !ok {
+			continue
+		}
+		polygon, ok := geo["$geometry"].(Polygon)
+		pos, isPoint := loc.Location.Coordinates.([]float64)
+		if !ok || !isPoint || loc.Location.Type != "Point" || len(polygon.Coordinates) == 0 {
+			return false
+		}
+		if !pointInRing(pos, polygon.Coordinates[0]) {
+			return false
+		}
+		for _, hole := range polygon.Coordinates[1:] {
+			if pointInRing(pos, hole) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compareField membandingkan satu field sort dua lokasi: -1, 0, atau 1
+func compareField(a, b Location, key string) int {
+	switch key {
+	case "_id":
+		return cmp.Compare(a.ID.Hex(), b.ID.Hex())
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+	return 0
+}
+
+// newFakeLocations membuat n lokasi dengan created_at yang sama, seperti hasil bulk insert dalam satu milidetik
+func newFakeLocations(n int) *fakeLocationRepository {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &fakeLocationRepository{}
+	for range n {
+		repo.Create(context.Background(), Location{
+			ID:        primitive.NewObjectID(),
+			Name:      "Halte",
+			Location:  Geometry{Type: "Point", Coordinates: []float64{106.8, -6.2}},
+			CreatedAt: created,
+		})
+	}
+	return repo
+}
+
+func TestListPaginationTiebreaker(t *testing.T) {
+	const total, limit = 7, 3
+	repo := newFakeLocations(total)
+	ctx := context.Background()
+
+	want := make([]string, 0, total)
+	for _, loc := range repo.locations {
+		want = append(want, loc.ID.Hex())
+	}
+	slices.Sort(want)
+
+	t.Run("after cursor", func(t *testing.T) {
+		var got []string
+		after := primitive.NilObjectID
+		for range total {
+			filter := bson.M{}
+			sortSpec, skip := listPageQuery(filter, after, 1, 1, limit)
+			locations, skipped, err := repo.List(ctx, filter, sortSpec, limit, skip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, loc := range locations {
+				got = append(got, loc.ID.Hex())
+			}
+			next := nextListCursor(locations, skipped, limit)
+			if next == "" {
+				break
+			}
+			after, _ = primitive.ObjectIDFromHex(next)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("pages returned %v, want each location once in _id order %v", got, want)
+		}
+	})
+
+	t.Run("page number", func(t *testing.T) {
+		var got []string
+		for page := 1; page <= (total+limit-1)/limit; page++ {
+			filter := bson.M{}
+			sortSpec, skip := listPageQuery(filter, primitive.NilObjectID, 1, page, limit)
+			locations, _, err := repo.List(ctx, filter, sortSpec, limit, skip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, loc := range locations {
+				got = append(got, loc.ID.Hex())
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("pages returned %v, want each location once in _id order %v", got, want)
+		}
+	})
+
+	t.Run("descending", func(t *testing.T) {
+		filter := bson.M{}
+		last, _ := primitive.ObjectIDFromHex(want[len(want)-1])
+		sortSpec, _ := listPageQuery(filter, last, -1, 1, limit)
+		locations, _, err := repo.List(ctx, filter, sortSpec, limit, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, loc := range locations {
+			got = append(got, loc.ID.Hex())
+		}
+		if exp := []string{want[total-2], want[total-3], want[total-4]}; !slices.Equal(got, exp) {
+			t.Fatalf("descending page after %s = %v, want %v", last.Hex(), got, exp)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxResolveRefs adalah batas jumlah referensi dalam satu request resolve
+const maxResolveRefs = 500
+
+// resolveRequest adalah body request untuk POST /locations/resolve
+type resolveRequest struct {
+	Refs []string `json:"refs"`
+}
+
+// resolveLocationsHandler memetakan referensi campuran (ObjectID hex atau nama) ke lokasinya.
+// Semua ID dicari dalam satu query $in dan semua nama dalam satu query lain, berapa pun jumlah referensinya.
+// Belum ada field slug di model, jadi referensi yang bukan ObjectID dicocokkan ke name_normalized;
+// jika beberapa lokasi memiliki nama yang sama, lokasi paling lama yang dipakai.
+func (s *Server) resolveLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var req resolveRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if len(req.Refs) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "refs must not be empty")
+		return
+	}
+	if len(req.Refs) > maxResolveRefs {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d refs can be resolved per request", maxResolveRefs))
+		return
+	}
+
+	var ids []primitive.ObjectID
+	var names []string
+	for _, ref := range req.Refs {
+		if id, err := primitive.ObjectIDFromHex(ref); err == nil {
+			ids = append(ids, id)
+		} else if name := normalizeName(ref); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	byID := map[primitive.ObjectID]Location{}
+	if len(ids) > 0 {
+		found, err := s.findLocationsByIDs(ctx, ids)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		byID = found
+	}
+
+	byName := map[string]Location{}
+	var warns []string
+	if len(names) > 0 {
+		// Urut dari yang paling lama agar pilihan untuk nama yang sama selalu konsisten
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+		cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, bson.M{"name_normalized": bson.M{"$in": names}}), opts)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		locations, skipped, err := decodeLocations(ctx, cursor)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		warns = append(warns, skipped...)
+		ambiguous := map[string]bool{}
+		for _, loc := range locations {
+			if _, seen := byName[loc.NameNormalized]; seen {
+				if !ambiguous[loc.NameNormalized] {
+					ambiguous[loc.NameNormalized] = true
+					warns = append(warns, fmt.Sprintf("more than one location is named %q; the oldest was used", loc.Name))
+				}
+				continue
+			}
+			byName[loc.NameNormalized] = loc
+		}
+	}
+	setWarningHeaders(w, warns)
+
+	resolved := map[string]Location{}
+	unresolved := []string{}
+	seen := map[string]bool{}
+	for _, ref := range req.Refs {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		var loc Location
+		var ok bool
+		if id, err := primitive.ObjectIDFromHex(ref); err == nil {
+			loc, ok = byID[id]
+		} else {
+			loc, ok = byName[normalizeName(ref)]
+		}
+		if ok {
+			resolved[ref] = loc
+		} else {
+			unresolved = append(unresolved, ref)
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"resolved":   resolved,
+		"unresolved": unresolved,
+	})
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// wantsMsgpack mengecek apakah client meminta response MessagePack lewat header Accept
+func wantsMsgpack(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/msgpack") || strings.Contains(accept, "application/x-msgpack")
+}
+
+// largeResponseBytes adalah ambang ukuran response (byte) yang dicatat ke log; 0 berarti tidak dicatat
+var largeResponseBytes = 0
+
+// loadLargeResponseBytes membaca LARGE_RESPONSE_BYTES dari environment (kosong berarti tidak aktif)
+func loadLargeResponseBytes() {
+	raw := os.Getenv("LARGE_RESPONSE_BYTES")
+	if raw == "" {
+		return
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		fatal("LARGE_RESPONSE_BYTES must be a positive integer", "value", raw)
+	}
+	largeResponseBytes = n
+	slog.Info("large responses will be logged", "threshold_bytes", n)
+}
+
+// writeResponse menulis payload sebagai JSON (default) atau MessagePack jika diminta lewat header Accept.
+// Payload di-encode ke buffer dulu agar ukurannya bisa dilaporkan lewat header X-Response-Bytes.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, payload interface{}) {
+	w.Header().Add("Vary", "Accept")
+
+	var buf bytes.Buffer
+	if wantsMsgpack(r) {
+		w.Header().Set("Content-Type", "application/msgpack")
+		enc := msgpack.NewEncoder(&buf)
+		// Nama field mengikuti tag json agar bentuk data sama persis dengan response JSON
+		enc.SetCustomStructTag("json")
+		enc.Encode(payload)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(&buf).Encode(payload)
+	}
+
+	size := buf.Len()
+	w.Header().Set("X-Response-Bytes", strconv.Itoa(size))
+	if largeResponseBytes > 0 && size > largeResponseBytes {
+		requestLogger(r).Warn("large response", "bytes", size, "threshold_bytes", largeResponseBytes)
+	}
+
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// writeJSON menulis payload sebagai JSON dengan status code yang diberikan. Content-Type yang sudah diset
+// handler (misalnya application/geo+json) dipertahankan.
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// APIError adalah isi envelope error {"error":{...}} yang dipakai semua handler. Code stabil dan bisa
+// dipakai client untuk bercabang; Message untuk manusia dan boleh berubah. Details berisi data tambahan
+// seperti index item yang gagal.
+type APIError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// errorCodeForStatus menurunkan code default dari status HTTP, misalnya 404 menjadi "not_found"
+func errorCodeForStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// writeErrorDetails menulis envelope error lengkap dengan details
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, status, map[string]APIError{
+		"error": {Code: code, Message: message, Details: details},
+	})
+}
+
+// writeError menulis envelope error {"error":{"code":"...","message":"..."}}
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+// writeJSONError menulis envelope error dengan code yang diturunkan dari status; pakai writeError jika
+// client perlu membedakan penyebab dengan status yang sama
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeError(w, status, errorCodeForStatus(status), message)
+}
+
+// addLimitWarning mencatat peringatan saat nilai dari client dipangkas ke batas server, alih-alih ditolak
+func addLimitWarning(warns *[]string, format string, args ...interface{}) {
+	*warns = append(*warns, fmt.Sprintf(format, args...))
+}
+
+// setWarningHeaders menulis setiap peringatan sebagai header Warning (RFC 7234, kode 299)
+func setWarningHeaders(w http.ResponseWriter, warns []string) {
+	for _, msg := range warns {
+		w.Header().Add("Warning", fmt.Sprintf("299 - %q", msg))
+	}
+}
+
+// writeDBError menulis error operasi database sebagai 500, 504 jika batas waktu terlampaui, atau 422 jika
+// dokumen tidak bisa di-decode. Jika request dibatalkan karena client sudah memutus koneksi,
+// response tidak ditulis sama sekali karena tidak ada yang akan menerimanya.
+func writeDBError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled) {
+		requestLogger(r).Debug("cancelled by client", "error", err)
+		return
+	}
+	var decodeErr *decodeError
+	if errors.As(err, &decodeErr) {
+		requestLogger(r).Error("stored location cannot be decoded", "error", err)
+		writeErrorDetails(w, http.StatusUnprocessableEntity, "undecodable_document",
+			"Stored location has an unexpected shape and cannot be read", map[string]interface{}{"id": decodeErr.ID})
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err) {
+		requestLogger(r).Error("database operation timed out", "error", err)
+		writeError(w, http.StatusGatewayTimeout, "timeout", "Database operation timed out")
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeError(w, http.StatusNotFound, "not_found", "Location not found")
+		return
+	}
+	// Pesan driver bisa berisi detail internal (host, nama koleksi), jadi hanya dicatat di log
+	requestLogger(r).Error("database operation failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "internal_error", "Internal database error")
+}
+
+// isAmbiguousWriteError mengecek apakah error write berupa timeout atau write concern error,
+// yaitu kasus di mana write mungkin sudah (sebagian) diterapkan di server
+func isAmbiguousWriteError(err error) bool {
+	if mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var we mongo.WriteException
+	if errors.As(err, &we) && we.WriteConcernError != nil {
+		return true
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && bwe.WriteConcernError != nil {
+		return true
+	}
+	return false
+}
+
+// ambiguousWriteAccepted membaca WRITE_TIMEOUT_ACCEPTED; default aktif, "false" mengembalikan perilaku 500
+func ambiguousWriteAccepted() bool {
+	return os.Getenv("WRITE_TIMEOUT_ACCEPTED") != "false"
+}
+
+// writeWriteError seperti writeDBError, tetapi untuk operasi write yang timeout atau gagal memenuhi
+// write concern mengembalikan 202 dengan peringatan agar client memverifikasi hasilnya
+func (s *Server) writeWriteError(w http.ResponseWriter, r *http.Request, err error) {
+	// Index unique selain _id adalah name dan external_id
+	if isDuplicateExternalID(err) {
+		writeError(w, http.StatusConflict, "duplicate_external_id", "A location with this external_id already exists")
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		writeError(w, http.StatusConflict, "duplicate_name", "A location with this name already exists")
+		return
+	}
+	if !isAmbiguousWriteError(err) || !ambiguousWriteAccepted() || errors.Is(r.Context().Err(), context.Canceled) {
+		writeDBError(w, r, err)
+		return
+	}
+	requestLogger(r).Warn("write outcome unknown", "error", err)
+	// Write mungkin sudah diterapkan; versi dinaikkan agar client yang menyimpan cache mengambil ulang.
+	// Context request bisa jadi sudah habis, jadi dipakai context tersendiri.
+	bumpCtx, cancel := context.WithTimeout(s.ctx, config.RequestTimeout)
+	defer cancel()
+	s.bumpCollectionVersion(bumpCtx)
+	setWarningHeaders(w, []string{"the write may have been partially applied; verify the resource before retrying"})
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status":  "accepted",
+		"message": "Write outcome unknown; it may have been partially applied",
+	})
+}
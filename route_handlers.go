@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultRouteBufferMeters adalah lebar buffer default (meter) di kiri-kanan rute
+	defaultRouteBufferMeters = 200
+	// maxRouteBufferMeters adalah lebar buffer maksimum (meter)
+	maxRouteBufferMeters = 5000
+	// maxRoutePositions adalah jumlah posisi maksimum pada LineString rute
+	maxRoutePositions = 1000
+	// maxRouteCandidates membatasi jumlah lokasi di dalam bbox rute yang dihitung jaraknya ke rute
+	maxRouteCandidates = 20000
+)
+
+// alongRouteRequest adalah body request untuk POST /locations/along-route
+type alongRouteRequest struct {
+	Route        LineString `json:"route"`
+	BufferMeters float64    `json:"bufferMeters"`
+}
+
+// LocationAlongRoute adalah lokasi di dekat rute beserta posisinya relatif terhadap rute
+type LocationAlongRoute struct {
+	Location
+	DistanceFromRoute  float64 `json:"distanceFromRoute"`
+	DistanceAlongRoute float64 `json:"distanceAlongRoute"`
+}
+
+// routeBBox menghitung bbox rute yang diperlebar sebesar buffer (meter), dipangkas ke rentang koordinat valid
+func routeBBox(line [][]float64, bufferMeters float64) BBox {
+	b := BBox{West: 180, South: 90, East: -180, North: -90}
+	for _, pos := range line {
+		b.West, b.East = math.Min(b.West, pos[0]), math.Max(b.East, pos[0])
+		b.South, b.North = math.Min(b.South, pos[1]), math.Max(b.North, pos[1])
+	}
+
+	padLat := bufferMeters / metersPerDegree
+	// Derajat bujur makin pendek ke arah kutub, jadi padding bujur memakai lintang terjauh dari ekuator
+	maxAbsLat := math.Min(89, math.Max(math.Abs(b.South), math.Abs(b.North))+padLat)
+	padLng := bufferMeters / (metersPerDegree * math.Cos(maxAbsLat*math.Pi/180))
+
+	b.West, b.East = math.Max(-180, b.West-padLng), math.Min(180, b.East+padLng)
+	b.South, b.North = math.Max(-90, b.South-padLat), math.Min(90, b.North+padLat)
+	return b
+}
+
+// alongRouteHandler mengembalikan lokasi yang berada dalam buffer di sekitar LineString,
+// diurutkan berdasarkan posisinya di sepanjang rute (dari titik awal ke titik akhir). Paling banyak
+// maxRouteCandidates lokasi di dalam bbox rute yang diperiksa, dan hasilnya dibatasi query param limit
+// seperti GET /locations/within; keduanya diberi Warning jika terpotong.
+func (s *Server) alongRouteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var req alongRouteRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	if err := validateLineString(req.Route); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Route.Coordinates) > maxRoutePositions {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("route is limited to %d positions", maxRoutePositions))
+		return
+	}
+
+	var warns []string
+	limit, err := parseAreaLimit(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	buffer := req.BufferMeters
+	switch {
+	case buffer < 0:
+		writeJSONError(w, http.StatusBadRequest, "bufferMeters must be a positive number")
+		return
+	case buffer == 0:
+		buffer = defaultRouteBufferMeters
+	case buffer > maxRouteBufferMeters:
+		addLimitWarning(&warns, "bufferMeters was reduced from %g to the server maximum of %d", buffer, maxRouteBufferMeters)
+		buffer = maxRouteBufferMeters
+	}
+
+	// Kandidat diambil dengan query bbox (index-backed), lalu disaring dengan jarak sebenarnya ke rute di Go
+	filter := withoutDeleted(ctx, routeBBox(req.Route.Coordinates, buffer).geoWithinFilter())
+	cursor, err := s.collection.Find(ctx, filter, options.Find().SetLimit(maxRouteCandidates+1))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	candidates, skipped, err := decodeLocations(ctx, cursor)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	warns = append(warns, skipped...)
+	if len(candidates) > maxRouteCandidates {
+		candidates = candidates[:maxRouteCandidates]
+		addLimitWarning(&warns, "only %d locations around the route were considered, use a shorter route or a narrower buffer", maxRouteCandidates)
+	}
+
+	results := []LocationAlongRoute{}
+	for _, loc := range candidates {
+		pos, ok := loc.Location.anchorPosition()
+		if !ok {
+			continue
+		}
+		dist, along := projectOntoLine(pos, req.Route.Coordinates)
+		if dist <= buffer {
+			results = append(results, LocationAlongRoute{Location: loc, DistanceFromRoute: dist, DistanceAlongRoute: along})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].DistanceAlongRoute < results[j].DistanceAlongRoute
+	})
+	if len(results) > limit {
+		results = results[:limit]
+		addLimitWarning(&warns, "results were truncated to the first %d locations along the route", limit)
+	}
+	setWarningHeaders(w, warns)
+
+	writeResponse(w, r, http.StatusOK, results)
+}
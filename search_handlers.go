@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultAutocompleteLimit adalah jumlah saran default pada endpoint autocomplete
+	defaultAutocompleteLimit = 10
+	// maxAutocompleteLimit adalah jumlah saran maksimum pada endpoint autocomplete
+	maxAutocompleteLimit = 50
+)
+
+// NameSuggestion adalah satu saran autocomplete
+type NameSuggestion struct {
+	ID   primitive.ObjectID `bson:"_id" json:"id"`
+	Name string             `bson:"name" json:"name"`
+}
+
+// normalizeName mengubah nama menjadi huruf kecil dengan spasi yang dirapikan
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// autocompleteHandler mengembalikan pasangan {id, name} yang namanya diawali q, untuk kebutuhan type-ahead.
+// Prefix regex yang di-anchor pada name_normalized bisa memakai index, berbeda dengan pencarian full text.
+func (s *Server) autocompleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	prefix := normalizeName(r.URL.Query().Get("q"))
+	if prefix == "" {
+		writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	var warns []string
+	limit := defaultAutocompleteLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if n > maxAutocompleteLimit {
+			addLimitWarning(&warns, "limit was reduced from %d to the server maximum of %d", n, maxAutocompleteLimit)
+			n = maxAutocompleteLimit
+		}
+		limit = n
+	}
+	setWarningHeaders(w, warns)
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, bson.M{"name_normalized": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}})},
+		bson.M{"$addFields": bson.M{"nameLength": bson.M{"$strLenCP": "$name_normalized"}}},
+		bson.M{"$sort": bson.D{{Key: "nameLength", Value: 1}, {Key: "name_normalized", Value: 1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{"name": 1}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	suggestions := []NameSuggestion{}
+	if err = cursor.All(ctx, &suggestions); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, suggestions)
+}
+
+// SearchResult adalah lokasi hasil pencarian teks; Score hanya diisi jika hasil diurutkan menurut relevansi
+type SearchResult struct {
+	Location `bson:",inline"`
+	Score    float64 `bson:"score,omitempty" json:"score,omitempty"`
+}
+
+// errCodeIndexNotFound adalah kode error MongoDB saat index yang dibutuhkan (index text untuk $text, atau
+// index yang akan dihapus) tidak ada
+const errCodeIndexNotFound = 27
+
+// isTextIndexMissing melaporkan apakah query $text gagal karena index text belum selesai dibuat
+func isTextIndexMissing(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCode(errCodeIndexNotFound)
+}
+
+// searchLocationsHandler mencari lokasi yang name atau description-nya memuat kata pada q memakai index text.
+// Dengan sort=score hasil diurutkan menurut relevansi ($meta textScore); tanpa itu urut _id agar paginasi stabil.
+func (s *Server) searchLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	byScore := false
+	switch r.URL.Query().Get("sort") {
+	case "":
+	case "score":
+		byScore = true
+	default:
+		writeJSONError(w, http.StatusBadRequest, "sort must be score")
+		return
+	}
+
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64((page - 1) * limit))
+	if byScore {
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+			SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}})
+	} else {
+		opts.SetSort(bson.M{"_id": 1})
+	}
+	cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, bson.M{"$text": bson.M{"$search": q}}), opts)
+	if err != nil {
+		if isTextIndexMissing(err) {
+			writeJSONError(w, http.StatusServiceUnavailable, "Text index is not available yet, try again later")
+			return
+		}
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	results := []SearchResult{}
+	if err = cursor.All(ctx, &results); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, results)
+}
+
+// caseInsensitiveCollation membandingkan string tanpa membedakan huruf besar/kecil (strength 2)
+var caseInsensitiveCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// checkNameHandler mengecek apakah sebuah nama masih tersedia (belum dipakai lokasi lain, tanpa membedakan huruf besar/kecil)
+func (s *Server) checkNameHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		writeJSONError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	// Cukup ambil _id agar query tetap ringan
+	findOptions := options.FindOne().
+		SetProjection(bson.M{"_id": 1}).
+		SetCollation(caseInsensitiveCollation)
+	err := s.collection.FindOne(ctx, forTenant(ctx, bson.M{"name": name}), findOptions).Err()
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"name":      name,
+		"available": errors.Is(err, mongo.ErrNoDocuments),
+	})
+}
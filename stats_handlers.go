@@ -0,0 +1,402 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// defaultStatsDays adalah jumlah hari default untuk statistik harian
+	defaultStatsDays = 30
+	// maxStatsDays adalah batas maksimum jumlah hari untuk statistik harian
+	maxStatsDays = 366
+)
+
+// DailyCount adalah jumlah lokasi yang dibuat pada satu hari (dalam timezone yang diminta)
+type DailyCount struct {
+	Day   string `bson:"_id" json:"day"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// parseTimezone membaca query param tz dan memvalidasinya, default UTC
+func parseTimezone(r *http.Request) (*time.Location, bool) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return time.UTC, true
+	}
+	// "Local" hanya bermakna bagi proses Go, MongoDB tidak mengenalinya
+	if tz == "Local" {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
+
+// parseStatsDays membaca query param days, memakai defaultStatsDays jika kosong dan memangkasnya ke maxStatsDays
+func parseStatsDays(r *http.Request, warns *[]string) (int, error) {
+	raw := r.URL.Query().Get("days")
+	if raw == "" {
+		return defaultStatsDays, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, errors.New("days must be a positive integer")
+	}
+	if n > maxStatsDays {
+		addLimitWarning(warns, "days was reduced from %d to the server maximum of %d", n, maxStatsDays)
+		n = maxStatsDays
+	}
+	return n, nil
+}
+
+// dailyCountStages mengelompokkan lokasi yang dibuat dalam days hari terakhir per hari di timezone tz.
+// Awal rentang dihitung dari tengah malam di timezone yang diminta, bukan UTC.
+func dailyCountStages(tz *time.Location, days int) bson.A {
+	now := time.Now().In(tz)
+	since := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, tz)
+	return bson.A{
+		bson.M{"$match": bson.M{"created_at": bson.M{"$gte": since}}},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   "%Y-%m-%d",
+				"date":     "$created_at",
+				"timezone": tz.String(),
+			}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+}
+
+// dailyStatsHandler mengembalikan jumlah lokasi yang dibuat per hari, dengan batas hari mengikuti timezone tz
+func (s *Server) dailyStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	tz, ok := parseTimezone(r)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Unknown timezone in tz parameter")
+		return
+	}
+
+	var warns []string
+	days, err := parseStatsDays(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	pipeline := bson.A{bson.M{"$match": withoutDeleted(ctx, nil)}}
+	pipeline = append(pipeline, dailyCountStages(tz, days)...)
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	stats := []DailyCount{}
+	if err = cursor.All(ctx, &stats); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, stats)
+}
+
+// LatestInCategory adalah lokasi terbaru dalam satu kategori
+type LatestInCategory struct {
+	Category string   `bson:"_id" json:"category"`
+	Location Location `bson:"location" json:"location"`
+}
+
+// latestByCategoryHandler mengembalikan lokasi yang paling baru dibuat untuk setiap kategori
+func (s *Server) latestByCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	// $sort harus sebelum $group agar $first benar-benar mengambil dokumen terbaru;
+	// _id sebagai tie-breaker supaya hasil stabil untuk created_at yang sama
+	pipeline := bson.A{
+		bson.M{"$match": withoutDeleted(ctx, nil)},
+		bson.M{"$sort": bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		bson.M{"$group": bson.M{
+			"_id":      bson.M{"$ifNull": bson.A{"$category", "uncategorized"}},
+			"location": bson.M{"$first": "$$ROOT"},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	latest := []LatestInCategory{}
+	if err = cursor.All(ctx, &latest); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, latest)
+}
+
+// categoryCentroidsTTL adalah lama hasil centroid per kategori disimpan di memori sebelum dihitung ulang
+const categoryCentroidsTTL = time.Minute
+
+// CategoryCentroid adalah titik rata-rata semua lokasi Point dalam satu kategori
+type CategoryCentroid struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+	Centroid Point  `json:"centroid"`
+}
+
+// categoryCentroidsCache menyimpan hasil terakhir categoryCentroidsHandler per tenant beserta waktu kedaluwarsanya
+type categoryCentroidsCache struct {
+	sync.Mutex
+	entries map[string]categoryCentroidsEntry
+}
+
+// categoryCentroidsEntry adalah hasil centroid satu tenant
+type categoryCentroidsEntry struct {
+	centroids []CategoryCentroid
+	expires   time.Time
+}
+
+// categoryCentroidsHandler mengembalikan centroid (rata-rata lng/lat) lokasi per kategori untuk label peta.
+// Hasil di-cache selama categoryCentroidsTTL karena jarang berubah.
+// Catatan: rata-rata aritmetika tidak benar untuk kategori yang titiknya melintasi antimeridian (±180°);
+// misalnya titik di 179° dan -179° menghasilkan centroid di 0°, bukan di sekitar 180°.
+func (s *Server) categoryCentroidsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	tenant := tenantFromContext(ctx)
+	cache := &s.centroids
+	cache.Lock()
+	if entry, ok := cache.entries[tenant]; ok && time.Now().Before(entry.expires) {
+		centroids := entry.centroids
+		cache.Unlock()
+		w.Header().Set("X-Cache", "HIT")
+		writeResponse(w, r, http.StatusOK, centroids)
+		return
+	}
+	cache.Unlock()
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, bson.M{"location.type": "Point"})},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$category", "uncategorized"}},
+			"count": bson.M{"$sum": 1},
+			"lng":   bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}},
+			"lat":   bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	})
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Category string  `bson:"_id"`
+		Count    int64   `bson:"count"`
+		Lng      float64 `bson:"lng"`
+		Lat      float64 `bson:"lat"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	centroids := make([]CategoryCentroid, 0, len(rows))
+	for _, row := range rows {
+		centroids = append(centroids, CategoryCentroid{
+			Category: row.Category,
+			Count:    row.Count,
+			Centroid: Point{Type: "Point", Coordinates: []float64{row.Lng, row.Lat}},
+		})
+	}
+
+	cache.Lock()
+	if cache.entries == nil {
+		cache.entries = map[string]categoryCentroidsEntry{}
+	}
+	cache.entries[tenant] = categoryCentroidsEntry{centroids: centroids, expires: time.Now().Add(categoryCentroidsTTL)}
+	cache.Unlock()
+
+	w.Header().Set("X-Cache", "MISS")
+	writeResponse(w, r, http.StatusOK, centroids)
+}
+
+// countLocationsHandler mengembalikan jumlah lokasi dengan filter yang sama seperti GET /locations.
+// Regex nama tanpa anchor tidak bisa memakai index, jadi filter nama tetap men-scan koleksi.
+func (s *Server) countLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseListFilter(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	count, err := s.locations.Count(ctx, filter)
+	if err != nil {
+		if isTextIndexMissing(err) {
+			writeJSONError(w, http.StatusServiceUnavailable, "Text index is not available yet, try again later")
+			return
+		}
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]int64{"count": count})
+}
+
+// maxStatsTags membatasi jumlah tag pada GET /locations/stats; tag lain tetap terhitung di total
+const maxStatsTags = 100
+
+// TagCount adalah jumlah lokasi yang memiliki satu tag
+type TagCount struct {
+	Tag   string `bson:"_id" json:"tag"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// LocationStats adalah ringkasan koleksi dari GET /locations/stats. BBox nil jika koleksi kosong.
+type LocationStats struct {
+	Total      int64           `json:"total"`
+	Categories []CategoryCount `json:"categories"`
+	Tags       []TagCount      `json:"tags"`
+	Daily      []DailyCount    `json:"daily"`
+	BBox       *BBox           `json:"bbox"`
+}
+
+// positionsExpr mengubah geometri location menjadi array posisi [lng, lat]: titiknya sendiri untuk Point,
+// semua vertex untuk LineString, dan vertex semua ring untuk Polygon
+var positionsExpr = bson.M{"$switch": bson.M{
+	"branches": bson.A{
+		bson.M{"case": bson.M{"$eq": bson.A{"$location.type", "Point"}}, "then": bson.A{"$location.coordinates"}},
+		bson.M{"case": bson.M{"$eq": bson.A{"$location.type", "LineString"}}, "then": "$location.coordinates"},
+		bson.M{"case": bson.M{"$eq": bson.A{"$location.type", "Polygon"}}, "then": bson.M{"$reduce": bson.M{
+			"input":        "$location.coordinates",
+			"initialValue": bson.A{},
+			"in":           bson.M{"$concatArrays": bson.A{"$$value", "$$this"}},
+		}}},
+	},
+	"default": bson.A{},
+}}
+
+// locationStatsHandler mengembalikan total lokasi, jumlah per kategori dan per tag, jumlah yang dibuat per hari
+// selama days hari terakhir (mengikuti tz), serta bounding box semua geometri, dalam satu aggregation $facet
+func (s *Server) locationStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	tz, ok := parseTimezone(r)
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "Unknown timezone in tz parameter")
+		return
+	}
+	var warns []string
+	days, err := parseStatsDays(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	setWarningHeaders(w, warns)
+
+	pipeline := bson.A{
+		bson.M{"$match": withoutDeleted(ctx, nil)},
+		bson.M{"$facet": bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"categories": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$ifNull": bson.A{"$category", "uncategorized"}},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+			"tags": bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": maxStatsTags},
+			},
+			"daily": dailyCountStages(tz, days),
+			"bbox": bson.A{
+				bson.M{"$project": bson.M{"position": positionsExpr}},
+				bson.M{"$unwind": "$position"},
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"west":  bson.M{"$min": bson.M{"$arrayElemAt": bson.A{"$position", 0}}},
+					"south": bson.M{"$min": bson.M{"$arrayElemAt": bson.A{"$position", 1}}},
+					"east":  bson.M{"$max": bson.M{"$arrayElemAt": bson.A{"$position", 0}}},
+					"north": bson.M{"$max": bson.M{"$arrayElemAt": bson.A{"$position", 1}}},
+				}},
+			},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Categories []CategoryCount `bson:"categories"`
+		Tags       []TagCount      `bson:"tags"`
+		Daily      []DailyCount    `bson:"daily"`
+		BBox       []struct {
+			West  float64 `bson:"west"`
+			South float64 `bson:"south"`
+			East  float64 `bson:"east"`
+			North float64 `bson:"north"`
+		} `bson:"bbox"`
+	}
+	if err = cursor.All(ctx, &facets); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	// $facet selalu menghasilkan tepat satu dokumen, tetapi setiap facet bisa kosong
+	stats := LocationStats{Categories: []CategoryCount{}, Tags: []TagCount{}, Daily: []DailyCount{}}
+	if len(facets) == 1 {
+		f := facets[0]
+		if len(f.Total) > 0 {
+			stats.Total = f.Total[0].Count
+		}
+		if f.Categories != nil {
+			stats.Categories = f.Categories
+		}
+		if f.Tags != nil {
+			stats.Tags = f.Tags
+		}
+		if f.Daily != nil {
+			stats.Daily = f.Daily
+		}
+		if len(f.BBox) > 0 {
+			b := f.BBox[0]
+			stats.BBox = &BBox{West: b.West, South: b.South, East: b.East, North: b.North}
+		}
+	}
+
+	writeResponse(w, r, http.StatusOK, stats)
+}
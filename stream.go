@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// streamHeartbeatInterval adalah jeda maksimum tanpa data sebelum komentar SSE dikirim, agar proxy
+	// tidak menutup koneksi yang sedang menganggur
+	streamHeartbeatInterval = 15 * time.Second
+	// maxStreamClients membatasi jumlah stream yang terbuka bersamaan, karena setiap stream memegang
+	// satu change stream dan satu koneksi dari pool selama getMore menunggu
+	maxStreamClients = 100
+	// errCodeChangeStreamNotSupported adalah kode error MongoDB saat change stream dibuka di server standalone
+	errCodeChangeStreamNotSupported = 40573
+)
+
+// streamClients adalah jumlah client GET /locations/stream yang sedang terhubung
+var streamClients atomic.Int64
+
+// LocationEvent adalah satu perubahan lokasi yang dikirim ke client. Location kosong untuk event delete.
+type LocationEvent struct {
+	Type     string    `json:"type"`
+	ID       string    `json:"id"`
+	Location *Location `json:"location,omitempty"`
+}
+
+// changeEvent adalah bagian change event MongoDB yang dipakai untuk membentuk LocationEvent
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *Location `bson:"fullDocument"`
+}
+
+// toLocationEvent memetakan change event ke LocationEvent. Soft delete tiba sebagai update yang mengisi
+// deleted_at, sehingga dilaporkan sebagai delete; restore tiba sebagai update biasa.
+func (e changeEvent) toLocationEvent() LocationEvent {
+	evt := LocationEvent{Type: e.OperationType, ID: e.DocumentKey.ID.Hex()}
+	switch e.OperationType {
+	case "insert", "update", "replace":
+		// fullDocument nil berarti dokumen sudah dihapus permanen sebelum lookup berjalan
+		if e.FullDocument == nil || e.FullDocument.DeletedAt != nil {
+			evt.Type = "delete"
+			return evt
+		}
+		if e.OperationType == "replace" {
+			evt.Type = "update"
+		}
+		evt.Location = e.FullDocument
+	}
+	return evt
+}
+
+// watchLocations membuka change stream perubahan lokasi milik tenant request, dilanjutkan setelah resumeToken
+// jika tidak kosong. Dipakai bersama oleh GET /locations/stream dan WatchLocations gRPC.
+func (s *Server) watchLocations(ctx context.Context, resumeToken string) (*mongo.ChangeStream, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}},
+	}
+	// Delete permanen (purge dan TTL) tidak membawa fullDocument sehingga tidak bisa dikaitkan ke tenant;
+	// dengan multi-tenancy event tersebut tidak dikirim. Lokasi yang di-purge sudah dilaporkan sebagai delete
+	// saat masuk trash.
+	if multiTenantEnabled() {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"fullDocument.tenant_id": tenantValue(ctx)}})
+	}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetMaxAwaitTime(streamHeartbeatInterval)
+	if resumeToken != "" {
+		opts.SetResumeAfter(bson.M{"_data": resumeToken})
+	}
+	return s.collection.Watch(ctx, pipeline, opts)
+}
+
+// streamLocationsHandler mengirim perubahan lokasi (insert, update, delete) sebagai Server-Sent Events dari
+// MongoDB change stream, sebagai pengganti polling GET /locations. ID setiap event adalah resume token, sehingga
+// EventSource yang tersambung ulang dengan Last-Event-ID melanjutkan tanpa kehilangan event.
+// Change stream membutuhkan replica set; pada server standalone endpoint ini mengembalikan 503.
+func (s *Server) streamLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+
+	if streamClients.Add(1) > maxStreamClients {
+		streamClients.Add(-1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "30")
+		writeJSONError(w, http.StatusServiceUnavailable, "Too many open streams, try again later")
+		return
+	}
+	defer streamClients.Add(-1)
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	stream, err := s.watchLocations(ctx, lastEventID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		var se mongo.ServerError
+		switch {
+		case errors.As(err, &se) && se.HasErrorCode(errCodeChangeStreamNotSupported):
+			writeJSONError(w, http.StatusServiceUnavailable, "Change streams require MongoDB to run as a replica set")
+		case lastEventID != "" && errors.As(err, &se):
+			// Token yang rusak atau sudah keluar dari oplog tidak bisa dilanjutkan; client harus memuat ulang data
+			writeJSONError(w, http.StatusBadRequest, "Last-Event-ID cannot be resumed, reload the locations and reconnect without it")
+		default:
+			writeDBError(w, r, err)
+		}
+		return
+	}
+	defer stream.Close(ctx)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// Komentar awal membuat header langsung terkirim sehingga client tahu stream sudah terbuka
+	fmt.Fprint(w, ": connected\n\n")
+	rc.Flush()
+
+	log := requestLogger(r)
+	for {
+		// TryNext menunggu paling lama streamHeartbeatInterval (MaxAwaitTime) sebelum kembali tanpa event
+		if !stream.TryNext(ctx) {
+			if err := stream.Err(); err != nil {
+				if ctx.Err() == nil {
+					log.Error("change stream failed", "error", err)
+				}
+				return
+			}
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			rc.Flush()
+			continue
+		}
+
+		var change changeEvent
+		if err := stream.Decode(&change); err != nil {
+			log.Warn("skipping change event that cannot be decoded", "error", err)
+			continue
+		}
+		evt := change.toLocationEvent()
+		data, err := json.Marshal(evt)
+		if err != nil {
+			log.Error("encode change event failed", "error", err)
+			continue
+		}
+		token, _ := stream.ResumeToken().Lookup("_data").StringValueOK()
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", token, evt.Type, data); err != nil {
+			return
+		}
+		rc.Flush()
+	}
+}
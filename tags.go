@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// maxTagsPerLocation adalah jumlah tag maksimum pada satu lokasi
+	maxTagsPerLocation = 20
+	// maxTagLength adalah panjang maksimum satu tag dalam karakter
+	maxTagLength = 50
+)
+
+// normalizeTags mengubah tag menjadi huruf kecil tanpa spasi di tepi dan membuang duplikat serta tag kosong,
+// dengan urutan kemunculan pertama dipertahankan. Hasilnya nil jika tidak ada tag tersisa agar field tidak disimpan.
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// validateTags memeriksa jumlah dan panjang tag yang sudah dinormalisasi
+func validateTags(tags []string) error {
+	if len(tags) > maxTagsPerLocation {
+		return fmt.Errorf("at most %d tags are allowed", maxTagsPerLocation)
+	}
+	for _, tag := range tags {
+		if len([]rune(tag)) > maxTagLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+	}
+	return nil
+}
+
+// parseTagsFilter membaca ?tags=cafe,wifi dan ?tags_mode=all|any. Default all: lokasi harus memiliki semua tag;
+// any cukup salah satunya. Mengembalikan nil jika tags tidak diisi.
+func parseTagsFilter(r *http.Request) (bson.M, error) {
+	q := r.URL.Query()
+	tags := normalizeTags(strings.Split(q.Get("tags"), ","))
+	mode := q.Get("tags_mode")
+	if mode != "" && mode != "all" && mode != "any" {
+		return nil, errors.New("tags_mode must be either all or any")
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if len(tags) > maxTagsPerLocation {
+		return nil, fmt.Errorf("tags accepts at most %d values", maxTagsPerLocation)
+	}
+	if mode == "any" {
+		return bson.M{"$in": tags}, nil
+	}
+	return bson.M{"$all": tags}, nil
+}
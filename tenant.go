@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxTenantIDLength membatasi panjang ID tenant yang disimpan di setiap dokumen
+const maxTenantIDLength = 64
+
+// tenantKey adalah key context untuk ID tenant request
+type tenantKey struct{}
+
+// multiTenantEnabled aktif jika TENANT_API_KEYS diset. Tanpanya semua request berbagi satu data set,
+// persis seperti sebelum ada tenant.
+func multiTenantEnabled() bool {
+	return len(config.TenantKeys) > 0
+}
+
+// validTenantID menerima ID tenant yang pendek dan hanya berisi huruf kecil, angka, - dan _
+func validTenantID(id string) bool {
+	if id == "" || len(id) > maxTenantIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_')
+	}) < 0
+}
+
+// parseTenantKeys membaca TENANT_API_KEYS berformat "tenant:key,tenant:key" menjadi map key ke tenant.
+// Satu tenant boleh punya beberapa key (untuk rotasi), tetapi satu key hanya boleh milik satu tenant.
+func parseTenantKeys(items []string) (map[string]string, []string) {
+	keys := map[string]string{}
+	var problems []string
+	for _, item := range items {
+		tenant, key, ok := strings.Cut(item, ":")
+		tenant, key = strings.TrimSpace(tenant), strings.TrimSpace(key)
+		switch {
+		case !ok || key == "":
+			problems = append(problems, fmt.Sprintf("TENANT_API_KEYS entries must look like tenant:key, got %q", tenant))
+		case !validTenantID(tenant):
+			problems = append(problems, fmt.Sprintf("TENANT_API_KEYS tenant IDs must be 1-%d lowercase letters, digits, - or _, got %q", maxTenantIDLength, tenant))
+		case keys[key] != "" && keys[key] != tenant:
+			problems = append(problems, fmt.Sprintf("TENANT_API_KEYS assigns the same key to tenants %q and %q", keys[key], tenant))
+		default:
+			keys[key] = tenant
+		}
+	}
+	return keys, problems
+}
+
+// tenantForKey mengembalikan tenant pemilik API key. Semua key dibandingkan dengan waktu konstan agar
+// key yang valid tidak bisa ditebak dari lama respons.
+func tenantForKey(key string) (string, bool) {
+	var found string
+	for candidate, tenant := range config.TenantKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			found = tenant
+		}
+	}
+	return found, found != ""
+}
+
+// jwtTenantClaim adalah klaim JWT yang menentukan tenant pemegang token
+const jwtTenantClaim = "tenant_id"
+
+// trustedTenantCaller memeriksa apakah request boleh memilih tenant sendiri lewat X-Tenant-ID: hanya
+// pemegang API_KEY atau X-Admin-Key (misalnya backend atau gateway milik operator). Pemegang JWT tidak
+// termasuk, karena token juga diberikan ke pengguna akhir.
+func trustedTenantCaller(r *http.Request) bool {
+	if key := r.Header.Get("X-API-Key"); key != "" && config.APIKey != "" &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(config.APIKey)) == 1 {
+		return true
+	}
+	return validAdminKey(r)
+}
+
+// jwtTenant mengembalikan tenant dari klaim tenant_id bearer token yang valid. ok false berarti request tidak
+// membawa JWT yang valid; tenant kosong dengan ok true berarti token tidak menyebut tenant.
+func jwtTenant(r *http.Request) (string, bool) {
+	token, ok := bearerToken(r)
+	if !ok || config.JWTSecret == "" {
+		return "", false
+	}
+	claims, err := parseJWTClaims(token, config.JWTSecret)
+	if err != nil {
+		return "", false
+	}
+	tenant, _ := claims[jwtTenantClaim].(string)
+	return tenant, true
+}
+
+// resolveTenant menentukan tenant request dari API key tenant, dari klaim tenant_id JWT, atau dari
+// X-Tenant-ID untuk caller yang dipercaya. Tenant kosong tanpa error berarti request tidak menyebut tenant sama sekali.
+func resolveTenant(r *http.Request) (string, int, error) {
+	header := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
+	if tenant, ok := tenantForKey(r.Header.Get("X-API-Key")); ok {
+		if header != "" && header != tenant {
+			return "", http.StatusForbidden, fmt.Errorf("X-Tenant-ID does not match the tenant of the API key")
+		}
+		return tenant, 0, nil
+	}
+	if trustedTenantCaller(r) {
+		if header != "" && !validTenantID(header) {
+			return "", http.StatusBadRequest, fmt.Errorf("X-Tenant-ID must be 1-%d lowercase letters, digits, - or _", maxTenantIDLength)
+		}
+		return header, 0, nil
+	}
+	// Tenant pemegang JWT hanya berasal dari klaim yang ditandatangani, tidak pernah dari header
+	if tenant, ok := jwtTenant(r); ok {
+		if tenant != "" && !validTenantID(tenant) {
+			return "", http.StatusForbidden, fmt.Errorf("the %s claim of the bearer token is not a valid tenant ID", jwtTenantClaim)
+		}
+		if header != "" && header != tenant {
+			return "", http.StatusForbidden, fmt.Errorf("X-Tenant-ID does not match the %s claim of the bearer token", jwtTenantClaim)
+		}
+		return tenant, 0, nil
+	}
+	if header != "" {
+		return "", http.StatusUnauthorized, fmt.Errorf("X-Tenant-ID is only accepted together with API_KEY or X-Admin-Key")
+	}
+	return "", 0, nil
+}
+
+// tenantMiddleware menyimpan tenant request di context bila multi-tenancy aktif. Setiap request wajib
+// menyebut tenant, kecuali endpoint /admin/ yang tanpa X-Tenant-ID hanya melihat data tanpa tenant
+// (data dari sebelum multi-tenancy diaktifkan).
+func tenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !multiTenantEnabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenant, status, err := resolveTenant(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			writeError(w, status, "invalid_tenant", err.Error())
+			return
+		}
+		if tenant == "" && !strings.Contains(r.URL.Path, "/admin/") {
+			w.Header().Set("Content-Type", "application/json")
+			writeError(w, http.StatusUnauthorized, "tenant_required", "Missing tenant API key in X-API-Key, tenant_id claim in the bearer token, or X-Tenant-ID header")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+	})
+}
+
+// tenantFromContext mengembalikan tenant request, atau string kosong jika tidak ada
+func tenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantValue adalah nilai tenant_id untuk filter: tanpa tenant dibandingkan dengan null, sehingga
+// dokumen tanpa field tenant_id cocok dan index yang diawali tenant_id tetap terpakai
+func tenantValue(ctx context.Context) interface{} {
+	if tenant := tenantFromContext(ctx); tenant != "" {
+		return tenant
+	}
+	return nil
+}
+
+// forTenant menyalin filter lalu membatasinya ke tenant request. tenant_id dari filter asal selalu ditimpa,
+// agar input client tidak pernah bisa membuka data tenant lain.
+func forTenant(ctx context.Context, filter bson.M) bson.M {
+	out := bson.M{}
+	for k, v := range filter {
+		out[k] = v
+	}
+	out["tenant_id"] = tenantValue(ctx)
+	return out
+}
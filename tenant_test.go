@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestResolveTenant(t *testing.T) {
+	old := config
+	t.Cleanup(func() { config = old })
+	config.APIKey = "operator-key"
+	config.AdminAPIKey = "admin-key"
+	config.JWTSecret = "secret"
+	config.TenantKeys = map[string]string{"acme-key": "acme"}
+
+	token := func(claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantTenant string
+		wantStatus int
+	}{
+		{name: "tenant API key", header: map[string]string{"X-API-Key": "acme-key"}, wantTenant: "acme"},
+		{name: "tenant API key with another tenant", header: map[string]string{"X-API-Key": "acme-key", "X-Tenant-ID": "globex"}, wantStatus: http.StatusForbidden},
+		{name: "API_KEY picks any tenant", header: map[string]string{"X-API-Key": "operator-key", "X-Tenant-ID": "globex"}, wantTenant: "globex"},
+		{name: "admin key picks any tenant", header: map[string]string{"X-Admin-Key": "admin-key", "X-Tenant-ID": "globex"}, wantTenant: "globex"},
+		{name: "JWT tenant claim", header: map[string]string{"Authorization": token(jwt.MapClaims{"sub": "u1", "tenant_id": "acme"})}, wantTenant: "acme"},
+		{name: "JWT with matching X-Tenant-ID", header: map[string]string{"Authorization": token(jwt.MapClaims{"tenant_id": "acme"}), "X-Tenant-ID": "acme"}, wantTenant: "acme"},
+		{name: "JWT with another X-Tenant-ID", header: map[string]string{"Authorization": token(jwt.MapClaims{"tenant_id": "acme"}), "X-Tenant-ID": "globex"}, wantStatus: http.StatusForbidden},
+		{name: "JWT without claim cannot pick a tenant", header: map[string]string{"Authorization": token(jwt.MapClaims{"sub": "u1"}), "X-Tenant-ID": "globex"}, wantStatus: http.StatusForbidden},
+		{name: "invalid JWT", header: map[string]string{"Authorization": "Bearer nope", "X-Tenant-ID": "globex"}, wantStatus: http.StatusUnauthorized},
+		{name: "no credential", header: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/locations", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			tenant, status, err := resolveTenant(r)
+			if status != tt.wantStatus || (tt.wantStatus == 0 && (err != nil || tenant != tt.wantTenant)) {
+				t.Fatalf("resolveTenant() = %q, %d, %v; want %q, %d", tenant, status, err, tt.wantTenant, tt.wantStatus)
+			}
+		})
+	}
+}
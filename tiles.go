@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxTileZoom adalah level zoom tertinggi yang diterima endpoint tile
+const maxTileZoom = 22
+
+// parseTile membaca z/x/y dari path dan memvalidasi rentangnya terhadap level zoom
+func parseTile(r *http.Request) (int, int, int, error) {
+	vars := mux.Vars(r)
+	z, err := strconv.Atoi(vars["z"])
+	if err != nil || z < 0 || z > maxTileZoom {
+		return 0, 0, 0, errors.New("z must be an integer between 0 and 22")
+	}
+	n := 1 << z
+	x, err := strconv.Atoi(vars["x"])
+	if err != nil || x < 0 || x >= n {
+		return 0, 0, 0, errors.New("x is out of range for the given zoom level")
+	}
+	y, err := strconv.Atoi(vars["y"])
+	if err != nil || y < 0 || y >= n {
+		return 0, 0, 0, errors.New("y is out of range for the given zoom level")
+	}
+	return z, x, y, nil
+}
+
+// tileBBox mengubah tile XYZ (Web Mercator, y=0 di utara) menjadi bbox lng/lat
+func tileBBox(z, x, y int) BBox {
+	n := float64(int(1) << z)
+	tileLng := func(x int) float64 {
+		return float64(x)/n*360 - 180
+	}
+	tileLat := func(y int) float64 {
+		return math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/n))) * 180 / math.Pi
+	}
+	return BBox{
+		West:  tileLng(x),
+		South: tileLat(y + 1),
+		East:  tileLng(x + 1),
+		North: tileLat(y),
+	}
+}
+
+// tileCountHandler mengembalikan jumlah lokasi di dalam satu tile XYZ
+func (s *Server) tileCountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	z, x, y, err := parseTile(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bbox := tileBBox(z, x, y)
+
+	count, err := s.collection.CountDocuments(ctx, withoutDeleted(ctx, bbox.geoWithinFilter()))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, map[string]interface{}{
+		"z":     z,
+		"x":     x,
+		"y":     y,
+		"bbox":  bbox,
+		"count": count,
+	})
+}
+
+const (
+	// maxClusterZoom adalah zoom tertinggi yang masih dikelompokkan menjadi cluster jika ?cluster=true
+	maxClusterZoom = 12
+	// clusterGridSize adalah jumlah sel per sisi tile saat titik dikelompokkan menjadi cluster
+	clusterGridSize = 16
+	// maxTileFeatures adalah jumlah lokasi maksimum yang dikirim satu per satu dalam satu tile
+	maxTileFeatures = 5000
+)
+
+// tileGeoJSONHandler mengembalikan lokasi di dalam satu tile XYZ sebagai GeoJSON FeatureCollection.
+// Dengan ?cluster=true pada zoom rendah, titik dikelompokkan per sel grid di dalam tile.
+// ?format=mapbox atau google mengubah bentuk output untuk library peta tersebut.
+// Tile berisi lebih dari maxTileFeatures lokasi tetap dikelompokkan jika zoom-nya masih bisa di-cluster dan
+// formatnya GeoJSON; selain itu hanya maxTileFeatures lokasi pertama yang dikirim, dengan Warning.
+func (s *Server) tileGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/geo+json")
+
+	z, x, y, err := parseTile(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format, err := parseFormat(r, formatGeoJSON)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.checkCollectionETag(w, r) {
+		return
+	}
+	bbox := tileBBox(z, x, y)
+
+	canCluster := z <= maxClusterZoom
+	if r.URL.Query().Get("cluster") == "true" && canCluster {
+		s.writeClusterTile(w, r, bbox)
+		return
+	}
+
+	cursor, err := s.collection.Find(ctx, withoutDeleted(ctx, bbox.geoWithinFilter()), options.Find().SetLimit(maxTileFeatures+1))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	locations, skipped, err := decodeLocations(ctx, cursor)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if len(locations) > maxTileFeatures {
+		if canCluster && format == formatGeoJSON {
+			setWarningHeaders(w, append(skipped, fmt.Sprintf("the tile holds more than %d locations, they are clustered instead", maxTileFeatures)))
+			s.writeClusterTile(w, r, bbox)
+			return
+		}
+		locations = locations[:maxTileFeatures]
+		skipped = append(skipped, fmt.Sprintf("only the first %d locations in the tile are returned, zoom in to see the rest", maxTileFeatures))
+	}
+	setWarningHeaders(w, skipped)
+
+	payload, contentType := formatLocations(format, locations)
+	w.Header().Set("Content-Type", contentType)
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// writeClusterTile menulis tile sebagai cluster per sel grid
+func (s *Server) writeClusterTile(w http.ResponseWriter, r *http.Request, bbox BBox) {
+	features, err := s.clusterTile(r.Context(), bbox)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newFeatureCollection(features))
+}
+
+// clusterCell adalah satu sel grid hasil pengelompokan: centroid, jumlah anggota, dan ID salah satu anggotanya
+type clusterCell struct {
+	Lng     float64            `bson:"lng"`
+	Lat     float64            `bson:"lat"`
+	Count   int64              `bson:"count"`
+	FirstID primitive.ObjectID `bson:"first_id"`
+}
+
+// gridCells mengelompokkan lokasi Point di dalam bbox ke sel selebar cellW x cellH derajat yang dihitung dari
+// titik asal (originLng, originLat), mengembalikan centroid dan jumlah anggota setiap sel yang berisi
+func (s *Server) gridCells(ctx context.Context, bbox BBox, originLng, originLat, cellW, cellH float64) ([]clusterCell, error) {
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": withoutDeleted(ctx, bbox.pointsWithinFilter())},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"col": bson.M{"$floor": bson.M{"$divide": bson.A{
+					bson.M{"$subtract": bson.A{bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}, originLng}},
+					cellW,
+				}}},
+				"row": bson.M{"$floor": bson.M{"$divide": bson.A{
+					bson.M{"$subtract": bson.A{bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}, originLat}},
+					cellH,
+				}}},
+			},
+			"lng":      bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 0}}},
+			"lat":      bson.M{"$avg": bson.M{"$arrayElemAt": bson.A{"$location.coordinates", 1}}},
+			"count":    bson.M{"$sum": 1},
+			"first_id": bson.M{"$first": "$_id"},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var cells []clusterCell
+	if err = cursor.All(ctx, &cells); err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
+
+// clusterFeature mengubah satu sel menjadi feature cluster dengan titik centroid dan jumlah anggotanya
+func clusterFeature(c clusterCell) Feature {
+	return Feature{
+		Type:     "Feature",
+		Geometry: Point{Type: "Point", Coordinates: []float64{c.Lng, c.Lat}},
+		Properties: map[string]interface{}{
+			"cluster":     true,
+			"point_count": c.Count,
+		},
+	}
+}
+
+// clusterTile mengelompokkan lokasi di dalam bbox ke grid clusterGridSize x clusterGridSize,
+// mengembalikan satu feature per sel dengan titik centroid dan jumlah anggotanya
+func (s *Server) clusterTile(ctx context.Context, bbox BBox) ([]Feature, error) {
+	cellW := (bbox.East - bbox.West) / clusterGridSize
+	cellH := (bbox.North - bbox.South) / clusterGridSize
+
+	cells, err := s.gridCells(ctx, bbox, bbox.West, bbox.South, cellW, cellH)
+	if err != nil {
+		return nil, err
+	}
+	features := make([]Feature, 0, len(cells))
+	for _, c := range cells {
+		features = append(features, clusterFeature(c))
+	}
+	return features, nil
+}
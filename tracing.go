@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingServiceName adalah nama service default pada span jika OTEL_SERVICE_NAME tidak diset, sekaligus nama
+// tracer untuk span yang dibuat aplikasi ini sendiri (gRPC dan request HTTP keluar)
+const tracingServiceName = "go-mongo-railway"
+
+// tracingEnabled mengecek apakah endpoint OTLP diset lewat OTEL_EXPORTER_OTLP_ENDPOINT atau
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (dan OTEL_SDK_DISABLED tidak bernilai true); tanpa endpoint tracing
+// sepenuhnya no-op
+func tracingEnabled() bool {
+	if os.Getenv("OTEL_SDK_DISABLED") == "true" {
+		return false
+	}
+	return tracingEndpoint() != ""
+}
+
+// tracingEndpoint mengembalikan endpoint OTLP untuk trace; variabel khusus trace menang atas yang umum,
+// sama seperti aturan exporter
+func tracingEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// initTracing memasang TracerProvider global yang mengekspor span lewat OTLP/HTTP ke OTEL_EXPORTER_OTLP_ENDPOINT,
+// serta propagator W3C trace context agar trace dari service lain tersambung. Mengembalikan fungsi shutdown
+// yang mengirim span yang masih tertahan di buffer.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(tracingServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME tetap menang atas nama default di atas
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(semconv.ServiceName(name)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	slog.Info("tracing enabled", "endpoint", tracingEndpoint())
+	return tp.Shutdown, nil
+}
+
+// tracedTransport mengembalikan RoundTripper untuk client HTTP keluar (webhook, geocoder, S3): dengan tracing
+// aktif setiap request menjadi span client dan membawa header traceparent, sehingga latensi layanan
+// eksternal terlihat di trace request yang memicunya
+func tracedTransport() http.RoundTripper {
+	if !tracingEnabled() {
+		return http.DefaultTransport
+	}
+	return tracingTransport{base: http.DefaultTransport}
+}
+
+// tracingTransport membungkus base dengan span client per request. Span selesai saat header response
+// diterima; waktu membaca body tidak termasuk.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Query tidak dicatat karena API key sebagian provider geocoding dikirim lewat query string
+	target := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}
+	ctx, span := otel.Tracer(tracingServiceName).Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLFull(target.String()),
+		))
+	defer span.End()
+
+	// RoundTripper tidak boleh mengubah request milik pemanggil, sehingga header disisipkan ke salinannya
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
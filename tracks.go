@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxDeviceIDLength adalah panjang maksimum ID perangkat pada /tracks/{deviceId}
+	maxDeviceIDLength = 64
+	// maxTrackPointsPerRequest adalah jumlah titik maksimum dalam satu POST /tracks/{deviceId}/points
+	maxTrackPointsPerRequest = 1000
+	// maxTrackPoints adalah jumlah titik maksimum yang dibaca untuk satu GET /tracks/{deviceId}; rentang
+	// waktu yang lebih panjang harus dipersempit dengan from dan to
+	maxTrackPoints = 10000
+	// maxSimplifyMeters adalah toleransi Douglas-Peucker terbesar yang diterima ?simplify=
+	maxSimplifyMeters = 10000
+	// trackClockSkew adalah seberapa jauh recorded_at boleh berada di masa depan, untuk jam perangkat yang
+	// sedikit lebih cepat dari jam server
+	trackClockSkew = 5 * time.Minute
+	// errCodeNamespaceExists dikembalikan MongoDB jika koleksi yang akan dibuat sudah ada
+	errCodeNamespaceExists = 48
+)
+
+// trackMeta adalah metaField koleksi time-series: MongoDB mengelompokkan titik dengan meta yang sama ke
+// bucket yang sama, sehingga titik satu perangkat tersimpan berdekatan
+type trackMeta struct {
+	DeviceID string `bson:"device_id"`
+	TenantID string `bson:"tenant_id,omitempty"`
+}
+
+// TrackPoint adalah satu posisi perangkat pada satu waktu, disimpan sebagai satu dokumen di <koleksi>_tracks
+type TrackPoint struct {
+	Meta       trackMeta `bson:"meta"`
+	RecordedAt time.Time `bson:"recorded_at"`
+	Location   Point     `bson:"location"`
+}
+
+// trackPointInput adalah satu titik di body POST /tracks/{deviceId}/points; tanpa recorded_at titik
+// dicatat dengan waktu server
+type trackPointInput struct {
+	Coordinates []float64  `json:"coordinates"`
+	RecordedAt  *time.Time `json:"recorded_at"`
+}
+
+// trackPointsInput adalah body POST /tracks/{deviceId}/points
+type trackPointsInput struct {
+	Points []trackPointInput `json:"points"`
+}
+
+// validate memeriksa jumlah titik, koordinat, dan waktu setiap titik
+func (in trackPointsInput) validate(now time.Time) []FieldError {
+	if len(in.Points) == 0 {
+		return []FieldError{{Field: "points", Message: "at least one point is required"}}
+	}
+	if len(in.Points) > maxTrackPointsPerRequest {
+		return []FieldError{{Field: "points", Message: fmt.Sprintf("at most %d points can be sent per request", maxTrackPointsPerRequest)}}
+	}
+	var errs []FieldError
+	for i, p := range in.Points {
+		if err := validatePosition(p.Coordinates); err != nil {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("points[%d].coordinates", i), Message: err.Error()})
+		}
+		if p.RecordedAt != nil && p.RecordedAt.After(now.Add(trackClockSkew)) {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("points[%d].recorded_at", i), Message: "recorded_at cannot be in the future"})
+		}
+	}
+	return errs
+}
+
+// validDeviceID mengecek ID perangkat: 1-maxDeviceIDLength huruf, angka, -, _, . atau :
+func validDeviceID(id string) bool {
+	if id == "" || len(id) > maxDeviceIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c))
+	}) < 0
+}
+
+// ensureTrackCollection membuat <koleksi>_tracks sebagai koleksi time-series (MongoDB 5.0+) beserta index
+// per perangkat dan waktu. Pada MongoDB yang lebih lama koleksi biasa dibuat otomatis saat titik pertama
+// disimpan; query-nya sama, hanya penyimpanannya kurang hemat.
+func (s *Server) ensureTrackCollection(ctx context.Context) error {
+	opts := options.CreateCollection().SetTimeSeriesOptions(options.TimeSeries().
+		SetTimeField("recorded_at").
+		SetMetaField("meta").
+		SetGranularity("seconds"))
+	err := s.tracks.Database().CreateCollection(ctx, s.tracks.Name(), opts)
+	var se mongo.ServerError
+	switch {
+	case err == nil:
+		slog.Info("track time-series collection created", "collection", s.tracks.Name())
+	case errors.As(err, &se) && se.HasErrorCode(errCodeNamespaceExists):
+	default:
+		slog.Warn("track time-series collection could not be created, using a regular collection", "error", err)
+	}
+	_, err = s.tracks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "meta.tenant_id", Value: 1}, {Key: "meta.device_id", Value: 1}, {Key: "recorded_at", Value: 1}},
+	})
+	return err
+}
+
+// trackFilter mengembalikan filter titik satu perangkat dalam tenant request. Seperti tenantValue, tanpa
+// tenant dibandingkan dengan null agar index yang diawali meta.tenant_id tetap terpakai.
+func trackFilter(ctx context.Context, deviceID string) bson.M {
+	return bson.M{"meta.tenant_id": tenantValue(ctx), "meta.device_id": deviceID}
+}
+
+// recordTrackPointsHandler menyimpan titik-titik baru untuk satu perangkat. Urutan titik di body tidak
+// harus urut waktu, karena GET mengurutkannya berdasarkan recorded_at.
+func (s *Server) recordTrackPointsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	deviceID := mux.Vars(r)["deviceId"]
+	if !validDeviceID(deviceID) {
+		writeError(w, http.StatusBadRequest, "invalid_device_id", fmt.Sprintf("Device ID must be 1-%d letters, digits, -, _, . or :", maxDeviceIDLength))
+		return
+	}
+
+	var input trackPointsInput
+	if err := decodeBody(w, r, &input); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	now := time.Now()
+	if errs := input.validate(now); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	meta := trackMeta{DeviceID: deviceID, TenantID: tenantFromContext(ctx)}
+	docs := make([]interface{}, len(input.Points))
+	for i, p := range input.Points {
+		recordedAt := now
+		if p.RecordedAt != nil {
+			recordedAt = *p.RecordedAt
+		}
+		docs[i] = TrackPoint{Meta: meta, RecordedAt: recordedAt, Location: Point{Type: "Point", Coordinates: p.Coordinates}}
+	}
+	if _, err := s.tracks.InsertMany(ctx, docs); err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"device_id": deviceID,
+		"recorded":  len(docs),
+	})
+}
+
+// trackHandler mengembalikan jalur satu perangkat sebagai GeoJSON Feature LineString yang urut waktu, dengan
+// waktu setiap posisi di properties.timestamps. from dan to (RFC3339, inklusif) membatasi rentang waktu;
+// ?simplify=<meter> mengurangi jumlah posisi dengan Douglas-Peucker untuk ditampilkan di peta.
+func (s *Server) trackHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	deviceID := mux.Vars(r)["deviceId"]
+	if !validDeviceID(deviceID) {
+		writeError(w, http.StatusBadRequest, "invalid_device_id", fmt.Sprintf("Device ID must be 1-%d letters, digits, -, _, . or :", maxDeviceIDLength))
+		return
+	}
+
+	q := r.URL.Query()
+	recorded := bson.M{}
+	var from, to time.Time
+	if raw := q.Get("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "from must be an RFC3339 timestamp")
+			return
+		}
+		from = t
+		recorded["$gte"] = t
+	}
+	if raw := q.Get("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "to must be an RFC3339 timestamp")
+			return
+		}
+		to = t
+		recorded["$lte"] = t
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		writeJSONError(w, http.StatusBadRequest, "from must not be later than to")
+		return
+	}
+	tolerance := 0.0
+	if raw := q.Get("simplify"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > maxSimplifyMeters {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("simplify must be a tolerance in meters between 0 and %d", maxSimplifyMeters))
+			return
+		}
+		tolerance = v
+	}
+
+	filter := trackFilter(ctx, deviceID)
+	if len(recorded) > 0 {
+		filter["recorded_at"] = recorded
+	}
+	// Satu titik lebih dari batas dibaca untuk mengetahui apakah rentangnya terlalu panjang
+	opts := options.Find().
+		SetSort(bson.D{{Key: "recorded_at", Value: 1}}).
+		SetLimit(maxTrackPoints + 1).
+		SetProjection(bson.M{"recorded_at": 1, "location": 1})
+	cursor, err := s.tracks.Find(ctx, filter, opts)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	var points []TrackPoint
+	if err := cursor.All(ctx, &points); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if len(points) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("No points recorded for device %s in this time range", deviceID))
+		return
+	}
+	if len(points) > maxTrackPoints {
+		writeError(w, http.StatusUnprocessableEntity, "track_too_long",
+			fmt.Sprintf("The track has more than %d points in this time range, narrow it with from and to", maxTrackPoints))
+		return
+	}
+
+	line := make([][]float64, len(points))
+	for i, p := range points {
+		line[i] = p.Location.Coordinates
+	}
+	distance := 0.0
+	for i := 1; i < len(line); i++ {
+		distance += haversineMeters(line[i-1], line[i])
+	}
+	keep := allIndexes(len(line))
+	if tolerance > 0 {
+		keep = simplifyLine(line, tolerance)
+	}
+	coords := make([][]float64, len(keep))
+	timestamps := make([]string, len(keep))
+	for i, idx := range keep {
+		coords[i] = []float64{roundCoord(line[idx][0]), roundCoord(line[idx][1])}
+		timestamps[i] = points[idx].RecordedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	// LineString butuh minimal dua posisi, sehingga jalur dengan satu titik dikembalikan sebagai Point
+	var geometry interface{} = LineString{Type: "LineString", Coordinates: coords}
+	if len(coords) == 1 {
+		geometry = Point{Type: "Point", Coordinates: coords[0]}
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	writeJSON(w, http.StatusOK, Feature{
+		Type:     "Feature",
+		ID:       deviceID,
+		Geometry: geometry,
+		Properties: map[string]interface{}{
+			"device_id":   deviceID,
+			"from":        timestamps[0],
+			"to":          timestamps[len(timestamps)-1],
+			"point_count": len(points),
+			"returned":    len(coords),
+			"distance_m":  distance,
+			"timestamps":  timestamps,
+		},
+	})
+}
+
+// allIndexes mengembalikan 0..n-1, yaitu semua posisi jalur tanpa penyederhanaan
+func allIndexes(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+// simplifyLine menjalankan Douglas-Peucker pada line dan mengembalikan indeks posisi yang dipertahankan,
+// urut dari awal: posisi yang jaraknya ke garis penyederhanaan tidak lebih dari tolerance (meter) dibuang.
+// Titik awal dan akhir selalu dipertahankan. Dikerjakan dengan stack agar jalur panjang tidak membuat
+// rekursi yang dalam.
+func simplifyLine(line [][]float64, tolerance float64) []int {
+	if len(line) < 3 {
+		return allIndexes(len(line))
+	}
+	kept := make([]bool, len(line))
+	kept[0], kept[len(line)-1] = true, true
+	stack := [][2]int{{0, len(line) - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		first, last := span[0], span[1]
+		farthest, farthestDist := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if dist, _ := projectOntoLine(line[i], [][]float64{line[first], line[last]}); dist > farthestDist {
+				farthest, farthestDist = i, dist
+			}
+		}
+		if farthest < 0 {
+			continue
+		}
+		kept[farthest] = true
+		stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+	}
+	out := []int{}
+	for i, k := range kept {
+		if k {
+			out = append(out, i)
+		}
+	}
+	return out
+}
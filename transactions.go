@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errCodeIllegalOperation dikembalikan server standalone saat diminta memulai transaksi
+const errCodeIllegalOperation = 20
+
+// detectTransactionSupport mengecek lewat perintah hello apakah MongoDB berjalan sebagai replica set atau
+// mongos, satu-satunya deployment yang mendukung transaksi multi-dokumen. Jika tidak, write yang menyentuh
+// banyak dokumen tetap berjalan seperti sebelumnya tanpa transaksi.
+func (s *Server) detectTransactionSupport(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := s.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		slog.Warn("transaction support could not be detected, multi-document writes run without transactions", "error", err)
+		return
+	}
+	supported := hello.SetName != "" || hello.Msg == "isdbgrid"
+	s.transactions.Store(supported)
+	if supported {
+		slog.Info("multi-document transactions enabled")
+	} else {
+		slog.Warn("MongoDB is not a replica set, multi-document writes run without transactions")
+	}
+}
+
+// runInTransaction menjalankan fn di dalam satu transaksi, sehingga semua write di dalamnya tersimpan atau
+// batal bersama-sama. fn harus memakai ctx yang diberikan dan boleh dijalankan ulang oleh driver saat terjadi
+// error transien. Pada deployment tanpa transaksi fn dijalankan langsung; hasil bool false memberi tahu
+// pemanggil bahwa write yang sudah berhasil sebelum error tetap tersimpan.
+func (s *Server) runInTransaction(r *http.Request, fn func(ctx context.Context) error) (bool, error) {
+	ctx := r.Context()
+	if !s.transactions.Load() {
+		return false, fn(ctx)
+	}
+
+	// Di dalam transaksi write concern per operasi diabaikan; X-Write-Concern (sudah divalidasi handler)
+	// berlaku saat commit
+	txnOpts := options.Transaction()
+	if wc, _ := parseWriteConcern(r); wc != nil {
+		txnOpts.SetWriteConcern(wc)
+	}
+	sess, err := s.client.StartSession()
+	if err != nil {
+		return false, err
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	}, txnOpts)
+	// Deployment diganti menjadi standalone setelah server berjalan; transaksinya tidak pernah dimulai
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCode(errCodeIllegalOperation) {
+		slog.Warn("MongoDB rejected the transaction, multi-document writes now run without transactions", "error", err)
+		s.transactions.Store(false)
+		return false, fn(ctx)
+	}
+	return true, err
+}
+
+// auditInTransaction menyimpan event audit di dalam transaksi runInTransaction, sehingga event tersimpan jika
+// dan hanya jika write-nya ter-commit. Di luar transaksi tidak melakukan apa-apa; pemanggil memanggil
+// recordAudit setelah write berhasil, seperti write tunggal.
+func (s *Server) auditInTransaction(ctx context.Context, r *http.Request, events ...AuditEvent) error {
+	if len(events) == 0 || mongo.SessionFromContext(ctx) == nil {
+		return nil
+	}
+	_, err := s.auditEvents.InsertMany(ctx, auditDocuments(r, events))
+	return err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// notDeletedFilter mencocokkan lokasi yang tidak sedang di trash. Dibandingkan dengan null (bukan $exists)
+// agar bisa memakai index; lokasi lama yang belum punya field deleted_at ikut cocok.
+func notDeletedFilter() bson.M {
+	return bson.M{"deleted_at": nil}
+}
+
+// withoutDeleted menambahkan notDeletedFilter ke filter tanpa mengubah map aslinya, lalu membatasinya ke
+// tenant request lewat forTenant. Filter yang sudah menyebut deleted_at (seperti daftar trash) dibiarkan apa adanya.
+// Lokasi yang sudah kedaluwarsa tetapi belum dihapus index TTL ikut disembunyikan, kecuali include_expired=true.
+func withoutDeleted(ctx context.Context, filter bson.M) bson.M {
+	out := notDeletedFilter()
+	if !includeExpired(ctx) {
+		out["expires_at"] = notExpiredFilter(time.Now())
+	}
+	for k, v := range filter {
+		out[k] = v
+	}
+	return forTenant(ctx, out)
+}
+
+// trashLocationsHandler mengembalikan lokasi di trash per halaman, yang terakhir dihapus lebih dulu
+func (s *Server) trashLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var warns []string
+	limit, page, err := parsePagination(r, &warns)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := bson.M{"deleted_at": bson.M{"$ne": nil}}
+	total, err := s.locations.Count(ctx, filter)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	sort := bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}}
+	locations, skipped, err := s.locations.List(ctx, filter, sort, int64(limit), int64((page-1)*limit))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	warns = append(warns, skipped...)
+	setWarningHeaders(w, warns)
+
+	response := map[string]interface{}{
+		"data":  locations,
+		"limit": limit,
+		"page":  page,
+		"total": total,
+	}
+	if len(warns) > 0 {
+		response["meta"] = map[string]interface{}{"warnings": warns}
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// restoreLocationHandler mengeluarkan lokasi dari trash dan mengembalikan dokumennya
+func (s *Server) restoreLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.requireLocationOwner(w, r, id) {
+		return
+	}
+
+	restored, err := repo.Restore(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Location with ID %s is not in the trash", vars["id"]))
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	s.bumpCollectionVersion(ctx)
+	s.recordAudit(r, newAuditEvent(auditRestore, nil, &restored))
+
+	writeResponse(w, r, http.StatusOK, restored)
+}
+
+// purgeLocationHandler menghapus permanen lokasi yang sudah di trash. Lokasi aktif harus di-DELETE dulu,
+// sehingga satu request yang salah tidak bisa langsung menghilangkan data.
+func (s *Server) purgeLocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid location ID format")
+		return
+	}
+	repo, err := s.writeRepository(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.requireLocationOwner(w, r, id) {
+		return
+	}
+
+	purged, err := repo.Purge(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Location with ID %s is not in the trash", vars["id"]))
+		return
+	}
+	if err != nil {
+		s.writeWriteError(w, r, err)
+		return
+	}
+	// Lokasi di trash tidak terlihat di endpoint lain, tetapi jumlah dokumen pada versi koleksi berubah
+	s.bumpCollectionVersion(ctx)
+	s.recordAudit(r, newAuditEvent(auditPurge, &purged, nil))
+	s.deletePhotoFiles(r, purged.Photos...)
+	s.deleteFavoritesOf(r, purged.ID)
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Location with ID %s was permanently deleted", vars["id"]),
+	})
+}
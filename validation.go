@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FieldError menjelaskan satu field yang gagal validasi
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validate memeriksa field wajib dan bentuk geometri GeoJSON sebelum dokumen ditulis,
+// agar data yang tidak valid tidak mengganggu index 2dsphere
+func (loc Location) validate() []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(loc.Name) == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "name is required"})
+	}
+	if len([]rune(loc.Address)) > maxAddressLength {
+		errs = append(errs, FieldError{Field: "address", Message: fmt.Sprintf("address must be at most %d characters", maxAddressLength)})
+	}
+	if err := validateTags(loc.Tags); err != nil {
+		errs = append(errs, FieldError{Field: "tags", Message: err.Error()})
+	}
+	if err := validateExternalID(loc.ExternalID); err != nil {
+		errs = append(errs, FieldError{Field: "external_id", Message: err.Error()})
+	}
+	if err := validateGeometry(loc.Location); err != nil {
+		errs = append(errs, FieldError{Field: "location", Message: err.Error()})
+	}
+	return errs
+}
+
+// writeValidationErrors menulis daftar field yang gagal validasi sebagai 422: body-nya JSON yang valid, tetapi
+// isinya tidak bisa disimpan. Body yang tidak bisa di-decode tetap 400.
+func writeValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	writeErrorDetails(w, http.StatusUnprocessableEntity, "validation_failed", "Validation failed", map[string]interface{}{"fields": errs})
+}
+
+// validateBusinessRules memeriksa aturan antar-field pada level record, di luar validasi per-field.
+// Semua aturan sengaja dikumpulkan di satu fungsi agar mudah ditinjau dan diuji. Pada update, fields berisi
+// field yang ditulis (format locationUpdate); nil untuk lokasi baru, sehingga semua aturan berlaku.
+func validateBusinessRules(loc Location, fields map[string]json.RawMessage) error {
+	// Lokasi event bersifat sementara, sehingga wajib memiliki waktu kedaluwarsa
+	if loc.Category == "event" && loc.ExpiresAt == nil {
+		return errors.New("rule violated: locations with category \"event\" require expires_at")
+	}
+	// Hanya expires_at yang sedang ditulis yang harus di masa depan, agar lokasi yang sudah kedaluwarsa
+	// (dibaca lewat include_expired) tetap bisa diubah field lainnya
+	_, expiresWritten := fields["expires_at"]
+	if loc.ExpiresAt != nil && (fields == nil || expiresWritten) && !loc.ExpiresAt.After(time.Now()) {
+		return errors.New("rule violated: expires_at must be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateBusinessRules(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		loc     Location
+		fields  map[string]json.RawMessage
+		wantErr string
+	}{
+		{name: "new location expiring in the past", loc: Location{ExpiresAt: &past}, wantErr: "expires_at must be in the future"},
+		{name: "new location expiring in the future", loc: Location{ExpiresAt: &future}},
+		{name: "new event without expiry", loc: Location{Category: "event"}, wantErr: "require expires_at"},
+		{
+			name:   "update of another field on an expired location",
+			loc:    Location{Name: "Konser", Category: "event", ExpiresAt: &past},
+			fields: map[string]json.RawMessage{"name": json.RawMessage(`"Konser"`)},
+		},
+		{
+			name:    "update writing a past expiry",
+			loc:     Location{ExpiresAt: &past},
+			fields:  map[string]json.RawMessage{"expires_at": json.RawMessage(`"2020-01-01T00:00:00Z"`)},
+			wantErr: "expires_at must be in the future",
+		},
+		{
+			name:    "update turning a location without expiry into an event",
+			loc:     Location{Category: "event"},
+			fields:  map[string]json.RawMessage{"category": json.RawMessage(`"event"`)},
+			wantErr: "require expires_at",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBusinessRules(tt.loc, tt.fields)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("validateBusinessRules() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("validateBusinessRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
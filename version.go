@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// versionDocID adalah _id dokumen metadata yang menyimpan versi koleksi
+const versionDocID = "version"
+
+// CollectionVersion adalah versi koleksi lokasi. Version naik setiap kali ada write lewat API; Count ikut
+// dimasukkan karena dokumen yang dihapus oleh index TTL tidak melewati API dan tidak menaikkan Version.
+type CollectionVersion struct {
+	Version int64 `json:"version"`
+	Count   int64 `json:"count"`
+	// UpdatedAt adalah waktu write terakhir lewat API, untuk header Last-Modified; kosong untuk dokumen
+	// versi yang dibuat sebelum field ini ada
+	UpdatedAt time.Time `json:"-"`
+}
+
+// ETag mengembalikan versi dalam bentuk header ETag
+func (v CollectionVersion) ETag() string {
+	return fmt.Sprintf(`"v%d-%d"`, v.Version, v.Count)
+}
+
+// bumpCollectionVersion menaikkan versi koleksi setelah write berhasil (atau mungkin berhasil).
+// Kegagalan hanya dicatat: write-nya sendiri sudah terjadi, paling buruk client menerima data lama sedikit lebih lama.
+func (s *Server) bumpCollectionVersion(ctx context.Context) {
+	_, err := s.meta.UpdateOne(ctx,
+		bson.M{"_id": versionDocID},
+		bson.M{"$inc": bson.M{"version": 1}, "$currentDate": bson.M{"updated_at": true}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		slog.Warn("could not bump collection version", "error", err)
+	}
+}
+
+// currentCollectionVersion membaca versi koleksi dan jumlah dokumennya saat ini
+func (s *Server) currentCollectionVersion(ctx context.Context) (CollectionVersion, error) {
+	var doc struct {
+		Version   int64     `bson:"version"`
+		UpdatedAt time.Time `bson:"updated_at"`
+	}
+	err := s.meta.FindOne(ctx, bson.M{"_id": versionDocID}).Decode(&doc)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return CollectionVersion{}, err
+	}
+	count, err := s.countTenantDocuments(ctx)
+	if err != nil {
+		return CollectionVersion{}, err
+	}
+	return CollectionVersion{Version: doc.Version, Count: count, UpdatedAt: doc.UpdatedAt}, nil
+}
+
+// countTenantDocuments menghitung dokumen koleksi (termasuk yang di trash). Tanpa multi-tenancy memakai
+// estimasi dari metadata yang murah; dengan multi-tenancy dihitung lewat index tenant, agar tenant tidak
+// melihat jumlah dokumen tenant lain.
+func (s *Server) countTenantDocuments(ctx context.Context) (int64, error) {
+	if !multiTenantEnabled() {
+		return s.collection.EstimatedDocumentCount(ctx)
+	}
+	return s.collection.CountDocuments(ctx, forTenant(ctx, nil))
+}
+
+// checkCollectionETag menulis ETag dan Last-Modified versi koleksi dan menjawab 304 jika If-None-Match masih
+// cocok. If-Modified-Since tidak dipakai di sini: dokumen yang dihapus index TTL tidak mengubah UpdatedAt,
+// sehingga hanya ETag (yang memuat Count) yang bisa mendeteksinya. Mengembalikan false jika response sudah ditulis.
+func (s *Server) checkCollectionETag(w http.ResponseWriter, r *http.Request) bool {
+	v, err := s.currentCollectionVersion(r.Context())
+	if err != nil {
+		writeDBError(w, r, err)
+		return false
+	}
+	etag := v.ETag()
+	w.Header().Set("ETag", etag)
+	setLastModified(w, v.UpdatedAt)
+	if etagMatches(r.Header.Get("If-None-Match"), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+	return true
+}
+
+// ETag mengembalikan ETag satu lokasi dari revision dan updated_at (dalam milidetik, presisi yang disimpan
+// MongoDB). updated_at ikut dipakai karena beberapa write admin mengubah lokasi tanpa menaikkan revision.
+func (l Location) ETag() string {
+	var updated int64
+	if !l.UpdatedAt.IsZero() {
+		updated = l.UpdatedAt.UnixMilli()
+	}
+	return fmt.Sprintf(`"r%d-%d"`, l.Revision, updated)
+}
+
+// revisionFilter adalah syarat update agar hanya berlaku jika lokasi masih pada revision dan updated_at yang
+// sama dengan loc. Dokumen lama yang belum punya kedua field tersebut dicocokkan lewat $exists.
+func revisionFilter(loc Location) bson.M {
+	filter := bson.M{"revision": bson.M{"$exists": false}, "updated_at": bson.M{"$exists": false}}
+	if loc.Revision != 0 {
+		filter["revision"] = loc.Revision
+	}
+	if !loc.UpdatedAt.IsZero() {
+		filter["updated_at"] = loc.UpdatedAt
+	}
+	return filter
+}
+
+// etagMatches memeriksa apakah header If-Match atau If-None-Match (daftar dipisah koma, atau "*") memuat etag.
+// If-None-Match memakai perbandingan weak (prefix W/ diabaikan); If-Match memakai perbandingan strong.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writePreconditionFailed menjawab 412 untuk update yang If-Match-nya sudah usang, beserta ETag terbaru
+// agar client bisa memuat ulang lokasinya lalu mengulang perubahan
+func writePreconditionFailed(w http.ResponseWriter, current Location) {
+	w.Header().Set("ETag", current.ETag())
+	writeErrorDetails(w, http.StatusPreconditionFailed, "precondition_failed",
+		"Location was modified by another request, fetch it again and retry",
+		map[string]interface{}{"currentETag": current.ETag()})
+}
+
+// collectionVersionHandler mengembalikan versi koleksi agar client bisa polling murah dan hanya mengambil
+// ulang seluruh data jika versinya berubah
+func (s *Server) collectionVersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	v, err := s.currentCollectionVersion(r.Context())
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", v.ETag())
+	writeResponse(w, r, http.StatusOK, v)
+}
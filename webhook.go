@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// webhookQueueSize adalah jumlah perubahan lokasi yang boleh mengantre untuk dievaluasi; jika penuh,
+	// perubahan berikutnya dibuang (dicatat di log) alih-alih menahan request write
+	webhookQueueSize = 1000
+	// maxWebhookDeliveries adalah jumlah pengiriman webhook yang boleh berjalan bersamaan
+	maxWebhookDeliveries = 10
+	// webhookAttempts adalah jumlah percobaan kirim per event per URL
+	webhookAttempts = 5
+	// webhookRetryDelay adalah jeda sebelum percobaan kedua; jeda berikutnya berlipat ganda
+	webhookRetryDelay = time.Second
+	// webhookTimeout adalah batas waktu satu percobaan kirim
+	webhookTimeout = 10 * time.Second
+)
+
+// locationChange adalah keadaan lokasi sebelum dan sesudah satu write; before nil untuk create
+type locationChange struct {
+	before *Location
+	after  *Location
+}
+
+// webhookNotifier mengevaluasi perubahan lokasi terhadap geofence di background dan mengirim event-nya
+// ke setiap URL yang dikonfigurasi, dengan retry untuk error jaringan, 429, dan 5xx
+type webhookNotifier struct {
+	urls   []string
+	secret string
+	client *http.Client
+	queue  chan locationChange
+	// slots membatasi pengiriman yang berjalan bersamaan
+	slots chan struct{}
+}
+
+// newWebhookNotifier membuat notifier untuk urls; secret boleh kosong (tanpa header signature)
+func newWebhookNotifier(urls []string, secret string) *webhookNotifier {
+	return &webhookNotifier{
+		urls:   urls,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout, Transport: tracedTransport()},
+		queue:  make(chan locationChange, webhookQueueSize),
+		slots:  make(chan struct{}, maxWebhookDeliveries),
+	}
+}
+
+// notifyLocationChange mengantrekan perubahan lokasi untuk evaluasi geofence. Tidak pernah memblokir
+// request: tanpa webhook yang dikonfigurasi tidak ada yang dilakukan, dan antrean yang penuh dibuang.
+func (s *Server) notifyLocationChange(before, after *Location) {
+	if s.webhooks == nil {
+		return
+	}
+	select {
+	case s.webhooks.queue <- locationChange{before: before, after: after}:
+	default:
+		slog.Warn("geofence queue is full, dropping location change", "queue_size", webhookQueueSize)
+	}
+}
+
+// runGeofenceWorker memproses antrean perubahan lokasi sampai ctx dibatalkan
+func (s *Server) runGeofenceWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-s.webhooks.queue:
+			s.evaluateGeofences(change)
+		}
+	}
+}
+
+// deliverAll mengirim event ke semua URL di goroutine terpisah, dibatasi oleh slots
+func (n *webhookNotifier) deliverAll(evt GeofenceEvent) {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		slog.Error("encode geofence event failed", "error", err)
+		return
+	}
+	for _, url := range n.urls {
+		n.slots <- struct{}{}
+		go func(url string) {
+			defer func() { <-n.slots }()
+			n.deliver(url, evt, body)
+		}(url)
+	}
+}
+
+// deliver mengirim satu event ke satu URL, mengulang dengan jeda berlipat ganda sampai webhookAttempts
+func (n *webhookNotifier) deliver(url string, evt GeofenceEvent, body []byte) {
+	log := slog.With("url", url, "event", evt.Type, "geofence_id", evt.GeofenceID, "location_id", evt.LocationID)
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(url, body)
+		if err == nil {
+			webhookDeliveries.WithLabelValues("delivered").Inc()
+			return
+		}
+		if !retry || attempt == webhookAttempts {
+			webhookDeliveries.WithLabelValues("failed").Inc()
+			log.Error("webhook delivery failed", "attempts", attempt, "error", err)
+			return
+		}
+		log.Warn("webhook delivery failed, retrying", "attempt", attempt, "retry_in", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post melakukan satu percobaan kirim. retry false berarti penerima menolak event (4xx selain 429),
+// sehingga mengulang tidak akan membantu.
+func (n *webhookNotifier) post(url string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-mongo-railway-webhooks")
+	// Penerima memverifikasi bahwa event benar dari server ini dengan menghitung HMAC yang sama atas body
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}